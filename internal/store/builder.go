@@ -18,13 +18,18 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	admissionregistration "k8s.io/api/admissionregistration/v1"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	autoscaling "k8s.io/api/autoscaling/v2beta1"
 	batchv1 "k8s.io/api/batch/v1"
@@ -34,16 +39,27 @@ import (
 	v1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	networkingv1 "k8s.io/api/networking/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	policy "k8s.io/api/policy/v1beta1"
 	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 	vpaautoscaling "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1beta2"
 	vpaclientset "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/client/clientset/versioned"
+	"k8s.io/client-go/discovery"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
 
 	ksmtypes "k8s.io/kube-state-metrics/pkg/builder/types"
-	"k8s.io/kube-state-metrics/pkg/listwatch"
+	"k8s.io/kube-state-metrics/pkg/metric"
 	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
 	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
 	"k8s.io/kube-state-metrics/pkg/options"
@@ -54,27 +70,107 @@ import (
 // Builder helps to build store. It follows the builder pattern
 // (https://en.wikipedia.org/wiki/Builder_pattern).
 type Builder struct {
-	kubeClient       clientset.Interface
-	vpaClient        vpaclientset.Interface
-	namespaces       options.NamespaceList
-	ctx              context.Context
-	enabledResources []string
-	allowDenyList    ksmtypes.AllowDenyLister
-	metrics          *watch.ListWatchMetrics
-	shard            int32
-	totalShards      int
-	buildStoreFunc   ksmtypes.BuildStoreFunc
+	kubeClient             clientset.Interface
+	vpaClient              vpaclientset.Interface
+	namespaces             options.NamespaceList
+	ctx                    context.Context
+	enabledResources       []string
+	allowDenyList          ksmtypes.AllowDenyLister
+	labelSelector          string
+	resourceLabelSelectors map[string]string
+	resourceFieldSelectors map[string]string
+	resyncPeriods          options.ResyncPeriods
+	useAPIServerCache      bool
+	metadataOnly           map[string]struct{}
+	metricPrefix           string
+	emitDeprecatedNames    bool
+	maxLabelValueLength    int
+	addUIDLabel            bool
+	skipAnnotation         string
+	durationSampling       int
+	customLabelKeys        []string
+	customLabelValues      []string
+	customStores           []ksmtypes.CustomStore
+	extraFamilyGenerators  map[string][]generator.FamilyGenerator
+	defaultLabelRemaps     map[string]map[string]string
+	metrics                *watch.ListWatchMetrics
+	selectorInfo           *prometheus.GaugeVec
+	resourceDisabled       *prometheus.GaugeVec
+	generatorErrors        *prometheus.CounterVec
+	labelValuesTruncated   *prometheus.CounterVec
+	generationDuration     *prometheus.HistogramVec
+	skippedObjects         *prometheus.CounterVec
+	duplicateConditions    *prometheus.CounterVec
+	groupVersions          map[string]schema.GroupVersion
+	shard                  int32
+	totalShards            int
+	buildStoreFunc         ksmtypes.BuildStoreFunc
 }
 
 // NewBuilder returns a new builder.
 func NewBuilder() *Builder {
-	b := &Builder{}
+	b := &Builder{
+		metricPrefix: generator.DefaultMetricPrefix,
+	}
 	return b
 }
 
 // WithMetrics sets the metrics property of a Builder.
 func (b *Builder) WithMetrics(r *prometheus.Registry) {
 	b.metrics = watch.NewListWatchMetrics(r)
+	b.selectorInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_state_metrics_selector_info",
+			Help: "Selectors restricting the objects listed and watched per resource. Metrics of resources listed here are intentionally partial.",
+		},
+		[]string{"resource", "label_selector", "field_selector"},
+	)
+	b.resourceDisabled = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_state_metrics_resource_disabled",
+			Help: "Enabled resources none of the API versions of which are served by the apiserver.",
+		},
+		[]string{"resource"},
+	)
+	b.generatorErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_state_metrics_generator_errors_total",
+			Help: "Number of panics recovered while generating a metric family for an object. The family is skipped for the object.",
+		},
+		[]string{"resource", "family"},
+	)
+	b.labelValuesTruncated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_state_metrics_label_values_truncated_total",
+			Help: "Number of values of labels converted from Kubernetes labels and annotations truncated to --max-label-value-length.",
+		},
+		[]string{"resource"},
+	)
+	b.generationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kube_state_metrics_family_generation_duration_seconds",
+			Help:    "Duration of generating a metric family for an object, observed for every nth generation according to --family-generation-duration-sampling.",
+			Buckets: prometheus.ExponentialBuckets(0.00001, 4, 8),
+		},
+		[]string{"resource", "family"},
+	)
+	b.skippedObjects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_state_metrics_skipped_objects_total",
+			Help: "Number of times objects annotated to be skipped were added, updated or listed without generating their metrics.",
+		},
+		[]string{"resource"},
+	)
+	b.duplicateConditions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_state_metrics_duplicate_conditions_total",
+			Help: "Number of status conditions dropped as the object had another condition of the same type, which hints at a bug of the controller setting them.",
+		},
+		[]string{"family"},
+	)
+	if r != nil {
+		r.MustRegister(b.selectorInfo, b.resourceDisabled, b.generatorErrors, b.labelValuesTruncated, b.generationDuration, b.skippedObjects, b.duplicateConditions)
+	}
 }
 
 // WithEnabledResources sets the enabledResources property of a Builder.
@@ -105,6 +201,28 @@ func (b *Builder) WithSharding(shard int32, totalShards int) {
 	b.totalShards = totalShards
 }
 
+// WithUseAPIServerCache configures whether lists are served from the watch
+// cache of the apiserver instead of etcd, by listing with resourceVersion 0.
+func (b *Builder) WithUseAPIServerCache(useAPIServerCache bool) {
+	b.useAPIServerCache = useAPIServerCache
+}
+
+// WithMetadataOnlyResources configures the resources the objects of which are
+// listed and watched metadata only, so that their data is neither transferred
+// nor cached. Only the resources in metadataOnlyResources are supported.
+func (b *Builder) WithMetadataOnlyResources(r []string) error {
+	metadataOnly := map[string]struct{}{}
+	for _, resource := range r {
+		if _, ok := metadataOnlyResources[resource]; !ok {
+			return errors.Errorf("resource %s cannot be listed and watched metadata only", resource)
+		}
+		metadataOnly[resource] = struct{}{}
+	}
+
+	b.metadataOnly = metadataOnly
+	return nil
+}
+
 // WithContext sets the ctx property of a Builder.
 func (b *Builder) WithContext(ctx context.Context) {
 	b.ctx = ctx
@@ -126,6 +244,263 @@ func (b *Builder) WithAllowDenyList(l ksmtypes.AllowDenyLister) {
 	b.allowDenyList = l
 }
 
+// WithLabelSelectors sets the label selector used when listing and watching
+// the objects of all resources, as well as label selectors for individual
+// resources, taking precedence over the former. An empty selector for an
+// individual resource disables the global label selector for it.
+func (b *Builder) WithLabelSelectors(labelSelector string, resourceLabelSelectors map[string]string) error {
+	if _, err := labels.Parse(labelSelector); err != nil {
+		return errors.Wrapf(err, "invalid label selector %q", labelSelector)
+	}
+
+	for resource, selector := range resourceLabelSelectors {
+		if !resourceExists(resource) {
+			return errors.Errorf("resource %s does not exist. Available resources: %s", resource, strings.Join(availableResources(), ","))
+		}
+		if _, err := labels.Parse(selector); err != nil {
+			return errors.Wrapf(err, "invalid label selector %q for resource %s", selector, resource)
+		}
+	}
+
+	b.labelSelector = labelSelector
+	b.resourceLabelSelectors = resourceLabelSelectors
+	return nil
+}
+
+// WithFieldSelectors sets the field selectors used when listing and watching
+// the objects of individual resources. They are merged with any field selector
+// kube-state-metrics itself may apply.
+func (b *Builder) WithFieldSelectors(resourceFieldSelectors map[string]string) error {
+	for resource, selector := range resourceFieldSelectors {
+		if !resourceExists(resource) {
+			return errors.Errorf("resource %s does not exist. Available resources: %s", resource, strings.Join(availableResources(), ","))
+		}
+		if _, err := fields.ParseSelector(selector); err != nil {
+			return errors.Wrapf(err, "invalid field selector %q for resource %s", selector, resource)
+		}
+	}
+
+	b.resourceFieldSelectors = resourceFieldSelectors
+	return nil
+}
+
+// metricPrefixRegexp matches valid metric name prefixes.
+var metricPrefixRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// WithResyncPeriods configures the resync periods of the reflectors per
+// resource, with the options.DefaultResyncPeriodKey entry applying to all
+// others.
+func (b *Builder) WithResyncPeriods(resyncPeriods map[string]time.Duration) error {
+	for resource, period := range resyncPeriods {
+		if resource != options.DefaultResyncPeriodKey && !resourceExists(resource) {
+			return errors.Errorf("resource %s does not exist. Available resources: %s", resource, strings.Join(availableResources(), ","))
+		}
+		if period < 0 {
+			return errors.Errorf("resync period for %s must not be negative, got %s", resource, period)
+		}
+	}
+
+	b.resyncPeriods = resyncPeriods
+	return nil
+}
+
+// WithCustomStores registers the stores of resources not built into
+// kube-state-metrics, which are built in addition to the enabled resources.
+func (b *Builder) WithCustomStores(stores []ksmtypes.CustomStore) error {
+	seen := map[string]struct{}{}
+	for _, s := range stores {
+		if s.Resource == "" {
+			return errors.New("custom store without resource name")
+		}
+		if resourceExists(s.Resource) {
+			return errors.Errorf("custom store %s collides with a built-in resource", s.Resource)
+		}
+		if _, ok := seen[s.Resource]; ok {
+			return errors.Errorf("duplicate custom store %s", s.Resource)
+		}
+		if s.ExpectedType == nil || s.ListWatchFunc == nil {
+			return errors.Errorf("custom store %s requires an expected type and a ListWatchFunc", s.Resource)
+		}
+		seen[s.Resource] = struct{}{}
+	}
+
+	b.customStores = append([]ksmtypes.CustomStore{}, stores...)
+	return nil
+}
+
+// WithExtraFamilyGenerators appends the given metric families to the built-in
+// ones of the given resource. They are subject to the same allow- and
+// denylist, and their metrics carry the default labels of the resource. Names
+// colliding with the metric families of the resource are rejected.
+func (b *Builder) WithExtraFamilyGenerators(resource string, gens []generator.FamilyGenerator) error {
+	wrap, ok := availableWrapFuncs[resource]
+	if !ok {
+		return errors.Errorf("resource %s does not exist. Available resources: %s", resource, strings.Join(availableResources(), ","))
+	}
+
+	names := map[string]struct{}{}
+	for _, f := range b.metricFamilies(resource) {
+		names[f.Name] = struct{}{}
+	}
+
+	wrapped := make([]generator.FamilyGenerator, 0, len(gens))
+	for _, g := range gens {
+		if _, ok := names[g.Name]; ok {
+			return errors.Errorf("metric family %s collides with an existing metric family of resource %s", g.Name, resource)
+		}
+		if g.GenerateFunc == nil {
+			return errors.Errorf("metric family %s requires a GenerateFunc", g.Name)
+		}
+		names[g.Name] = struct{}{}
+
+		g.GenerateFunc = wrap(g.GenerateFunc)
+		wrapped = append(wrapped, g)
+	}
+
+	if b.extraFamilyGenerators == nil {
+		b.extraFamilyGenerators = map[string][]generator.FamilyGenerator{}
+	}
+	b.extraFamilyGenerators[resource] = append(b.extraFamilyGenerators[resource], wrapped...)
+	return nil
+}
+
+// WithDefaultLabelRemaps renames the default labels identifying the objects of
+// a resource, e.g. namespace to exported_namespace. remaps maps resources to
+// the names of their default labels and their replacements. Replacements
+// colliding with the other labels of the resource are rejected.
+func (b *Builder) WithDefaultLabelRemaps(remaps map[string]map[string]string) error {
+	reserved := reservedLabelKeys()
+	for resource, remap := range remaps {
+		defaultLabels, ok := availableDefaultLabels[resource]
+		if !ok {
+			return errors.Errorf("resource %s does not exist. Available resources: %s", resource, strings.Join(availableResources(), ","))
+		}
+
+		keys := map[string]struct{}{}
+		for _, key := range defaultLabels {
+			if replacement, ok := remap[key]; ok {
+				key = replacement
+			}
+			if _, ok := keys[key]; ok {
+				return errors.Errorf("label %q occurs more than once in the default labels of resource %s", key, resource)
+			}
+			keys[key] = struct{}{}
+		}
+
+		for key, replacement := range remap {
+			if !contains(defaultLabels, key) {
+				return errors.Errorf("label %q is not a default label of resource %s, which are %s", key, resource, strings.Join(defaultLabels, ","))
+			}
+			if !labelNameRegexp.MatchString(replacement) || strings.HasPrefix(replacement, "__") {
+				return errors.Errorf("invalid label name %q replacing label %q of resource %s", replacement, key, resource)
+			}
+			if strings.HasPrefix(replacement, "label_") || strings.HasPrefix(replacement, "annotation_") {
+				return errors.Errorf("label %q replacing label %q of resource %s collides with the labels converted from Kubernetes labels and annotations", replacement, key, resource)
+			}
+			if _, ok := reserved[replacement]; ok && !contains(defaultLabels, replacement) {
+				return errors.Errorf("label %q replacing label %q of resource %s collides with the labels of the metric families", replacement, key, resource)
+			}
+		}
+	}
+
+	b.defaultLabelRemaps = remaps
+	return nil
+}
+
+// WithMetricPrefix sets the prefix replacing the leading "kube_" of all metric
+// family names. Allow- and denylists apply to the prefixed names.
+func (b *Builder) WithMetricPrefix(prefix string) error {
+	if !metricPrefixRegexp.MatchString(prefix) {
+		return errors.Errorf("invalid metric prefix %q, it has to match %s", prefix, metricPrefixRegexp)
+	}
+
+	b.metricPrefix = prefix
+	return nil
+}
+
+// WithEmitDeprecatedMetricNames configures whether the metric families
+// renamed to end with their unit are generated under their deprecated names
+// as well, see unitSuffixedNames, and whether the metric families replaced by
+// resource and unit labels are generated, see deprecatedMetricFamilies.
+func (b *Builder) WithEmitDeprecatedMetricNames(emitDeprecatedNames bool) {
+	b.emitDeprecatedNames = emitDeprecatedNames
+}
+
+// WithUIDLabel configures whether the uid of each object is added as a label
+// to the info metric family of its resource, see infoMetricFamilies.
+func (b *Builder) WithUIDLabel(addUIDLabel bool) {
+	b.addUIDLabel = addUIDLabel
+}
+
+// WithSkipAnnotation sets the key of the annotation objects opt out of
+// metrics with by setting it to "true". Their metrics are not generated and
+// they are not held by the stores. An empty key disables skipping objects.
+func (b *Builder) WithSkipAnnotation(key string) error {
+	if key != "" {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return errors.Errorf("invalid skip annotation key %q: %s", key, strings.Join(errs, ", "))
+		}
+	}
+
+	b.skipAnnotation = key
+	return nil
+}
+
+// WithMaxLabelValueLength sets the maximum length in bytes of the values of
+// labels converted from Kubernetes labels and annotations, see
+// truncateLabelValue. 0 disables truncation.
+func (b *Builder) WithMaxLabelValueLength(max int) error {
+	if max < 0 || (max > 0 && max <= len(truncatedLabelValueMarker)) {
+		return errors.Errorf("invalid maximum label value length %d, it has to be 0 or longer than the truncation marker %q", max, truncatedLabelValueMarker)
+	}
+
+	b.maxLabelValueLength = max
+	return nil
+}
+
+// WithFamilyGenerationDurationSampling sets n, so that the duration of every
+// nth generation of each metric family for an object is observed. 0 disables
+// the observation, which costs two clock reads per sampled generation.
+func (b *Builder) WithFamilyGenerationDurationSampling(n int) error {
+	if n < 0 {
+		return errors.Errorf("invalid family generation duration sampling %d, it must not be negative", n)
+	}
+
+	b.durationSampling = n
+	return nil
+}
+
+// labelNameRegexp matches valid label names.
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// WithCustomLabels sets static labels appended to every metric, ordered by
+// their names. Names colliding with the default labels of any resource, or
+// with the labels converted from Kubernetes labels, are rejected.
+func (b *Builder) WithCustomLabels(customLabels map[string]string) error {
+	reserved := reservedLabelKeys()
+
+	keys := make([]string, 0, len(customLabels))
+	for key := range customLabels {
+		if !labelNameRegexp.MatchString(key) || strings.HasPrefix(key, "__") {
+			return errors.Errorf("invalid custom label name %q", key)
+		}
+		if _, ok := reserved[key]; ok || isConvertedLabel(key) {
+			return errors.Errorf("custom label %q collides with a label of kube-state-metrics", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, customLabels[key])
+	}
+
+	b.customLabelKeys = keys
+	b.customLabelValues = values
+	return nil
+}
+
 // WithGenerateStoreFunc configures a constom generate store function
 func (b *Builder) WithGenerateStoreFunc(f ksmtypes.BuildStoreFunc) {
 	b.buildStoreFunc = f
@@ -136,27 +511,399 @@ func (b *Builder) DefaultGenerateStoreFunc() ksmtypes.BuildStoreFunc {
 	return b.buildStore
 }
 
+// Snapshot returns a function restoring the current configuration of the
+// Builder, e.g. to roll back a reconfiguration the stores of which could not
+// be built.
+func (b *Builder) Snapshot() func() {
+	snapshot := *b
+	snapshot.extraFamilyGenerators = make(map[string][]generator.FamilyGenerator, len(b.extraFamilyGenerators))
+	for r, gens := range b.extraFamilyGenerators {
+		snapshot.extraFamilyGenerators[r] = gens
+	}
+	return func() {
+		*b = snapshot
+	}
+}
+
 // Build initializes and registers all enabled stores.
-func (b *Builder) Build() []cache.Store {
+func (b *Builder) Build() ([]cache.Store, error) {
+	_, stores, err := b.BuildStores()
+	return stores, err
+}
+
+// BuildStores initializes and registers all enabled stores. It returns the
+// names of the resources alongside their stores. No store is built if the
+// field selector of any resource is rejected by the apiserver.
+func (b *Builder) BuildStores() ([]string, []cache.Store, error) {
+	resources := b.ServedResources()
+
+	for _, r := range resources {
+		if err := b.validateFieldSelector(r); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	stores := make([]cache.Store, 0, len(resources))
+	for _, r := range resources {
+		stores = append(stores, b.newStore(r))
+	}
+
+	klog.Infof("Active resources: %s", strings.Join(resources, ","))
+
+	return resources, stores, nil
+}
+
+// ServedResources discovers the API versions served by the apiserver and
+// returns the enabled resources served in any of their supported versions.
+// The other enabled resources are disabled, which is logged and exposed.
+func (b *Builder) ServedResources() []string {
 	if b.allowDenyList == nil {
 		panic("allowDenyList should not be nil")
 	}
 
-	stores := []cache.Store{}
-	activeStoreNames := []string{}
+	if b.selectorInfo != nil {
+		b.selectorInfo.Reset()
+	}
+	if b.resourceDisabled != nil {
+		b.resourceDisabled.Reset()
+	}
+
+	b.groupVersions = b.discoverGroupVersions()
 
+	resources := []string{}
 	for _, c := range b.enabledResources {
-		constructor, ok := availableStores[c]
-		if ok {
-			store := constructor(b)
-			activeStoreNames = append(activeStoreNames, c)
-			stores = append(stores, store)
+		if _, ok := b.groupVersions[c]; !ok {
+			klog.Warningf("Disabling resource %s, none of its API versions %s are served by the apiserver", c, groupVersionsString(availableGroupVersions[c]))
+			if b.resourceDisabled != nil {
+				b.resourceDisabled.WithLabelValues(c).Set(1)
+			}
+			continue
+		}
+		if _, ok := availableStores[c]; !ok {
+			continue
+		}
+		resources = append(resources, c)
+	}
+	for _, s := range b.customStores {
+		resources = append(resources, s.Resource)
+	}
+
+	for _, r := range resources {
+		labelSelector, fieldSelector := b.labelSelectorFor(r), b.resourceFieldSelectors[r]
+		if labelSelector != "" || fieldSelector != "" {
+			klog.Infof("Using label selector %q and field selector %q for resource %s", labelSelector, fieldSelector, r)
+			if b.selectorInfo != nil {
+				b.selectorInfo.WithLabelValues(r, labelSelector, fieldSelector).Set(1)
+			}
+		}
+	}
+
+	return resources
+}
+
+// BuildStore initializes and registers the store of the given resource, which
+// has to be one of the ServedResources. It returns an error if the field
+// selector of the resource is rejected by the apiserver.
+func (b *Builder) BuildStore(resource string) (cache.Store, error) {
+	if err := b.validateFieldSelector(resource); err != nil {
+		return nil, err
+	}
+	return b.newStore(resource), nil
+}
+
+// newStore initializes and registers the store of the given resource.
+func (b *Builder) newStore(resource string) cache.Store {
+	if s, ok := b.customStore(resource); ok {
+		return b.buildStoreFunc(s.Resource, s.FamilyGenerators, s.ExpectedType, s.ListWatchFunc)
+	}
+	return availableStores[resource](b)
+}
+
+// validateFieldSelector lists the objects of the given resource with its field
+// selector, if any, in every configured namespace. The apiserver rejects field
+// selectors it does not support for a resource, which the reflectors would
+// retry forever, leaving the store silently empty. Namespaces are validated
+// individually, as the apiserver may reject a selector in some of them only,
+// e.g. due to an admission webhook.
+func (b *Builder) validateFieldSelector(resource string) error {
+	selector, ok := b.resourceFieldSelectors[resource]
+	if !ok {
+		return nil
+	}
+
+	listWatchFunc := b.resourceListWatchFunc(resource)
+	tweakListOptions := b.tweakListOptions(resource)
+	namespaces := []string{metav1.NamespaceAll}
+	if len(b.namespaces) > 0 && !b.isClusterScoped(resource) {
+		namespaces = b.namespaces
+	}
+	for _, ns := range namespaces {
+		if err := validateSelectors(listWatchFunc(b.kubeClient, ns, tweakListOptions)); err != nil {
+			return fmt.Errorf("field selector %q for resource %s was rejected by the apiserver in namespace %q: %v", selector, resource, ns, err)
+		}
+	}
+	return nil
+}
+
+// resourceListWatchFunc returns the function creating the list/watch the store
+// of the given resource is built with.
+func (b *Builder) resourceListWatchFunc(resource string) func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
+	if s, ok := b.customStore(resource); ok {
+		return s.ListWatchFunc
+	}
+
+	// The list/watch is picked alongside the API version of the resource
+	// when building its store, so it is captured from a copy of the Builder
+	// building no store.
+	var listWatchFunc func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher
+	capture := *b
+	capture.buildStoreFunc = func(
+		_ string,
+		_ []generator.FamilyGenerator,
+		_ interface{},
+		f func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher,
+	) cache.Store {
+		listWatchFunc = f
+		return nil
+	}
+	availableStores[resource](&capture)
+	return listWatchFunc
+}
+
+// customStore returns the registered custom store of the given resource.
+func (b *Builder) customStore(resource string) (ksmtypes.CustomStore, bool) {
+	for _, s := range b.customStores {
+		if s.Resource == resource {
+			return s, true
+		}
+	}
+	return ksmtypes.CustomStore{}, false
+}
+
+// metricFamilies returns the metric families generated for the objects of the
+// given resource.
+func (b *Builder) metricFamilies(resource string) []generator.FamilyGenerator {
+	if s, ok := b.customStore(resource); ok {
+		return s.FamilyGenerators
+	}
+	families := unitSuffixMetricFamilies(availableMetricFamilies[resource], b.emitDeprecatedNames)
+	if b.emitDeprecatedNames {
+		families = append(families, deprecatedMetricFamilies[resource]...)
+	}
+	if extra, ok := b.extraFamilyGenerators[resource]; ok {
+		families = append(append([]generator.FamilyGenerator{}, families...), extra...)
+	}
+	if remap, ok := b.defaultLabelRemaps[resource]; ok && len(remap) > 0 {
+		families = remapDefaultLabels(families, availableDefaultLabels[resource], remap)
+	}
+	if b.maxLabelValueLength > 0 {
+		families = b.truncateLabelValues(resource, families)
+	}
+	if b.addUIDLabel {
+		families = addUIDLabel(families, infoMetricFamilies[resource])
+	}
+	if _, ok := b.metadataOnly[resource]; ok {
+		families = withoutFamilies(families, metadataOnlyResources[resource].dataFamilies)
+	}
+	return families
+}
+
+// withoutFamilies returns the given metric families except for the ones of the
+// given names.
+func withoutFamilies(families []generator.FamilyGenerator, names []string) []generator.FamilyGenerator {
+	if len(names) == 0 {
+		return families
+	}
+
+	filtered := make([]generator.FamilyGenerator, 0, len(families))
+	for _, f := range families {
+		if !contains(names, f.Name) {
+			filtered = append(filtered, f)
 		}
 	}
+	return filtered
+}
+
+// listWatchFunc returns the given function creating the list/watch of the
+// given resource, unless the resource is listed and watched metadata only.
+func (b *Builder) listWatchFunc(
+	resource string,
+	listWatchFunc func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher,
+) func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
+	if _, ok := b.metadataOnly[resource]; !ok {
+		return listWatchFunc
+	}
 
-	klog.Infof("Active resources: %s", strings.Join(activeStoreNames, ","))
+	m := metadataOnlyResources[resource]
+	if len(m.dataFamilies) > 0 {
+		klog.Infof("Listing and watching %s metadata only, the metric families %s are not available", resource, strings.Join(m.dataFamilies, ","))
+	} else {
+		klog.Infof("Listing and watching %s metadata only", resource)
+	}
+	return createMetadataOnlyListWatchFunc(resource, m.newObject)
+}
 
-	return stores
+// remapDefaultLabels returns the given metric families with the default labels
+// renamed according to remap. Replacements colliding with the labels of the
+// metric families are rejected by WithDefaultLabelRemaps().
+func remapDefaultLabels(families []generator.FamilyGenerator, defaultLabels []string, remap map[string]string) []generator.FamilyGenerator {
+	remapped := make([]generator.FamilyGenerator, 0, len(families))
+	for _, f := range families {
+		generate := f.GenerateFunc
+		f.GenerateFunc = func(obj interface{}) *metric.Family {
+			family := generate(obj)
+			for _, m := range family.Metrics {
+				for i, key := range defaultLabels {
+					replacement, ok := remap[key]
+					if !ok || i >= len(m.LabelKeys) || m.LabelKeys[i] != key {
+						continue
+					}
+					m.LabelKeys[i] = replacement
+				}
+			}
+			return family
+		}
+		remapped = append(remapped, f)
+	}
+	return remapped
+}
+
+// addUIDLabel returns the given metric families with the uid of the object
+// appended as a label to the metrics of the family of the given name, unless
+// they already carry a uid label, e.g. kube_pod_info.
+func addUIDLabel(families []generator.FamilyGenerator, name string) []generator.FamilyGenerator {
+	withUID := make([]generator.FamilyGenerator, 0, len(families))
+	for _, f := range families {
+		if f.Name == name {
+			generate := f.GenerateFunc
+			f.GenerateFunc = func(obj interface{}) *metric.Family {
+				family := generate(obj)
+				o, err := meta.Accessor(obj)
+				if err != nil {
+					return family
+				}
+				for _, m := range family.Metrics {
+					if !contains(m.LabelKeys, "uid") {
+						m.AppendLabels([]string{"uid"}, []string{string(o.GetUID())})
+					}
+				}
+				return family
+			}
+		}
+		withUID = append(withUID, f)
+	}
+	return withUID
+}
+
+// truncateLabelValues returns the given metric families of a resource with
+// the values of the labels converted from Kubernetes labels and annotations
+// truncated to the maximum label value length, counting each truncation.
+func (b *Builder) truncateLabelValues(resource string, families []generator.FamilyGenerator) []generator.FamilyGenerator {
+	truncated := make([]generator.FamilyGenerator, 0, len(families))
+	for _, f := range families {
+		generate := f.GenerateFunc
+		f.GenerateFunc = func(obj interface{}) *metric.Family {
+			family := generate(obj)
+			for _, m := range family.Metrics {
+				for i, key := range m.LabelKeys {
+					if i >= len(m.LabelValues) || !isConvertedLabel(key) {
+						continue
+					}
+					v, ok := truncateLabelValue(m.LabelValues[i], b.maxLabelValueLength)
+					if !ok {
+						continue
+					}
+					m.LabelValues[i] = v
+					if b.labelValuesTruncated != nil {
+						b.labelValuesTruncated.WithLabelValues(resource).Inc()
+					}
+				}
+			}
+			return family
+		}
+		truncated = append(truncated, f)
+	}
+	return truncated
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// StoreFingerprint returns a string identifying the configuration the store of
+// the given resource is built with. Stores with equal fingerprints expose the
+// same metrics, so that unaffected stores can be kept on reconfiguration.
+func (b *Builder) StoreFingerprint(resource string) string {
+	return b.storeFingerprint(resource, b.shard)
+}
+
+// ShardConfigFingerprint returns a string identifying the configuration the
+// stores of the given resources are built with, except for the shard, so that
+// it is equal for all shards configured consistently.
+func (b *Builder) ShardConfigFingerprint(resources []string) string {
+	fingerprints := make([]string, len(resources))
+	for i, r := range resources {
+		fingerprints[i] = r + ":" + b.storeFingerprint(r, 0)
+	}
+	return strings.Join(fingerprints, "\n")
+}
+
+// storeFingerprint returns the fingerprint of the store of the given resource
+// as if it was built for the given shard.
+func (b *Builder) storeFingerprint(resource string, shard int32) string {
+	families := []string{}
+	prefixed := generator.PrefixMetricFamilies(b.metricPrefix, b.metricFamilies(resource))
+	for _, f := range generator.FilterMetricFamilies(b.allowDenyList, prefixed) {
+		families = append(families, f.Name)
+	}
+
+	namespaces := []string(b.namespaces)
+	if b.isClusterScoped(resource) {
+		namespaces = nil
+	}
+
+	_, metadataOnly := b.metadataOnly[resource]
+
+	return fmt.Sprintf("%+v", struct {
+		GroupVersion      schema.GroupVersion
+		Namespaces        []string
+		Shard             int32
+		TotalShards       int
+		Families          []string
+		CustomLabelKeys   []string
+		CustomLabelValues []string
+		LabelSelector     string
+		FieldSelector     string
+		ResyncPeriod      time.Duration
+		UseAPIServerCache bool
+		MetadataOnly      bool
+		DefaultLabelRemap map[string]string
+		MaxLabelValueLen  int
+		AddUIDLabel       bool
+		SkipAnnotation    string
+	}{
+		GroupVersion:      b.groupVersions[resource],
+		Namespaces:        namespaces,
+		Shard:             shard,
+		TotalShards:       b.totalShards,
+		Families:          families,
+		CustomLabelKeys:   b.customLabelKeys,
+		CustomLabelValues: b.customLabelValues,
+		LabelSelector:     b.labelSelectorFor(resource),
+		FieldSelector:     b.resourceFieldSelectors[resource],
+		ResyncPeriod:      b.resyncPeriods.Get(resource),
+		UseAPIServerCache: b.useAPIServerCache,
+		MetadataOnly:      metadataOnly,
+		DefaultLabelRemap: b.defaultLabelRemaps[resource],
+		MaxLabelValueLen:  b.maxLabelValueLength,
+		AddUIDLabel:       b.addUIDLabel && infoMetricFamilies[resource] != "",
+		SkipAnnotation:    b.skipAnnotation,
+	})
 }
 
 var availableStores = map[string]func(f *Builder) cache.Store{
@@ -191,6 +938,259 @@ var availableStores = map[string]func(f *Builder) cache.Store{
 	"verticalpodautoscalers":          func(b *Builder) cache.Store { return b.buildVPAStore() },
 }
 
+// availableMetricFamilies maps the resources to the metric families generated
+// for their objects.
+var availableMetricFamilies = map[string][]generator.FamilyGenerator{
+	"certificatesigningrequests":      csrMetricFamilies,
+	"configmaps":                      configMapMetricFamilies,
+	"cronjobs":                        cronJobMetricFamilies,
+	"daemonsets":                      daemonSetMetricFamilies,
+	"deployments":                     deploymentMetricFamilies,
+	"endpoints":                       endpointMetricFamilies,
+	"horizontalpodautoscalers":        hpaMetricFamilies,
+	"ingresses":                       ingressMetricFamilies,
+	"jobs":                            jobMetricFamilies,
+	"leases":                          leaseMetricFamilies,
+	"limitranges":                     limitRangeMetricFamilies,
+	"mutatingwebhookconfigurations":   mutatingWebhookConfigurationMetricFamilies,
+	"namespaces":                      namespaceMetricFamilies,
+	"networkpolicies":                 networkpolicyMetricFamilies,
+	"nodes":                           nodeMetricFamilies,
+	"persistentvolumeclaims":          persistentVolumeClaimMetricFamilies,
+	"persistentvolumes":               persistentVolumeMetricFamilies,
+	"poddisruptionbudgets":            podDisruptionBudgetMetricFamilies,
+	"pods":                            podMetricFamilies,
+	"replicasets":                     replicaSetMetricFamilies,
+	"replicationcontrollers":          replicationControllerMetricFamilies,
+	"resourcequotas":                  resourceQuotaMetricFamilies,
+	"secrets":                         secretMetricFamilies,
+	"services":                        serviceMetricFamilies,
+	"statefulsets":                    statefulSetMetricFamilies,
+	"storageclasses":                  storageClassMetricFamilies,
+	"validatingwebhookconfigurations": validatingWebhookConfigurationMetricFamilies,
+	"volumeattachments":               volumeAttachmentMetricFamilies,
+	"verticalpodautoscalers":          vpaMetricFamilies,
+}
+
+// availableWrapFuncs maps the resources to functions adding the default labels
+// of the resource to the metrics of a family, used for extra metric families.
+var availableWrapFuncs = map[string]func(func(interface{}) *metric.Family) func(interface{}) *metric.Family{
+	"certificatesigningrequests": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapCSRFunc(func(o *certv1beta1.CertificateSigningRequest) *metric.Family { return f(o) })
+	},
+	"configmaps": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapConfigMapFunc(func(o *v1.ConfigMap) *metric.Family { return f(o) })
+	},
+	"cronjobs": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapCronJobFunc(func(o *batchv1beta1.CronJob) *metric.Family { return f(o) })
+	},
+	"daemonsets": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapDaemonSetFunc(func(o *appsv1.DaemonSet) *metric.Family { return f(o) })
+	},
+	"deployments": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapDeploymentFunc(func(o *appsv1.Deployment) *metric.Family { return f(o) })
+	},
+	"endpoints": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapEndpointFunc(func(o *v1.Endpoints) *metric.Family { return f(o) })
+	},
+	"horizontalpodautoscalers": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapHPAFunc(func(o *autoscaling.HorizontalPodAutoscaler) *metric.Family { return f(o) })
+	},
+	"ingresses": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapIngressFunc(func(o *extensions.Ingress) *metric.Family { return f(o) })
+	},
+	"jobs": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapJobFunc(func(o *batchv1.Job) *metric.Family { return f(o) })
+	},
+	"leases": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapLeaseFunc(func(o *coordinationv1.Lease) *metric.Family { return f(o) })
+	},
+	"limitranges": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapLimitRangeFunc(func(o *v1.LimitRange) *metric.Family { return f(o) })
+	},
+	"mutatingwebhookconfigurations": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapMutatingWebhookConfigurationFunc(func(o *admissionregistrationv1beta1.MutatingWebhookConfiguration) *metric.Family { return f(o) })
+	},
+	"namespaces": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapNamespaceFunc(func(o *v1.Namespace) *metric.Family { return f(o) })
+	},
+	"networkpolicies": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapNetworkPolicyFunc(func(o *networkingv1.NetworkPolicy) *metric.Family { return f(o) })
+	},
+	"nodes": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapNodeFunc(func(o *v1.Node) *metric.Family { return f(o) })
+	},
+	"persistentvolumeclaims": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapPersistentVolumeClaimFunc(func(o *v1.PersistentVolumeClaim) *metric.Family { return f(o) })
+	},
+	"persistentvolumes": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapPersistentVolumeFunc(func(o *v1.PersistentVolume) *metric.Family { return f(o) })
+	},
+	"poddisruptionbudgets": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapPodDisruptionBudgetFunc(func(o *policy.PodDisruptionBudget) *metric.Family { return f(o) })
+	},
+	"pods": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapPodFunc(func(o *v1.Pod) *metric.Family { return f(o) })
+	},
+	"replicasets": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapReplicaSetFunc(func(o *appsv1.ReplicaSet) *metric.Family { return f(o) })
+	},
+	"replicationcontrollers": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapReplicationControllerFunc(func(o *v1.ReplicationController) *metric.Family { return f(o) })
+	},
+	"resourcequotas": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapResourceQuotaFunc(func(o *v1.ResourceQuota) *metric.Family { return f(o) })
+	},
+	"secrets": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapSecretFunc(func(o *v1.Secret) *metric.Family { return f(o) })
+	},
+	"services": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapSvcFunc(func(o *v1.Service) *metric.Family { return f(o) })
+	},
+	"statefulsets": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapStatefulSetFunc(func(o *appsv1.StatefulSet) *metric.Family { return f(o) })
+	},
+	"storageclasses": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapStorageClassFunc(func(o *storagev1.StorageClass) *metric.Family { return f(o) })
+	},
+	"validatingwebhookconfigurations": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapValidatingWebhookConfigurationFunc(func(o *admissionregistration.ValidatingWebhookConfiguration) *metric.Family { return f(o) })
+	},
+	"volumeattachments": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapVolumeAttachmentFunc(func(o *storagev1beta1.VolumeAttachment) *metric.Family { return f(o) })
+	},
+	"verticalpodautoscalers": func(f func(interface{}) *metric.Family) func(interface{}) *metric.Family {
+		return wrapVPAFunc(func(o *vpaautoscaling.VerticalPodAutoscaler) *metric.Family { return f(o) })
+	},
+}
+
+// availableDefaultLabels maps the resources to the default labels identifying
+// the object, which every metric of the resource starts with.
+var availableDefaultLabels = map[string][]string{
+	"certificatesigningrequests":      descCSRLabelsDefaultLabels,
+	"configmaps":                      descConfigMapLabelsDefaultLabels,
+	"cronjobs":                        descCronJobLabelsDefaultLabels,
+	"daemonsets":                      descDaemonSetLabelsDefaultLabels,
+	"deployments":                     descDeploymentLabelsDefaultLabels,
+	"endpoints":                       descEndpointLabelsDefaultLabels,
+	"horizontalpodautoscalers":        descHorizontalPodAutoscalerLabelsDefaultLabels,
+	"ingresses":                       descIngressLabelsDefaultLabels,
+	"jobs":                            descJobLabelsDefaultLabels,
+	"leases":                          descLeaseLabelsDefaultLabels,
+	"limitranges":                     descLimitRangeLabelsDefaultLabels,
+	"mutatingwebhookconfigurations":   descMutatingWebhookConfigurationDefaultLabels,
+	"namespaces":                      descNamespaceLabelsDefaultLabels,
+	"networkpolicies":                 descNetworkPolicyLabelsDefaultLabels,
+	"nodes":                           descNodeLabelsDefaultLabels,
+	"persistentvolumeclaims":          descPersistentVolumeClaimLabelsDefaultLabels,
+	"persistentvolumes":               descPersistentVolumeLabelsDefaultLabels,
+	"poddisruptionbudgets":            descPodDisruptionBudgetLabelsDefaultLabels,
+	"pods":                            descPodLabelsDefaultLabels,
+	"replicasets":                     descReplicaSetLabelsDefaultLabels,
+	"replicationcontrollers":          descReplicationControllerLabelsDefaultLabels,
+	"resourcequotas":                  descResourceQuotaLabelsDefaultLabels,
+	"secrets":                         descSecretLabelsDefaultLabels,
+	"services":                        descServiceLabelsDefaultLabels,
+	"statefulsets":                    descStatefulSetLabelsDefaultLabels,
+	"storageclasses":                  descStorageClassLabelsDefaultLabels,
+	"validatingwebhookconfigurations": descValidatingWebhookConfigurationDefaultLabels,
+	"volumeattachments":               descVolumeAttachmentLabelsDefaultLabels,
+	"verticalpodautoscalers":          descVerticalPodAutoscalerLabelsDefaultLabels,
+}
+
+// infoMetricFamilies lists the info metric family describing the objects of
+// each resource, which --add-uid-label adds the uid label to. Resources
+// without such a family are left out.
+var infoMetricFamilies = map[string]string{
+	"configmaps":                      "kube_configmap_info",
+	"cronjobs":                        "kube_cronjob_info",
+	"endpoints":                       "kube_endpoint_info",
+	"horizontalpodautoscalers":        "kube_horizontalpodautoscaler_info",
+	"ingresses":                       "kube_ingress_info",
+	"jobs":                            "kube_job_info",
+	"mutatingwebhookconfigurations":   "kube_mutatingwebhookconfiguration_info",
+	"nodes":                           "kube_node_info",
+	"persistentvolumeclaims":          "kube_persistentvolumeclaim_info",
+	"persistentvolumes":               "kube_persistentvolume_info",
+	"pods":                            "kube_pod_info",
+	"secrets":                         "kube_secret_info",
+	"services":                        "kube_service_info",
+	"storageclasses":                  "kube_storageclass_info",
+	"validatingwebhookconfigurations": "kube_validatingwebhookconfiguration_info",
+	"volumeattachments":               "kube_volumeattachment_info",
+}
+
+// availableGroupVersions lists the API versions the objects of each resource
+// can be listed and watched in, in order of preference. Every resource in
+// availableStores needs an entry.
+var availableGroupVersions = map[string][]schema.GroupVersion{
+	"certificatesigningrequests":      {certv1beta1.SchemeGroupVersion},
+	"configmaps":                      {v1.SchemeGroupVersion},
+	"cronjobs":                        {batchv1beta1.SchemeGroupVersion},
+	"daemonsets":                      {appsv1.SchemeGroupVersion},
+	"deployments":                     {appsv1.SchemeGroupVersion},
+	"endpoints":                       {v1.SchemeGroupVersion},
+	"horizontalpodautoscalers":        {autoscaling.SchemeGroupVersion},
+	"ingresses":                       {extensions.SchemeGroupVersion, networkingv1beta1.SchemeGroupVersion},
+	"jobs":                            {batchv1.SchemeGroupVersion},
+	"leases":                          {coordinationv1.SchemeGroupVersion},
+	"limitranges":                     {v1.SchemeGroupVersion},
+	"mutatingwebhookconfigurations":   {admissionregistrationv1beta1.SchemeGroupVersion},
+	"namespaces":                      {v1.SchemeGroupVersion},
+	"networkpolicies":                 {networkingv1.SchemeGroupVersion},
+	"nodes":                           {v1.SchemeGroupVersion},
+	"persistentvolumeclaims":          {v1.SchemeGroupVersion},
+	"persistentvolumes":               {v1.SchemeGroupVersion},
+	"poddisruptionbudgets":            {policy.SchemeGroupVersion},
+	"pods":                            {v1.SchemeGroupVersion},
+	"replicasets":                     {appsv1.SchemeGroupVersion},
+	"replicationcontrollers":          {v1.SchemeGroupVersion},
+	"resourcequotas":                  {v1.SchemeGroupVersion},
+	"secrets":                         {v1.SchemeGroupVersion},
+	"services":                        {v1.SchemeGroupVersion},
+	"statefulsets":                    {appsv1.SchemeGroupVersion},
+	"storageclasses":                  {storagev1.SchemeGroupVersion},
+	"validatingwebhookconfigurations": {admissionregistration.SchemeGroupVersion},
+	"volumeattachments":               {storagev1beta1.SchemeGroupVersion},
+	"verticalpodautoscalers":          {vpaautoscaling.SchemeGroupVersion},
+}
+
+// familyLabelKeys are the names of the labels the metric families of the
+// resources carry in addition to their default labels. They are only known
+// once metrics are generated, hence listed here to reject colliding custom
+// labels and default label replacements upfront.
+var familyLabelKeys = []string{
+	"access_mode", "attacher", "cluster_ip", "concurrency_policy", "condition",
+	"constraint", "container", "container_id", "container_runtime_version",
+	"created_by_kind", "created_by_name", "effect", "external_ip",
+	"external_name", "host", "host_ip", "host_network", "hostname", "image",
+	"image_id", "ip", "job_name", "kernel_version", "key", "kubelet_version",
+	"kubeproxy_version", "load_balancer_ip", "metric_name",
+	"metric_target_type", "os_image", "owner_is_controller", "owner_kind",
+	"owner_name", "path", "phase", "pod_cidr", "pod_ip", "priority_class",
+	"provider_id", "provisioner", "reason", "reclaim_policy", "resource",
+	"revision", "role", "scaletargetref_api_version", "scaletargetref_kind",
+	"scaletargetref_name", "schedule", "service_name", "service_port",
+	"status", "storageclass", "target_api_version", "target_kind",
+	"target_name", "tls_host", "type", "uid", "unit", "update_mode", "value",
+	"volume", "volume_binding_mode", "volumename",
+}
+
+// reservedLabelKeys returns the names of the labels every metric of any of the
+// resources carries, plus the names of the labels of their metric families.
+func reservedLabelKeys() map[string]struct{} {
+	keys := map[string]struct{}{}
+	for _, labels := range availableDefaultLabels {
+		for _, key := range labels {
+			keys[key] = struct{}{}
+		}
+	}
+	for _, key := range familyLabelKeys {
+		keys[key] = struct{}{}
+	}
+	return keys
+}
+
 func resourceExists(name string) bool {
 	_, ok := availableStores[name]
 	return ok
@@ -205,128 +1205,228 @@ func availableResources() []string {
 }
 
 func (b *Builder) buildConfigMapStore() cache.Store {
-	return b.buildStoreFunc(configMapMetricFamilies, &v1.ConfigMap{}, createConfigMapListWatch)
+	return b.buildStoreFunc("configmaps", b.metricFamilies("configmaps"), &v1.ConfigMap{}, b.listWatchFunc("configmaps", createConfigMapListWatch))
 }
 
 func (b *Builder) buildCronJobStore() cache.Store {
-	return b.buildStoreFunc(cronJobMetricFamilies, &batchv1beta1.CronJob{}, createCronJobListWatch)
+	return b.buildStoreFunc("cronjobs", b.metricFamilies("cronjobs"), &batchv1beta1.CronJob{}, createCronJobListWatch)
 }
 
 func (b *Builder) buildDaemonSetStore() cache.Store {
-	return b.buildStoreFunc(daemonSetMetricFamilies, &appsv1.DaemonSet{}, createDaemonSetListWatch)
+	return b.buildStoreFunc("daemonsets", b.metricFamilies("daemonsets"), &appsv1.DaemonSet{}, createDaemonSetListWatch)
 }
 
 func (b *Builder) buildDeploymentStore() cache.Store {
-	return b.buildStoreFunc(deploymentMetricFamilies, &appsv1.Deployment{}, createDeploymentListWatch)
+	return b.buildStoreFunc("deployments", b.metricFamilies("deployments"), &appsv1.Deployment{}, createDeploymentListWatch)
 }
 
 func (b *Builder) buildEndpointsStore() cache.Store {
-	return b.buildStoreFunc(endpointMetricFamilies, &v1.Endpoints{}, createEndpointsListWatch)
+	return b.buildStoreFunc("endpoints", b.metricFamilies("endpoints"), &v1.Endpoints{}, createEndpointsListWatch)
 }
 
 func (b *Builder) buildHPAStore() cache.Store {
-	return b.buildStoreFunc(hpaMetricFamilies, &autoscaling.HorizontalPodAutoscaler{}, createHPAListWatch)
+	return b.buildStoreFunc("horizontalpodautoscalers", b.metricFamilies("horizontalpodautoscalers"), &autoscaling.HorizontalPodAutoscaler{}, createHPAListWatch)
 }
 
 func (b *Builder) buildIngressStore() cache.Store {
-	return b.buildStoreFunc(ingressMetricFamilies, &extensions.Ingress{}, createIngressListWatch)
+	if b.groupVersions["ingresses"] == networkingv1beta1.SchemeGroupVersion {
+		return b.buildStoreFunc("ingresses", b.metricFamilies("ingresses"), &extensions.Ingress{}, createNetworkingIngressListWatch)
+	}
+	return b.buildStoreFunc("ingresses", b.metricFamilies("ingresses"), &extensions.Ingress{}, createIngressListWatch)
 }
 
 func (b *Builder) buildJobStore() cache.Store {
-	return b.buildStoreFunc(jobMetricFamilies, &batchv1.Job{}, createJobListWatch)
+	return b.buildStoreFunc("jobs", b.metricFamilies("jobs"), &batchv1.Job{}, createJobListWatch)
 }
 
 func (b *Builder) buildLimitRangeStore() cache.Store {
-	return b.buildStoreFunc(limitRangeMetricFamilies, &v1.LimitRange{}, createLimitRangeListWatch)
+	return b.buildStoreFunc("limitranges", b.metricFamilies("limitranges"), &v1.LimitRange{}, createLimitRangeListWatch)
 }
 
 func (b *Builder) buildMutatingWebhookConfigurationStore() cache.Store {
-	return b.buildStoreFunc(mutatingWebhookConfigurationMetricFamilies, &admissionregistration.MutatingWebhookConfiguration{}, createMutatingWebhookConfigurationListWatch)
+	return b.buildStoreFunc("mutatingwebhookconfigurations", b.metricFamilies("mutatingwebhookconfigurations"), &admissionregistration.MutatingWebhookConfiguration{}, createMutatingWebhookConfigurationListWatch)
 }
 
 func (b *Builder) buildNamespaceStore() cache.Store {
-	return b.buildStoreFunc(namespaceMetricFamilies, &v1.Namespace{}, createNamespaceListWatch)
+	return b.buildStoreFunc("namespaces", b.metricFamilies("namespaces"), &v1.Namespace{}, createNamespaceListWatch)
 }
 
 func (b *Builder) buildNetworkPolicyStore() cache.Store {
-	return b.buildStoreFunc(networkpolicyMetricFamilies, &networkingv1.NetworkPolicy{}, createNetworkPolicyListWatch)
+	return b.buildStoreFunc("networkpolicies", b.metricFamilies("networkpolicies"), &networkingv1.NetworkPolicy{}, createNetworkPolicyListWatch)
 }
 
 func (b *Builder) buildNodeStore() cache.Store {
-	return b.buildStoreFunc(nodeMetricFamilies, &v1.Node{}, createNodeListWatch)
+	return b.buildStoreFunc("nodes", b.metricFamilies("nodes"), &v1.Node{}, createNodeListWatch)
 }
 
 func (b *Builder) buildPersistentVolumeClaimStore() cache.Store {
-	return b.buildStoreFunc(persistentVolumeClaimMetricFamilies, &v1.PersistentVolumeClaim{}, createPersistentVolumeClaimListWatch)
+	return b.buildStoreFunc("persistentvolumeclaims", b.metricFamilies("persistentvolumeclaims"), &v1.PersistentVolumeClaim{}, createPersistentVolumeClaimListWatch)
 }
 
 func (b *Builder) buildPersistentVolumeStore() cache.Store {
-	return b.buildStoreFunc(persistentVolumeMetricFamilies, &v1.PersistentVolume{}, createPersistentVolumeListWatch)
+	return b.buildStoreFunc("persistentvolumes", b.metricFamilies("persistentvolumes"), &v1.PersistentVolume{}, createPersistentVolumeListWatch)
 }
 
 func (b *Builder) buildPodDisruptionBudgetStore() cache.Store {
-	return b.buildStoreFunc(podDisruptionBudgetMetricFamilies, &policy.PodDisruptionBudget{}, createPodDisruptionBudgetListWatch)
+	return b.buildStoreFunc("poddisruptionbudgets", b.metricFamilies("poddisruptionbudgets"), &policy.PodDisruptionBudget{}, createPodDisruptionBudgetListWatch)
 }
 
 func (b *Builder) buildReplicaSetStore() cache.Store {
-	return b.buildStoreFunc(replicaSetMetricFamilies, &appsv1.ReplicaSet{}, createReplicaSetListWatch)
+	return b.buildStoreFunc("replicasets", b.metricFamilies("replicasets"), &appsv1.ReplicaSet{}, createReplicaSetListWatch)
 }
 
 func (b *Builder) buildReplicationControllerStore() cache.Store {
-	return b.buildStoreFunc(replicationControllerMetricFamilies, &v1.ReplicationController{}, createReplicationControllerListWatch)
+	return b.buildStoreFunc("replicationcontrollers", b.metricFamilies("replicationcontrollers"), &v1.ReplicationController{}, createReplicationControllerListWatch)
 }
 
 func (b *Builder) buildResourceQuotaStore() cache.Store {
-	return b.buildStoreFunc(resourceQuotaMetricFamilies, &v1.ResourceQuota{}, createResourceQuotaListWatch)
+	return b.buildStoreFunc("resourcequotas", b.metricFamilies("resourcequotas"), &v1.ResourceQuota{}, createResourceQuotaListWatch)
 }
 
 func (b *Builder) buildSecretStore() cache.Store {
-	return b.buildStoreFunc(secretMetricFamilies, &v1.Secret{}, createSecretListWatch)
+	return b.buildStoreFunc("secrets", b.metricFamilies("secrets"), &v1.Secret{}, b.listWatchFunc("secrets", createSecretListWatch))
 }
 
 func (b *Builder) buildServiceStore() cache.Store {
-	return b.buildStoreFunc(serviceMetricFamilies, &v1.Service{}, createServiceListWatch)
+	return b.buildStoreFunc("services", b.metricFamilies("services"), &v1.Service{}, createServiceListWatch)
 }
 
 func (b *Builder) buildStatefulSetStore() cache.Store {
-	return b.buildStoreFunc(statefulSetMetricFamilies, &appsv1.StatefulSet{}, createStatefulSetListWatch)
+	return b.buildStoreFunc("statefulsets", b.metricFamilies("statefulsets"), &appsv1.StatefulSet{}, createStatefulSetListWatch)
 }
 
 func (b *Builder) buildStorageClassStore() cache.Store {
-	return b.buildStoreFunc(storageClassMetricFamilies, &storagev1.StorageClass{}, createStorageClassListWatch)
+	return b.buildStoreFunc("storageclasses", b.metricFamilies("storageclasses"), &storagev1.StorageClass{}, createStorageClassListWatch)
 }
 
 func (b *Builder) buildPodStore() cache.Store {
-	return b.buildStoreFunc(podMetricFamilies, &v1.Pod{}, createPodListWatch)
+	return b.buildStoreFunc("pods", b.metricFamilies("pods"), &v1.Pod{}, createPodListWatch)
 }
 
 func (b *Builder) buildCsrStore() cache.Store {
-	return b.buildStoreFunc(csrMetricFamilies, &certv1beta1.CertificateSigningRequest{}, createCSRListWatch)
+	return b.buildStoreFunc("certificatesigningrequests", b.metricFamilies("certificatesigningrequests"), &certv1beta1.CertificateSigningRequest{}, createCSRListWatch)
 }
 
 func (b *Builder) buildValidatingWebhookConfigurationStore() cache.Store {
-	return b.buildStoreFunc(validatingWebhookConfigurationMetricFamilies, &admissionregistration.ValidatingWebhookConfiguration{}, createValidatingWebhookConfigurationListWatch)
+	return b.buildStoreFunc("validatingwebhookconfigurations", b.metricFamilies("validatingwebhookconfigurations"), &admissionregistration.ValidatingWebhookConfiguration{}, createValidatingWebhookConfigurationListWatch)
 }
 
 func (b *Builder) buildVolumeAttachmentStore() cache.Store {
-	return b.buildStoreFunc(volumeAttachmentMetricFamilies, &storagev1.VolumeAttachment{}, createVolumeAttachmentListWatch)
+	return b.buildStoreFunc("volumeattachments", b.metricFamilies("volumeattachments"), &storagev1.VolumeAttachment{}, createVolumeAttachmentListWatch)
 }
 
 func (b *Builder) buildVPAStore() cache.Store {
-	return b.buildStoreFunc(vpaMetricFamilies, &vpaautoscaling.VerticalPodAutoscaler{}, createVPAListWatchFunc(b.vpaClient))
+	return b.buildStoreFunc("verticalpodautoscalers", b.metricFamilies("verticalpodautoscalers"), &vpaautoscaling.VerticalPodAutoscaler{}, createVPAListWatchFunc(b.vpaClient))
 }
 
 func (b *Builder) buildLeases() cache.Store {
-	return b.buildStoreFunc(leaseMetricFamilies, &coordinationv1.Lease{}, createLeaseListWatch)
+	return b.buildStoreFunc("leases", b.metricFamilies("leases"), &coordinationv1.Lease{}, createLeaseListWatch)
+}
+
+// recoverMetricFamilies makes the given metric families of a resource recover
+// from panics while generating metrics for an object, logging the object and
+// counting the error instead of crashing.
+func (b *Builder) recoverMetricFamilies(resource string, families []generator.FamilyGenerator) []generator.FamilyGenerator {
+	return generator.RecoverMetricFamilies(families, func(family string, obj interface{}, r interface{}) {
+		name := "<unknown>"
+		if o, err := meta.Accessor(obj); err == nil {
+			name = o.GetName()
+			if o.GetNamespace() != "" {
+				name = o.GetNamespace() + "/" + o.GetName()
+			}
+		}
+		klog.Errorf("Failed to generate metric family %s for %s %s, skipping it: %v", family, resource, name, r)
+		if b.generatorErrors != nil {
+			b.generatorErrors.WithLabelValues(resource, family).Inc()
+		}
+	})
+}
+
+// timeMetricFamilies makes the given metric families of a resource observe
+// the duration of every nth generation for an object, if enabled.
+func (b *Builder) timeMetricFamilies(resource string, families []generator.FamilyGenerator) []generator.FamilyGenerator {
+	if b.durationSampling == 0 || b.generationDuration == nil {
+		return families
+	}
+
+	n := uint64(b.durationSampling)
+	timed := make([]generator.FamilyGenerator, 0, len(families))
+	for _, f := range families {
+		generate := f.GenerateFunc
+		observer := b.generationDuration.WithLabelValues(resource, f.Name)
+		var generations uint64
+		f.GenerateFunc = func(obj interface{}) *metric.Family {
+			if atomic.AddUint64(&generations, 1)%n != 0 {
+				return generate(obj)
+			}
+			start := time.Now()
+			family := generate(obj)
+			observer.Observe(time.Since(start).Seconds())
+			return family
+		}
+		timed = append(timed, f)
+	}
+	return timed
+}
+
+// conditionFamilies returns the status conditions of an object exposed by the
+// metric families of the given names, which expose only the conditions kept by
+// latestConditions().
+var conditionFamilies = map[string]func(obj interface{}) []condition{
+	"kube_daemonset_status_condition":               func(obj interface{}) []condition { return daemonSetConditions(obj.(*appsv1.DaemonSet)) },
+	"kube_daemonset_status_condition_reason":        func(obj interface{}) []condition { return daemonSetConditions(obj.(*appsv1.DaemonSet)) },
+	"kube_deployment_status_condition":              func(obj interface{}) []condition { return deploymentConditions(obj.(*appsv1.Deployment)) },
+	"kube_horizontalpodautoscaler_status_condition": func(obj interface{}) []condition { return hpaConditions(obj.(*autoscaling.HorizontalPodAutoscaler)) },
+	"kube_job_complete":                             func(obj interface{}) []condition { return jobConditions(obj.(*batchv1.Job)) },
+	"kube_job_failed":                               func(obj interface{}) []condition { return jobConditions(obj.(*batchv1.Job)) },
+	"kube_namespace_status_condition":               func(obj interface{}) []condition { return namespaceConditions(obj.(*v1.Namespace)) },
+	"kube_node_status_condition":                    func(obj interface{}) []condition { return nodeConditions(obj.(*v1.Node)) },
+	"kube_persistentvolumeclaim_status_condition": func(obj interface{}) []condition {
+		return persistentVolumeClaimConditions(obj.(*v1.PersistentVolumeClaim))
+	},
+	"kube_pod_status_ready":     func(obj interface{}) []condition { return podConditions(obj.(*v1.Pod)) },
+	"kube_pod_status_scheduled": func(obj interface{}) []condition { return podConditions(obj.(*v1.Pod)) },
+}
+
+// countDuplicateConditions makes the given metric families of status
+// conditions count the conditions of an object they drop as duplicates, under
+// the name the families are exposed with.
+func (b *Builder) countDuplicateConditions(families []generator.FamilyGenerator) []generator.FamilyGenerator {
+	if b.duplicateConditions == nil {
+		return families
+	}
+
+	counted := make([]generator.FamilyGenerator, 0, len(families))
+	for _, f := range families {
+		conditions, ok := conditionFamilies[f.Name]
+		if !ok {
+			counted = append(counted, f)
+			continue
+		}
+		generate := f.GenerateFunc
+		name := generator.PrefixMetricFamilies(b.metricPrefix, []generator.FamilyGenerator{f})[0].Name
+		f.GenerateFunc = func(obj interface{}) *metric.Family {
+			family := generate(obj)
+			all := conditions(obj)
+			if dropped := len(all) - len(latestConditions(all)); dropped > 0 {
+				b.duplicateConditions.WithLabelValues(name).Add(float64(dropped))
+			}
+			return family
+		}
+		counted = append(counted, f)
+	}
+	return counted
 }
 
 func (b *Builder) buildStore(
+	resource string,
 	metricFamilies []generator.FamilyGenerator,
 	expectedType interface{},
-	listWatchFunc func(kubeClient clientset.Interface, ns string) cache.ListerWatcher,
+	listWatchFunc func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher,
 ) cache.Store {
-	filteredMetricFamilies := generator.FilterMetricFamilies(b.allowDenyList, metricFamilies)
-	composedMetricGenFuncs := generator.ComposeMetricGenFuncs(filteredMetricFamilies)
+	prefixedMetricFamilies := generator.PrefixMetricFamilies(b.metricPrefix, b.countDuplicateConditions(metricFamilies))
+	filteredMetricFamilies := generator.FilterMetricFamilies(b.allowDenyList, prefixedMetricFamilies)
+	composedMetricGenFuncs := generator.ComposeMetricGenFuncs(b.recoverMetricFamilies(resource, b.timeMetricFamilies(resource, filteredMetricFamilies)))
 
 	familyHeaders := generator.ExtractMetricFamilyHeaders(filteredMetricFamilies)
 
@@ -334,21 +1434,228 @@ func (b *Builder) buildStore(
 		familyHeaders,
 		composedMetricGenFuncs,
 	)
-	b.reflectorPerNamespace(expectedType, store, listWatchFunc)
+	store.WithOpenMetricsHeaders(generator.ExtractMetricFamilyOpenMetricsHeaders(filteredMetricFamilies))
+	if len(b.customLabelKeys) > 0 {
+		store.WithCustomLabels(b.customLabelKeys, b.customLabelValues)
+	}
+	store.WithObjectsGauge(b.metrics.StoreObjects.WithLabelValues(reflect.TypeOf(expectedType).String()))
+	if b.skipAnnotation != "" {
+		var skipped metricsstore.Counter
+		if b.skippedObjects != nil {
+			skipped = b.skippedObjects.WithLabelValues(resource)
+		}
+		key := b.skipAnnotation
+		store.WithSkip(func(o metav1.Object) bool {
+			return o.GetAnnotations()[key] == "true"
+		}, skipped)
+	}
+
+	tweakListOptions := b.tweakListOptions(resource)
+
+	resyncPeriod := b.resyncPeriods.Get(resource)
+	if resyncPeriod > 0 {
+		klog.Infof("Resyncing %s every %s", resource, resyncPeriod)
+	} else {
+		klog.Infof("Resyncing %s disabled", resource)
+	}
+
+	b.reflectorPerNamespace(resource, expectedType, store, listWatchFunc, tweakListOptions, resyncPeriod)
 
 	return store
 }
 
+// discoverGroupVersions returns, for each enabled resource, the first of its
+// available API versions served by the apiserver. Resources none of the API
+// versions of which are served are left out. API versions that could not be
+// discovered are assumed to be served, so that transient discovery errors do
+// not disable resources.
+func (b *Builder) discoverGroupVersions() map[string]schema.GroupVersion {
+	groupVersions := map[string]schema.GroupVersion{}
+
+	var lists []*metav1.APIResourceList
+	failed := map[schema.GroupVersion]error{}
+	var err error
+	if b.kubeClient != nil {
+		_, lists, err = b.kubeClient.Discovery().ServerGroupsAndResources()
+		if e, ok := err.(*discovery.ErrGroupDiscoveryFailed); ok {
+			failed = e.Groups
+		} else if err != nil {
+			lists = nil
+		}
+	}
+
+	if len(lists) == 0 {
+		if b.kubeClient != nil {
+			klog.Warningf("API discovery returned no API versions, assuming the preferred ones are served: %v", err)
+		}
+		for _, c := range b.enabledResources {
+			if versions := availableGroupVersions[c]; len(versions) > 0 {
+				groupVersions[c] = versions[0]
+			}
+		}
+		return groupVersions
+	}
+
+	served := map[schema.GroupVersion]map[string]struct{}{}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		resources := map[string]struct{}{}
+		for _, r := range list.APIResources {
+			resources[r.Name] = struct{}{}
+		}
+		served[gv] = resources
+	}
+
+	for _, c := range b.enabledResources {
+		for _, gv := range availableGroupVersions[c] {
+			if err, ok := failed[gv]; ok {
+				klog.Warningf("Failed to discover API version %s, assuming resource %s is served: %v", gv, c, err)
+				groupVersions[c] = gv
+				break
+			}
+			if _, ok := served[gv][c]; ok {
+				groupVersions[c] = gv
+				break
+			}
+		}
+	}
+
+	return groupVersions
+}
+
+// groupVersionsString returns the given API versions as a comma-separated
+// list.
+func groupVersionsString(groupVersions []schema.GroupVersion) string {
+	s := make([]string, 0, len(groupVersions))
+	for _, gv := range groupVersions {
+		s = append(s, gv.String())
+	}
+	return strings.Join(s, ",")
+}
+
+// labelSelectorFor returns the label selector configured for the given
+// resource.
+func (b *Builder) labelSelectorFor(resource string) string {
+	if selector, ok := b.resourceLabelSelectors[resource]; ok {
+		return selector
+	}
+	return b.labelSelector
+}
+
+// tweakListOptions returns a function applying the selectors configured for
+// the given resource to the metav1.ListOptions of both list and watch calls.
+// Selectors already present in the metav1.ListOptions are kept.
+func (b *Builder) tweakListOptions(resource string) func(*metav1.ListOptions) {
+	labelSelector := b.labelSelectorFor(resource)
+	fieldSelector := b.resourceFieldSelectors[resource]
+	return func(opts *metav1.ListOptions) {
+		opts.LabelSelector = mergeSelectors(opts.LabelSelector, labelSelector)
+		opts.FieldSelector = mergeSelectors(opts.FieldSelector, fieldSelector)
+	}
+}
+
+// mergeSelectors returns the conjunction of the two given label or field
+// selectors.
+func mergeSelectors(a, b string) string {
+	if a == "" || a == b {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + "," + b
+}
+
+// validateSelectors lists a single object with the given cache.ListerWatcher
+// and returns an error in case the apiserver rejected the request as invalid.
+func validateSelectors(lw cache.ListerWatcher) error {
+	_, err := lw.List(metav1.ListOptions{Limit: 1})
+	if apierrors.IsBadRequest(err) || apierrors.IsInvalid(err) {
+		return err
+	}
+	return nil
+}
+
 // reflectorPerNamespace creates a Kubernetes client-go reflector with the given
-// listWatchFunc for each given namespace and registers it with the given store.
+// listWatchFunc and resync period for each given namespace and registers it
+// with the given store. Cluster-scoped resources are listed and watched only
+// once, regardless of the given namespaces.
 func (b *Builder) reflectorPerNamespace(
+	resource string,
+	expectedType interface{},
+	store *metricsstore.MetricsStore,
+	listWatchFunc func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher,
+	tweakListOptions func(*metav1.ListOptions),
+	resyncPeriod time.Duration,
+) {
+	if len(b.namespaces) == 0 || b.namespaces.IsAllNamespaces() || b.isClusterScoped(resource) {
+		b.startReflector(expectedType, store, listWatchFunc(b.kubeClient, metav1.NamespaceAll, tweakListOptions), resyncPeriod)
+		return
+	}
+
+	for _, ns := range b.namespaces {
+		b.startReflector(expectedType, metricsstore.NewNamespacedStore(store, ns), listWatchFunc(b.kubeClient, ns, tweakListOptions), resyncPeriod)
+	}
+}
+
+// startReflector starts a Kubernetes client-go reflector with the given
+// cache.ListerWatcher and resync period and registers it with the given store.
+// A resync period of zero disables resyncing.
+func (b *Builder) startReflector(
 	expectedType interface{},
 	store cache.Store,
-	listWatchFunc func(kubeClient clientset.Interface, ns string) cache.ListerWatcher,
+	listWatcher cache.ListerWatcher,
+	resyncPeriod time.Duration,
 ) {
-	lwf := func(ns string) cache.ListerWatcher { return listWatchFunc(b.kubeClient, ns) }
-	lw := listwatch.MultiNamespaceListerWatcher(b.namespaces, nil, lwf)
-	instrumentedListWatch := watch.NewInstrumentedListerWatcher(lw, b.metrics, reflect.TypeOf(expectedType).String())
-	reflector := cache.NewReflector(sharding.NewShardedListWatch(b.shard, b.totalShards, instrumentedListWatch), expectedType, store, 0)
+	if b.useAPIServerCache {
+		listWatcher = apiserverCacheListWatch{listWatcher}
+	}
+	resource := reflect.TypeOf(expectedType).String()
+	instrumentedListWatch := watch.NewInstrumentedListerWatcher(listWatcher, b.metrics, resource)
+	instrumentedStore := watch.NewInstrumentedStore(store, b.metrics, resource)
+	reflector := cache.NewReflector(sharding.NewShardedListWatch(b.shard, b.totalShards, instrumentedListWatch), expectedType, instrumentedStore, resyncPeriod)
 	go reflector.Run(b.ctx.Done())
 }
+
+// apiserverCacheListWatch lists with resourceVersion 0, so that lists are
+// served from the watch cache of the apiserver instead of a quorum read from
+// etcd, at the cost of possibly stale results. Watches are left untouched.
+type apiserverCacheListWatch struct {
+	cache.ListerWatcher
+}
+
+func (lw apiserverCacheListWatch) List(opts metav1.ListOptions) (runtime.Object, error) {
+	// A resourceVersion must not be given when continuing a paginated list.
+	if opts.Continue == "" {
+		opts.ResourceVersion = "0"
+	}
+	return lw.ListerWatcher.List(opts)
+}
+
+// clusterScopedResources are the resources the objects of which are not bound
+// to a namespace. Leases are treated alike, as only the kube-node-lease
+// namespace is ever listed.
+var clusterScopedResources = map[string]struct{}{
+	"certificatesigningrequests":      {},
+	"leases":                          {},
+	"mutatingwebhookconfigurations":   {},
+	"namespaces":                      {},
+	"nodes":                           {},
+	"persistentvolumes":               {},
+	"storageclasses":                  {},
+	"validatingwebhookconfigurations": {},
+	"volumeattachments":               {},
+}
+
+// isClusterScoped returns whether the objects of the given resource are not
+// bound to a namespace.
+func (b *Builder) isClusterScoped(resource string) bool {
+	if s, ok := b.customStore(resource); ok {
+		return s.ClusterScoped
+	}
+	_, ok := clusterScopedResources[resource]
+	return ok
+}
@@ -0,0 +1,1113 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	autoscaling "k8s.io/api/autoscaling/v2beta1"
+	v1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	ksmtypes "k8s.io/kube-state-metrics/pkg/builder/types"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
+)
+
+func TestWithLabelSelectors(t *testing.T) {
+	tests := []struct {
+		Desc                   string
+		LabelSelector          string
+		ResourceLabelSelectors map[string]string
+		WantedError            bool
+		Wanted                 map[string]string
+	}{
+		{
+			Desc:   "no selectors",
+			Wanted: map[string]string{"pods": "", "nodes": ""},
+		},
+		{
+			Desc:          "global selector",
+			LabelSelector: "team=payments",
+			Wanted:        map[string]string{"pods": "team=payments", "nodes": "team=payments"},
+		},
+		{
+			Desc:                   "resource selectors take precedence",
+			LabelSelector:          "team=payments",
+			ResourceLabelSelectors: map[string]string{"pods": "app=web", "nodes": ""},
+			Wanted:                 map[string]string{"pods": "app=web", "nodes": "", "services": "team=payments"},
+		},
+		{
+			Desc:          "invalid global selector",
+			LabelSelector: "team==,",
+			WantedError:   true,
+		},
+		{
+			Desc:                   "invalid resource selector",
+			ResourceLabelSelectors: map[string]string{"pods": "app in web"},
+			WantedError:            true,
+		},
+		{
+			Desc:                   "unknown resource",
+			ResourceLabelSelectors: map[string]string{"foos": "app=web"},
+			WantedError:            true,
+		},
+	}
+
+	for _, test := range tests {
+		b := NewBuilder()
+		err := b.WithLabelSelectors(test.LabelSelector, test.ResourceLabelSelectors)
+		if (err != nil) != test.WantedError {
+			t.Errorf("Test error for Desc: %s. Wanted Error: %v, Got Error: %v", test.Desc, test.WantedError, err)
+			continue
+		}
+
+		for resource, want := range test.Wanted {
+			opts := metav1.ListOptions{}
+			b.tweakListOptions(resource)(&opts)
+			if opts.LabelSelector != want {
+				t.Errorf("Test error for Desc: %s. Want label selector %q for resource %s. Got: %q", test.Desc, want, resource, opts.LabelSelector)
+			}
+		}
+	}
+}
+
+func TestListWatchTweakListOptions(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+
+	b := NewBuilder()
+	if err := b.WithLabelSelectors("team=payments", map[string]string{"pods": "app=web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lw := createPodListWatch(kubeClient, metav1.NamespaceAll, b.tweakListOptions("pods"))
+	if _, err := lw.List(metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lw.Watch(metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	actions := kubeClient.Actions()
+	if len(actions) != 2 {
+		t.Fatalf("expected a list and a watch action, got %v", actions)
+	}
+	list := actions[0].(k8stesting.ListAction).GetListRestrictions()
+	watch := actions[1].(k8stesting.WatchAction).GetWatchRestrictions()
+	if list.Labels.String() != "app=web" || watch.Labels.String() != "app=web" {
+		t.Errorf("expected label selector %q on both list and watch, got %q and %q", "app=web", list.Labels, watch.Labels)
+	}
+}
+
+func TestWithFieldSelectors(t *testing.T) {
+	tests := []struct {
+		Desc                   string
+		ResourceFieldSelectors map[string]string
+		ListOptions            metav1.ListOptions
+		WantedError            bool
+		Wanted                 string
+	}{
+		{
+			Desc:                   "resource selector",
+			ResourceFieldSelectors: map[string]string{"pods": "status.phase!=Succeeded"},
+			Wanted:                 "status.phase!=Succeeded",
+		},
+		{
+			Desc:                   "merged with existing selector",
+			ResourceFieldSelectors: map[string]string{"pods": "status.phase!=Succeeded"},
+			ListOptions:            metav1.ListOptions{FieldSelector: "spec.nodeName=node-1"},
+			Wanted:                 "spec.nodeName=node-1,status.phase!=Succeeded",
+		},
+		{
+			Desc:        "existing selector only",
+			ListOptions: metav1.ListOptions{FieldSelector: "spec.nodeName=node-1"},
+			Wanted:      "spec.nodeName=node-1",
+		},
+		{
+			Desc:                   "invalid selector",
+			ResourceFieldSelectors: map[string]string{"pods": "status.phase"},
+			WantedError:            true,
+		},
+		{
+			Desc:                   "unknown resource",
+			ResourceFieldSelectors: map[string]string{"foos": "status.phase!=Succeeded"},
+			WantedError:            true,
+		},
+	}
+
+	for _, test := range tests {
+		b := NewBuilder()
+		err := b.WithFieldSelectors(test.ResourceFieldSelectors)
+		if (err != nil) != test.WantedError {
+			t.Errorf("Test error for Desc: %s. Wanted Error: %v, Got Error: %v", test.Desc, test.WantedError, err)
+			continue
+		}
+		if test.WantedError {
+			continue
+		}
+
+		opts := test.ListOptions
+		b.tweakListOptions("pods")(&opts)
+		if opts.FieldSelector != test.Wanted {
+			t.Errorf("Test error for Desc: %s. Want field selector %q. Got: %q", test.Desc, test.Wanted, opts.FieldSelector)
+		}
+	}
+}
+
+func TestValidateSelectors(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	lw := createPodListWatch(kubeClient, metav1.NamespaceAll, func(*metav1.ListOptions) {})
+
+	if err := validateSelectors(lw); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	kubeClient.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewBadRequest(`field label not supported: spec.foo`)
+	})
+
+	if err := validateSelectors(lw); err == nil {
+		t.Error("expected rejected field selector to return an error")
+	}
+}
+
+func TestBuildStoreRejectedFieldSelector(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "restricted" {
+			return true, nil, apierrors.NewBadRequest(`field label not supported: spec.nodeName`)
+		}
+		return false, nil, nil
+	})
+
+	b := NewBuilder()
+	b.WithKubeClient(kubeClient)
+	b.WithNamespaces([]string{"default", "restricted"})
+	if err := b.WithFieldSelectors(map[string]string{"pods": "spec.nodeName=node-1"}); err != nil {
+		t.Fatal(err)
+	}
+	built := false
+	b.WithGenerateStoreFunc(func(string, []generator.FamilyGenerator, interface{}, func(clientset.Interface, string, func(*metav1.ListOptions)) cache.ListerWatcher) cache.Store {
+		built = true
+		return nil
+	})
+
+	if _, err := b.BuildStore("pods"); err == nil {
+		t.Error("expected field selector rejected in a namespace other than the first to return an error")
+	}
+	if built {
+		t.Error("expected no store to be built for a rejected field selector")
+	}
+
+	b.WithNamespaces([]string{"default"})
+	if _, err := b.BuildStore("pods"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if !built {
+		t.Error("expected store to be built for an accepted field selector")
+	}
+}
+
+// TestListWatchBookmarks ensures the list/watch constructors pass the options
+// of the reflectors through, so that watches request bookmarks.
+func TestListWatchBookmarks(t *testing.T) {
+	tests := []struct {
+		Desc          string
+		ListWatchFunc func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher
+		ExpectedType  runtime.Object
+		List          string
+	}{
+		{
+			Desc:          "pods",
+			ListWatchFunc: createPodListWatch,
+			ExpectedType:  &v1.Pod{},
+			List:          `{"kind":"PodList","apiVersion":"v1","metadata":{"resourceVersion":"1"},"items":[]}`,
+		},
+		{
+			Desc:          "nodes",
+			ListWatchFunc: createNodeListWatch,
+			ExpectedType:  &v1.Node{},
+			List:          `{"kind":"NodeList","apiVersion":"v1","metadata":{"resourceVersion":"1"},"items":[]}`,
+		},
+		{
+			Desc:          "horizontalpodautoscalers",
+			ListWatchFunc: createHPAListWatch,
+			ExpectedType:  &autoscaling.HorizontalPodAutoscaler{},
+			List:          `{"kind":"HorizontalPodAutoscalerList","apiVersion":"autoscaling/v2beta1","metadata":{"resourceVersion":"1"},"items":[]}`,
+		},
+	}
+
+	for _, test := range tests {
+		watches := make(chan url.Values, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Query().Get("watch") != "true" {
+				io.WriteString(w, test.List)
+				return
+			}
+			select {
+			case watches <- r.URL.Query():
+			default:
+			}
+		}))
+
+		kubeClient, err := clientset.NewForConfig(&rest.Config{Host: server.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lw := test.ListWatchFunc(kubeClient, metav1.NamespaceAll, func(*metav1.ListOptions) {})
+		stop := make(chan struct{})
+		go cache.NewReflector(lw, test.ExpectedType, cache.NewStore(cache.MetaNamespaceKeyFunc), 0).Run(stop)
+
+		select {
+		case query := <-watches:
+			if query.Get("allowWatchBookmarks") != "true" {
+				t.Errorf("Test error for Desc: %s. Want watch query with allowWatchBookmarks=true. Got: %s", test.Desc, query.Encode())
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("Test error for Desc: %s. Want watch request, got none", test.Desc)
+		}
+		close(stop)
+		server.Close()
+	}
+}
+
+func TestAvailableGroupVersions(t *testing.T) {
+	for _, resource := range availableResources() {
+		if len(availableGroupVersions[resource]) == 0 {
+			t.Errorf("no API versions declared for resource %s", resource)
+		}
+	}
+}
+
+func TestDiscoverGroupVersions(t *testing.T) {
+	tests := []struct {
+		Desc      string
+		Resources []*metav1.APIResourceList
+		Wanted    map[string]schema.GroupVersion
+	}{
+		{
+			Desc: "preferred versions served",
+			Resources: []*metav1.APIResourceList{
+				{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods"}}},
+				{GroupVersion: "extensions/v1beta1", APIResources: []metav1.APIResource{{Name: "ingresses"}}},
+				{GroupVersion: "networking.k8s.io/v1beta1", APIResources: []metav1.APIResource{{Name: "ingresses"}}},
+				{GroupVersion: "autoscaling/v2beta1", APIResources: []metav1.APIResource{{Name: "horizontalpodautoscalers"}}},
+			},
+			Wanted: map[string]schema.GroupVersion{
+				"pods":                     v1.SchemeGroupVersion,
+				"ingresses":                extensions.SchemeGroupVersion,
+				"horizontalpodautoscalers": autoscaling.SchemeGroupVersion,
+			},
+		},
+		{
+			Desc: "fallback version and disabled resource",
+			Resources: []*metav1.APIResourceList{
+				{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods"}}},
+				{GroupVersion: "networking.k8s.io/v1beta1", APIResources: []metav1.APIResource{{Name: "ingresses"}}},
+				{GroupVersion: "autoscaling/v1", APIResources: []metav1.APIResource{{Name: "horizontalpodautoscalers"}}},
+			},
+			Wanted: map[string]schema.GroupVersion{
+				"pods":      v1.SchemeGroupVersion,
+				"ingresses": networkingv1beta1.SchemeGroupVersion,
+			},
+		},
+		{
+			Desc: "nothing discovered",
+			Wanted: map[string]schema.GroupVersion{
+				"pods":                     v1.SchemeGroupVersion,
+				"ingresses":                extensions.SchemeGroupVersion,
+				"horizontalpodautoscalers": autoscaling.SchemeGroupVersion,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		kubeClient := fake.NewSimpleClientset()
+		kubeClient.Discovery().(*fakediscovery.FakeDiscovery).Resources = test.Resources
+
+		b := NewBuilder()
+		b.WithKubeClient(kubeClient)
+		if err := b.WithEnabledResources([]string{"horizontalpodautoscalers", "ingresses", "pods"}); err != nil {
+			t.Fatal(err)
+		}
+
+		got := b.discoverGroupVersions()
+		if !reflect.DeepEqual(got, test.Wanted) {
+			t.Errorf("Test error for Desc: %s. Want: %v. Got: %v", test.Desc, test.Wanted, got)
+		}
+	}
+}
+
+func TestNetworkingIngressListWatch(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress1", Namespace: "ns1"},
+		Spec: networkingv1beta1.IngressSpec{
+			Rules: []networkingv1beta1.IngressRule{{Host: "example.com"}},
+		},
+	})
+
+	lw := createNetworkingIngressListWatch(kubeClient, metav1.NamespaceAll, func(*metav1.ListOptions) {})
+	obj, err := lw.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, ok := obj.(*extensions.IngressList)
+	if !ok {
+		t.Fatalf("expected *v1beta1.IngressList, got %T", obj)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "ingress1" || list.Items[0].Spec.Rules[0].Host != "example.com" {
+		t.Errorf("unexpected converted ingresses: %+v", list.Items)
+	}
+}
+
+func TestWithResyncPeriods(t *testing.T) {
+	tests := []struct {
+		Desc          string
+		ResyncPeriods map[string]time.Duration
+		WantedError   bool
+	}{
+		{
+			Desc:          "default and resource periods",
+			ResyncPeriods: map[string]time.Duration{"default": 5 * time.Minute, "pods": 0},
+		},
+		{
+			Desc:          "unknown resource",
+			ResyncPeriods: map[string]time.Duration{"foos": time.Minute},
+			WantedError:   true,
+		},
+		{
+			Desc:          "negative period",
+			ResyncPeriods: map[string]time.Duration{"nodes": -time.Minute},
+			WantedError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		b := NewBuilder()
+		if err := b.WithResyncPeriods(test.ResyncPeriods); (err != nil) != test.WantedError {
+			t.Errorf("Test error for Desc: %s. Wanted Error: %v, Got Error: %v", test.Desc, test.WantedError, err)
+		}
+	}
+}
+
+func TestWithCustomStores(t *testing.T) {
+	listWatchFunc := func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
+		return &cache.ListWatch{}
+	}
+
+	tests := []struct {
+		Desc         string
+		CustomStores []ksmtypes.CustomStore
+		WantedError  bool
+	}{
+		{
+			Desc: "valid custom store",
+			CustomStores: []ksmtypes.CustomStore{
+				{Resource: "foos", ExpectedType: &v1.ConfigMap{}, ListWatchFunc: listWatchFunc},
+			},
+		},
+		{
+			Desc: "missing resource name",
+			CustomStores: []ksmtypes.CustomStore{
+				{ExpectedType: &v1.ConfigMap{}, ListWatchFunc: listWatchFunc},
+			},
+			WantedError: true,
+		},
+		{
+			Desc: "collision with built-in resource",
+			CustomStores: []ksmtypes.CustomStore{
+				{Resource: "configmaps", ExpectedType: &v1.ConfigMap{}, ListWatchFunc: listWatchFunc},
+			},
+			WantedError: true,
+		},
+		{
+			Desc: "duplicate custom store",
+			CustomStores: []ksmtypes.CustomStore{
+				{Resource: "foos", ExpectedType: &v1.ConfigMap{}, ListWatchFunc: listWatchFunc},
+				{Resource: "foos", ExpectedType: &v1.ConfigMap{}, ListWatchFunc: listWatchFunc},
+			},
+			WantedError: true,
+		},
+		{
+			Desc: "missing ListWatchFunc",
+			CustomStores: []ksmtypes.CustomStore{
+				{Resource: "foos", ExpectedType: &v1.ConfigMap{}},
+			},
+			WantedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		b := NewBuilder()
+		if err := b.WithCustomStores(test.CustomStores); (err != nil) != test.WantedError {
+			t.Errorf("Test error for Desc: %s. Wanted Error: %v, Got Error: %v", test.Desc, test.WantedError, err)
+		}
+	}
+}
+
+func TestWithExtraFamilyGenerators(t *testing.T) {
+	family := func(name string) generator.FamilyGenerator {
+		return generator.FamilyGenerator{
+			Name: name,
+			Type: metric.Gauge,
+			Help: "Extra metric family.",
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				return &metric.Family{Metrics: []*metric.Metric{{Value: 1}}}
+			},
+		}
+	}
+
+	tests := []struct {
+		Desc        string
+		Resource    string
+		Families    []generator.FamilyGenerator
+		WantedError bool
+	}{
+		{
+			Desc:     "extra pod metric family",
+			Resource: "pods",
+			Families: []generator.FamilyGenerator{family("kube_pod_acme_team")},
+		},
+		{
+			Desc:        "unknown resource",
+			Resource:    "foos",
+			Families:    []generator.FamilyGenerator{family("kube_foo_acme_team")},
+			WantedError: true,
+		},
+		{
+			Desc:        "collision with built-in metric family",
+			Resource:    "pods",
+			Families:    []generator.FamilyGenerator{family("kube_pod_info")},
+			WantedError: true,
+		},
+		{
+			Desc:        "duplicate extra metric family",
+			Resource:    "pods",
+			Families:    []generator.FamilyGenerator{family("kube_pod_acme_team"), family("kube_pod_acme_team")},
+			WantedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		b := NewBuilder()
+		if err := b.WithExtraFamilyGenerators(test.Resource, test.Families); (err != nil) != test.WantedError {
+			t.Errorf("Test error for Desc: %s. Wanted Error: %v, Got Error: %v", test.Desc, test.WantedError, err)
+		}
+	}
+
+	b := NewBuilder()
+	if err := b.WithExtraFamilyGenerators("pods", []generator.FamilyGenerator{family("kube_pod_acme_team")}); err != nil {
+		t.Fatal(err)
+	}
+	families := b.metricFamilies("pods")
+	if len(families) != len(podMetricFamilies)+1 || len(availableMetricFamilies["pods"]) != len(podMetricFamilies) {
+		t.Fatalf("expected the extra metric family to be appended to a copy of the built-in ones, got %d metric families", len(families))
+	}
+
+	extra := families[len(families)-1]
+	m := extra.GenerateFunc(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1"}}).Metrics[0]
+	if !reflect.DeepEqual(m.LabelKeys, []string{"namespace", "pod"}) || !reflect.DeepEqual(m.LabelValues, []string{"ns1", "pod1"}) {
+		t.Errorf("expected the default pod labels, got keys %v and values %v", m.LabelKeys, m.LabelValues)
+	}
+}
+
+func TestWithDefaultLabelRemaps(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Remaps      map[string]map[string]string
+		WantedError bool
+	}{
+		{
+			Desc: "rename namespace and identity labels",
+			Remaps: map[string]map[string]string{
+				"pods":                     {"namespace": "exported_namespace"},
+				"horizontalpodautoscalers": {"namespace": "exported_namespace", "horizontalpodautoscaler": "name"},
+			},
+		},
+		{
+			Desc:        "unknown resource",
+			Remaps:      map[string]map[string]string{"foos": {"namespace": "exported_namespace"}},
+			WantedError: true,
+		},
+		{
+			Desc:        "not a default label",
+			Remaps:      map[string]map[string]string{"pods": {"node": "exported_node"}},
+			WantedError: true,
+		},
+		{
+			Desc:        "collision with another default label",
+			Remaps:      map[string]map[string]string{"pods": {"pod": "namespace"}},
+			WantedError: true,
+		},
+		{
+			Desc:        "swapped default labels",
+			Remaps:      map[string]map[string]string{"pods": {"pod": "namespace", "namespace": "pod"}},
+			WantedError: false,
+		},
+		{
+			Desc:        "collision with converted Kubernetes labels",
+			Remaps:      map[string]map[string]string{"pods": {"pod": "label_app"}},
+			WantedError: true,
+		},
+		{
+			Desc:        "collision with a metric family label",
+			Remaps:      map[string]map[string]string{"pods": {"pod": "phase"}},
+			WantedError: true,
+		},
+		{
+			Desc:        "collision with a metric family label named like a default label of another resource",
+			Remaps:      map[string]map[string]string{"pods": {"namespace": "node"}},
+			WantedError: true,
+		},
+		{
+			Desc:        "invalid label name",
+			Remaps:      map[string]map[string]string{"pods": {"pod": "exported-pod"}},
+			WantedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		b := NewBuilder()
+		if err := b.WithDefaultLabelRemaps(test.Remaps); (err != nil) != test.WantedError {
+			t.Errorf("Test error for Desc: %s. Wanted Error: %v, Got Error: %v", test.Desc, test.WantedError, err)
+		}
+	}
+
+	b := NewBuilder()
+	err := b.WithDefaultLabelRemaps(map[string]map[string]string{"pods": {"namespace": "exported_namespace", "pod": "name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1", UID: "uid1"},
+		Spec:       v1.PodSpec{NodeName: "node1"},
+	}
+	for _, f := range b.metricFamilies("pods") {
+		if f.Name != "kube_pod_info" {
+			continue
+		}
+		m := f.Generate(pod).Metrics[0]
+		if m.LabelKeys[0] != "exported_namespace" || m.LabelKeys[1] != "name" || m.LabelValues[0] != "ns1" || m.LabelValues[1] != "pod1" {
+			t.Errorf("expected renamed default labels, got keys %v and values %v", m.LabelKeys, m.LabelValues)
+		}
+	}
+	for _, f := range availableMetricFamilies["pods"] {
+		if f.Name != "kube_pod_info" {
+			continue
+		}
+		if m := f.Generate(pod).Metrics[0]; m.LabelKeys[0] != "namespace" || m.LabelKeys[1] != "pod" {
+			t.Errorf("expected the built-in metric families to be unaffected, got keys %v", m.LabelKeys)
+		}
+	}
+}
+
+func TestAPIServerCacheListWatch(t *testing.T) {
+	tests := []struct {
+		Desc                  string
+		Options               metav1.ListOptions
+		Watch                 bool
+		WantedResourceVersion string
+	}{
+		{
+			Desc:                  "initial list",
+			Options:               metav1.ListOptions{},
+			WantedResourceVersion: "0",
+		},
+		{
+			Desc:                  "list with resource version",
+			Options:               metav1.ListOptions{ResourceVersion: "42"},
+			WantedResourceVersion: "0",
+		},
+		{
+			Desc:                  "continued list",
+			Options:               metav1.ListOptions{Continue: "token"},
+			WantedResourceVersion: "",
+		},
+		{
+			Desc:                  "watch",
+			Options:               metav1.ListOptions{ResourceVersion: "42"},
+			Watch:                 true,
+			WantedResourceVersion: "42",
+		},
+	}
+
+	for _, test := range tests {
+		var got metav1.ListOptions
+		lw := apiserverCacheListWatch{&cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				got = opts
+				return &v1.PodList{}, nil
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				got = opts
+				return watch.NewFake(), nil
+			},
+		}}
+
+		var err error
+		if test.Watch {
+			_, err = lw.Watch(test.Options)
+		} else {
+			_, err = lw.List(test.Options)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ResourceVersion != test.WantedResourceVersion {
+			t.Errorf("Test error for Desc: %s. Want resource version %q. Got: %q", test.Desc, test.WantedResourceVersion, got.ResourceVersion)
+		}
+	}
+}
+
+func TestWithMetricPrefix(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Prefix      string
+		WantedError bool
+		Wanted      []string
+	}{
+		{
+			Desc:   "default prefix",
+			Prefix: "kube_",
+			Wanted: []string{"kube_pod_info", "kube_pod_labels"},
+		},
+		{
+			Desc:   "custom prefix",
+			Prefix: "ksm_staging_",
+			Wanted: []string{"ksm_staging_pod_info", "ksm_staging_pod_labels"},
+		},
+		{
+			Desc:        "empty prefix",
+			Prefix:      "",
+			WantedError: true,
+		},
+		{
+			Desc:        "invalid prefix",
+			Prefix:      "ksm-staging_",
+			WantedError: true,
+		},
+	}
+
+	families := []generator.FamilyGenerator{{Name: "kube_pod_info"}, {Name: "kube_pod_labels"}}
+
+	for _, test := range tests {
+		b := NewBuilder()
+		err := b.WithMetricPrefix(test.Prefix)
+		if (err != nil) != test.WantedError {
+			t.Errorf("Test error for Desc: %s. Wanted Error: %v, Got Error: %v", test.Desc, test.WantedError, err)
+			continue
+		}
+		if test.WantedError {
+			continue
+		}
+
+		var got []string
+		for _, f := range generator.PrefixMetricFamilies(b.metricPrefix, families) {
+			got = append(got, f.Name)
+		}
+		if !reflect.DeepEqual(got, test.Wanted) {
+			t.Errorf("Test error for Desc: %s. Want: %v. Got: %v", test.Desc, test.Wanted, got)
+		}
+	}
+
+	if families[0].Name != "kube_pod_info" {
+		t.Errorf("expected the given metric families to stay unchanged, got %s", families[0].Name)
+	}
+}
+
+func TestRecoverMetricFamilies(t *testing.T) {
+	r := prometheus.NewRegistry()
+	b := NewBuilder()
+	b.WithMetrics(r)
+
+	families := b.recoverMetricFamilies("pods", []generator.FamilyGenerator{
+		{
+			Name: "kube_pod_info",
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				return &metric.Family{Metrics: []*metric.Metric{{Value: 1}}}
+			},
+		},
+		{
+			Name: "kube_pod_status_phase",
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				var status *v1.PodStatus
+				return &metric.Family{Metrics: []*metric.Metric{{LabelValues: []string{string(status.Phase)}}}}
+			},
+		},
+		{
+			Name: "kube_pod_owner",
+			Type: metric.Info,
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				return &metric.Family{Metrics: []*metric.Metric{{Value: 2}}}
+			},
+		},
+	})
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1"}}
+	got := generator.ComposeMetricGenFuncs(families)(pod)
+
+	if len(got) != 3 || len(got[0].(*metric.Family).Metrics) != 1 || len(got[1].(*metric.Family).Metrics) != 0 || len(got[2].(*metric.Family).Metrics) != 0 {
+		t.Errorf("expected the panicking family and the info family with a value to be skipped only, got %v", got)
+	}
+
+	mfs, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "kube_state_metrics_generator_errors_total" {
+			continue
+		}
+		errors := map[string]float64{}
+		for _, m := range mf.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			errors[labels["resource"]+"/"+labels["family"]] = m.GetCounter().GetValue()
+		}
+		want := map[string]float64{"pods/kube_pod_status_phase": 1, "pods/kube_pod_owner": 1}
+		if !reflect.DeepEqual(errors, want) {
+			t.Errorf("expected generator errors %v, got %v", want, errors)
+		}
+		return
+	}
+	t.Error("expected kube_state_metrics_generator_errors_total to be exposed")
+}
+
+func TestWithUIDLabel(t *testing.T) {
+	for resource, name := range infoMetricFamilies {
+		found := false
+		for _, f := range availableMetricFamilies[resource] {
+			found = found || f.Name == name
+		}
+		if !found {
+			t.Errorf("info metric family %s of resource %s does not exist", name, resource)
+		}
+	}
+
+	objects := map[string]interface{}{
+		"pods": &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1", UID: "uid1"},
+		},
+		"nodes": &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1", UID: "uid2"},
+		},
+		"horizontalpodautoscalers": &autoscaling.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "hpa1", Namespace: "ns1", UID: "uid3"},
+		},
+	}
+
+	for _, addUIDLabel := range []bool{false, true} {
+		b := NewBuilder()
+		b.WithUIDLabel(addUIDLabel)
+		for resource, obj := range objects {
+			o, _ := obj.(metav1.Object)
+			for _, f := range b.metricFamilies(resource) {
+				if f.Name != infoMetricFamilies[resource] {
+					continue
+				}
+				m := f.Generate(obj).Metrics[0]
+				uids := 0
+				for i, key := range m.LabelKeys {
+					if key != "uid" {
+						continue
+					}
+					uids++
+					if m.LabelValues[i] != string(o.GetUID()) {
+						t.Errorf("expected uid %s on %s, got %s", o.GetUID(), f.Name, m.LabelValues[i])
+					}
+				}
+				// kube_pod_info always carries the uid.
+				want := 0
+				if addUIDLabel || resource == "pods" {
+					want = 1
+				}
+				if uids != want {
+					t.Errorf("expected %d uid labels on %s with the uid label added %v, got keys %v", want, f.Name, addUIDLabel, m.LabelKeys)
+				}
+			}
+		}
+	}
+}
+
+func TestWithSkipAnnotation(t *testing.T) {
+	for key, wantedError := range map[string]bool{
+		"":                                   false,
+		"kube-state-metrics.io/skip-metrics": false,
+		"skip":                               false,
+		"kube-state-metrics.io/":             true,
+		"skip metrics":                       true,
+	} {
+		b := NewBuilder()
+		if err := b.WithSkipAnnotation(key); (err != nil) != wantedError {
+			t.Errorf("skip annotation %q: wanted error %v, got %v", key, wantedError, err)
+		}
+	}
+}
+
+func TestWithMaxLabelValueLength(t *testing.T) {
+	for max, wantedError := range map[int]bool{-1: true, 0: false, 3: true, 4: false, 1024: false} {
+		b := NewBuilder()
+		if err := b.WithMaxLabelValueLength(max); (err != nil) != wantedError {
+			t.Errorf("maximum label value length %d: wanted error %v, got %v", max, wantedError, err)
+		}
+	}
+
+	r := prometheus.NewRegistry()
+	b := NewBuilder()
+	b.WithMetrics(r)
+	if err := b.WithMaxLabelValueLength(8); err != nil {
+		t.Fatal(err)
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-pod-with-a-long-name",
+			Namespace: "ns1",
+			Labels: map[string]string{
+				"app":    "web",
+				"config": `{"replicas": 3}`,
+				"owner":  "payments-team",
+			},
+		},
+	}
+	for _, f := range b.metricFamilies("pods") {
+		if f.Name != "kube_pod_labels" {
+			continue
+		}
+		m := f.Generate(pod).Metrics[0]
+		want := []string{"ns1", "a-pod-with-a-long-name", "web", `{"rep...`, "payme..."}
+		if !reflect.DeepEqual(m.LabelValues, want) {
+			t.Errorf("expected the converted label values only to be truncated to %v, got %v", want, m.LabelValues)
+		}
+	}
+
+	mfs, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "kube_state_metrics_label_values_truncated_total" {
+			continue
+		}
+		if len(mf.GetMetric()) != 1 || mf.GetMetric()[0].GetLabel()[0].GetValue() != "pods" || mf.GetMetric()[0].GetCounter().GetValue() != 2 {
+			t.Errorf("expected two truncated label values of pods, got %v", mf.GetMetric())
+		}
+		return
+	}
+	t.Error("expected kube_state_metrics_label_values_truncated_total to be exposed")
+}
+
+func TestTimeMetricFamilies(t *testing.T) {
+	b := NewBuilder()
+	if err := b.WithFamilyGenerationDurationSampling(-1); err == nil {
+		t.Error("expected negative sampling to be rejected")
+	}
+
+	r := prometheus.NewRegistry()
+	b.WithMetrics(r)
+	families := []generator.FamilyGenerator{
+		{
+			Name: "kube_pod_info",
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				return &metric.Family{Metrics: []*metric.Metric{{Value: 1}}}
+			},
+		},
+	}
+	if timed := b.timeMetricFamilies("pods", families); len(timed) != 1 || reflect.ValueOf(timed[0].GenerateFunc).Pointer() != reflect.ValueOf(families[0].GenerateFunc).Pointer() {
+		t.Error("expected the metric families not to be timed while disabled")
+	}
+
+	if err := b.WithFamilyGenerationDurationSampling(2); err != nil {
+		t.Fatal(err)
+	}
+	timed := b.timeMetricFamilies("pods", families)
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1"}}
+	for i := 0; i < 5; i++ {
+		if m := timed[0].Generate(pod).Metrics; len(m) != 1 {
+			t.Fatalf("expected the generated metrics to be unchanged, got %v", m)
+		}
+	}
+
+	mfs, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "kube_state_metrics_family_generation_duration_seconds" {
+			continue
+		}
+		if len(mf.GetMetric()) != 1 {
+			t.Fatalf("expected a single series, got %v", mf.GetMetric())
+		}
+		labels := map[string]string{}
+		for _, l := range mf.GetMetric()[0].GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if labels["resource"] != "pods" || labels["family"] != "kube_pod_info" || mf.GetMetric()[0].GetHistogram().GetSampleCount() != 2 {
+			t.Errorf("expected every second of 5 generations of kube_pod_info of pods to be observed, got %v", mf.GetMetric()[0])
+		}
+		return
+	}
+	t.Error("expected kube_state_metrics_family_generation_duration_seconds to be exposed")
+}
+
+func TestCountDuplicateConditions(t *testing.T) {
+	names := map[string]bool{}
+	for _, families := range availableMetricFamilies {
+		for _, f := range families {
+			names[f.Name] = true
+		}
+	}
+	for name := range conditionFamilies {
+		if !names[name] {
+			t.Errorf("expected condition family %s to be an available metric family", name)
+		}
+	}
+
+	r := prometheus.NewRegistry()
+	b := NewBuilder()
+	b.WithMetrics(r)
+	if err := b.WithMetricPrefix("custom_"); err != nil {
+		t.Fatal(err)
+	}
+	other := prometheus.NewRegistry()
+	NewBuilder().WithMetrics(other)
+
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: v1.ConditionFalse, LastTransitionTime: metav1.Unix(1500000000, 0)},
+				{Type: v1.NodeReady, Status: v1.ConditionTrue, LastTransitionTime: metav1.Unix(1600000000, 0)},
+			},
+		},
+	}
+	for _, f := range b.countDuplicateConditions(b.metricFamilies("nodes")) {
+		f.Generate(node)
+	}
+
+	mfs, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, mf := range mfs {
+		if mf.GetName() != "kube_state_metrics_duplicate_conditions_total" {
+			continue
+		}
+		found = true
+		if len(mf.GetMetric()) != 1 || mf.GetMetric()[0].GetLabel()[0].GetValue() != "custom_node_status_condition" || mf.GetMetric()[0].GetCounter().GetValue() != 1 {
+			t.Errorf("expected a single dropped condition of custom_node_status_condition, got %v", mf.GetMetric())
+		}
+	}
+	if !found {
+		t.Error("expected kube_state_metrics_duplicate_conditions_total to be exposed")
+	}
+
+	mfs, err = other.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == "kube_state_metrics_duplicate_conditions_total" {
+			t.Errorf("expected the dropped conditions not to be counted by another builder, got %v", mf.GetMetric())
+		}
+	}
+}
+
+func TestWithCustomLabels(t *testing.T) {
+	tests := []struct {
+		Desc         string
+		CustomLabels map[string]string
+		WantedError  bool
+		WantedKeys   []string
+		WantedValues []string
+	}{
+		{
+			Desc:         "labels ordered by name",
+			CustomLabels: map[string]string{"region": "eu-west-1", "cluster": "prod", "env": "staging"},
+			WantedKeys:   []string{"cluster", "env", "region"},
+			WantedValues: []string{"prod", "staging", "eu-west-1"},
+		},
+		{
+			Desc:         "collision with default label",
+			CustomLabels: map[string]string{"cluster": "prod", "namespace": "x"},
+			WantedError:  true,
+		},
+		{
+			Desc:         "collision with kubernetes labels",
+			CustomLabels: map[string]string{"label_app": "web"},
+			WantedError:  true,
+		},
+		{
+			Desc:         "collision with kubernetes annotations",
+			CustomLabels: map[string]string{"annotation_team": "payments"},
+			WantedError:  true,
+		},
+		{
+			Desc:         "collision with metric family label",
+			CustomLabels: map[string]string{"status": "x"},
+			WantedError:  true,
+		},
+		{
+			Desc:         "invalid name",
+			CustomLabels: map[string]string{"k8s-cluster": "prod"},
+			WantedError:  true,
+		},
+		{
+			Desc:         "reserved name",
+			CustomLabels: map[string]string{"__cluster": "prod"},
+			WantedError:  true,
+		},
+	}
+
+	for _, test := range tests {
+		b := NewBuilder()
+		err := b.WithCustomLabels(test.CustomLabels)
+		if (err != nil) != test.WantedError {
+			t.Errorf("Test error for Desc: %s. Wanted Error: %v, Got Error: %v", test.Desc, test.WantedError, err)
+			continue
+		}
+		if test.WantedError {
+			continue
+		}
+		if !reflect.DeepEqual(b.customLabelKeys, test.WantedKeys) || !reflect.DeepEqual(b.customLabelValues, test.WantedValues) {
+			t.Errorf("Test error for Desc: %s. Want: %v=%v. Got: %v=%v", test.Desc, test.WantedKeys, test.WantedValues, b.customLabelKeys, b.customLabelValues)
+		}
+	}
+}
@@ -56,17 +56,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapCSRFunc(func(csr *certv1beta1.CertificateSigningRequest) *metric.Family {
-				ms := []*metric.Metric{}
-				if !csr.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						LabelKeys:   []string{},
-						LabelValues: []string{},
-						Value:       float64(csr.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&csr.CreationTimestamp),
 				}
 			}),
 		},
@@ -105,21 +96,20 @@ func wrapCSRFunc(f func(*certv1beta1.CertificateSigningRequest) *metric.Family)
 
 		metricFamily := f(csr)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descCSRLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{csr.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descCSRLabelsDefaultLabels, []string{csr.Name})
 
 		return metricFamily
 	}
 }
 
-func createCSRListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createCSRListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CertificatesV1beta1().CertificateSigningRequests().List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CertificatesV1beta1().CertificateSigningRequests().Watch(opts)
 		},
 	}
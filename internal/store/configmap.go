@@ -34,14 +34,13 @@ var (
 	configMapMetricFamilies = []generator.FamilyGenerator{
 		{
 			Name: "kube_configmap_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about configmap.",
 			GenerateFunc: wrapConfigMapFunc(func(c *v1.ConfigMap) *metric.Family {
 				return &metric.Family{
 					Metrics: []*metric.Metric{{
 						LabelKeys:   []string{},
 						LabelValues: []string{},
-						Value:       1,
 					}},
 				}
 			}),
@@ -51,18 +50,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapConfigMapFunc(func(c *v1.ConfigMap) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !c.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						LabelKeys:   []string{},
-						LabelValues: []string{},
-						Value:       float64(c.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&c.CreationTimestamp),
 				}
 			}),
 		},
@@ -79,12 +68,14 @@ var (
 	}
 )
 
-func createConfigMapListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createConfigMapListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().ConfigMaps(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().ConfigMaps(ns).Watch(opts)
 		},
 	}
@@ -96,10 +87,7 @@ func wrapConfigMapFunc(f func(*v1.ConfigMap) *metric.Family) func(interface{}) *
 
 		metricFamily := f(configMap)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descConfigMapLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{configMap.Namespace, configMap.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descConfigMapLabelsDefaultLabels, []string{configMap.Namespace, configMap.Name})
 
 		return metricFamily
 	}
@@ -57,7 +57,7 @@ var (
 		},
 		{
 			Name: "kube_cronjob_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Info about cronjob.",
 			GenerateFunc: wrapCronJobFunc(func(j *batchv1beta1.CronJob) *metric.Family {
 				return &metric.Family{
@@ -65,7 +65,6 @@ var (
 						{
 							LabelKeys:   []string{"schedule", "concurrency_policy"},
 							LabelValues: []string{j.Spec.Schedule, string(j.Spec.ConcurrencyPolicy)},
-							Value:       1,
 						},
 					},
 				}
@@ -76,17 +75,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapCronJobFunc(func(j *batchv1beta1.CronJob) *metric.Family {
-				ms := []*metric.Metric{}
-				if !j.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						LabelKeys:   []string{},
-						LabelValues: []string{},
-						Value:       float64(j.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&j.CreationTimestamp),
 				}
 			}),
 		},
@@ -111,18 +101,8 @@ var (
 			Type: metric.Gauge,
 			Help: "LastScheduleTime keeps information of when was the last time the job was successfully scheduled.",
 			GenerateFunc: wrapCronJobFunc(func(j *batchv1beta1.CronJob) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if j.Status.LastScheduleTime != nil {
-					ms = append(ms, &metric.Metric{
-						LabelKeys:   []string{},
-						LabelValues: []string{},
-						Value:       float64(j.Status.LastScheduleTime.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(j.Status.LastScheduleTime),
 				}
 			}),
 		},
@@ -200,21 +180,20 @@ func wrapCronJobFunc(f func(*batchv1beta1.CronJob) *metric.Family) func(interfac
 
 		metricFamily := f(cronJob)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descCronJobLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{cronJob.Namespace, cronJob.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descCronJobLabelsDefaultLabels, []string{cronJob.Namespace, cronJob.Name})
 
 		return metricFamily
 	}
 }
 
-func createCronJobListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createCronJobListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.BatchV1beta1().CronJobs(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.BatchV1beta1().CronJobs(ns).Watch(opts)
 		},
 	}
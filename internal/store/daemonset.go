@@ -38,14 +38,43 @@ var (
 			Name: "kube_daemonset_created",
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
+			GenerateFunc: wrapDaemonSetFunc(func(d *v1.DaemonSet) *metric.Family {
+				return &metric.Family{
+					Metrics: timestampMetrics(&d.CreationTimestamp),
+				}
+			}),
+		},
+		{
+			Name: "kube_daemonset_status_condition",
+			Type: metric.StateSet,
+			Help: "The current status conditions of a daemonset.",
+			GenerateFunc: wrapDaemonSetFunc(func(d *v1.DaemonSet) *metric.Family {
+				ms := make([]*metric.Metric, 0, len(d.Status.Conditions)*len(conditionStatuses))
+
+				for _, c := range latestConditions(daemonSetConditions(d)) {
+					ms = append(ms, addConditionMetricsWithType(c.conditionType, c.status)...)
+				}
+
+				return &metric.Family{
+					Metrics: ms,
+				}
+			}),
+		},
+		{
+			Name: "kube_daemonset_status_condition_reason",
+			Type: metric.Gauge,
+			Help: "The reason of the last transition of the current status conditions of a daemonset, for conditions with a reason.",
 			GenerateFunc: wrapDaemonSetFunc(func(d *v1.DaemonSet) *metric.Family {
 				ms := []*metric.Metric{}
 
-				if !d.CreationTimestamp.IsZero() {
+				for _, c := range latestConditions(daemonSetConditions(d)) {
+					if c.reason == "" {
+						continue
+					}
 					ms = append(ms, &metric.Metric{
-						LabelKeys:   []string{},
-						LabelValues: []string{},
-						Value:       float64(d.CreationTimestamp.Unix()),
+						LabelKeys:   []string{"condition", "reason"},
+						LabelValues: []string{c.conditionType, c.reason},
+						Value:       1,
 					})
 				}
 
@@ -200,27 +229,35 @@ var (
 	}
 )
 
+// daemonSetConditions returns the status conditions of the given daemonset.
+func daemonSetConditions(d *v1.DaemonSet) []condition {
+	cs := make([]condition, len(d.Status.Conditions))
+	for i, c := range d.Status.Conditions {
+		cs[i] = condition{conditionType: string(c.Type), status: c.Status, reason: c.Reason, lastTransitionTime: c.LastTransitionTime}
+	}
+	return cs
+}
+
 func wrapDaemonSetFunc(f func(*v1.DaemonSet) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		daemonSet := obj.(*v1.DaemonSet)
 
 		metricFamily := f(daemonSet)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descDaemonSetLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{daemonSet.Namespace, daemonSet.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descDaemonSetLabelsDefaultLabels, []string{daemonSet.Namespace, daemonSet.Name})
 
 		return metricFamily
 	}
 }
 
-func createDaemonSetListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createDaemonSetListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AppsV1().DaemonSets(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AppsV1().DaemonSets(ns).Watch(opts)
 		},
 	}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
@@ -215,6 +216,46 @@ func TestDaemonSetStore(t *testing.T) {
 				"kube_daemonset_updated_number_scheduled",
 			},
 		},
+		{
+			// The core API defines no daemonset condition types, so custom
+			// ones set by other controllers are exposed as they are.
+			Obj: &v1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ds4",
+					Namespace: "ns4",
+				},
+				Status: v1.DaemonSetStatus{
+					Conditions: []v1.DaemonSetCondition{
+						{
+							Type:   "PlacementFailed",
+							Status: corev1.ConditionTrue,
+							Reason: "InsufficientMemory",
+						},
+						{
+							Type:   "ImagePrePulled",
+							Status: corev1.ConditionUnknown,
+						},
+					},
+				},
+			},
+			Want: `
+				# HELP kube_daemonset_status_condition The current status conditions of a daemonset.
+				# HELP kube_daemonset_status_condition_reason The reason of the last transition of the current status conditions of a daemonset, for conditions with a reason.
+				# TYPE kube_daemonset_status_condition gauge
+				# TYPE kube_daemonset_status_condition_reason gauge
+				kube_daemonset_status_condition{condition="ImagePrePulled",daemonset="ds4",namespace="ns4",status="false"} 0
+				kube_daemonset_status_condition{condition="ImagePrePulled",daemonset="ds4",namespace="ns4",status="true"} 0
+				kube_daemonset_status_condition{condition="ImagePrePulled",daemonset="ds4",namespace="ns4",status="unknown"} 1
+				kube_daemonset_status_condition{condition="PlacementFailed",daemonset="ds4",namespace="ns4",status="false"} 0
+				kube_daemonset_status_condition{condition="PlacementFailed",daemonset="ds4",namespace="ns4",status="true"} 1
+				kube_daemonset_status_condition{condition="PlacementFailed",daemonset="ds4",namespace="ns4",status="unknown"} 0
+				kube_daemonset_status_condition_reason{condition="PlacementFailed",daemonset="ds4",namespace="ns4",reason="InsufficientMemory"} 1
+`,
+			MetricNames: []string{
+				"kube_daemonset_status_condition",
+				"kube_daemonset_status_condition_reason",
+			},
+		},
 	}
 	for i, c := range cases {
 		c.Func = generator.ComposeMetricGenFuncs(daemonSetMetricFamilies)
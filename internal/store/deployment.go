@@ -40,16 +40,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapDeploymentFunc(func(d *v1.Deployment) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !d.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						Value: float64(d.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&d.CreationTimestamp),
 				}
 			}),
 		},
@@ -125,21 +117,13 @@ var (
 		},
 		{
 			Name: "kube_deployment_status_condition",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "The current status conditions of a deployment.",
 			GenerateFunc: wrapDeploymentFunc(func(d *v1.Deployment) *metric.Family {
-				ms := make([]*metric.Metric, len(d.Status.Conditions)*len(conditionStatuses))
+				ms := make([]*metric.Metric, 0, len(d.Status.Conditions)*len(conditionStatuses))
 
-				for i, c := range d.Status.Conditions {
-					conditionMetrics := addConditionMetrics(c.Status)
-
-					for j, m := range conditionMetrics {
-						metric := m
-
-						metric.LabelKeys = []string{"condition", "status"}
-						metric.LabelValues = append([]string{string(c.Type)}, metric.LabelValues...)
-						ms[i*len(conditionStatuses)+j] = metric
-					}
+				for _, c := range latestConditions(deploymentConditions(d)) {
+					ms = append(ms, addConditionMetricsWithType(c.conditionType, c.status)...)
 				}
 
 				return &metric.Family{
@@ -255,27 +239,35 @@ var (
 	}
 )
 
+// deploymentConditions returns the status conditions of the given deployment.
+func deploymentConditions(d *v1.Deployment) []condition {
+	cs := make([]condition, len(d.Status.Conditions))
+	for i, c := range d.Status.Conditions {
+		cs[i] = condition{conditionType: string(c.Type), status: c.Status, lastTransitionTime: c.LastTransitionTime}
+	}
+	return cs
+}
+
 func wrapDeploymentFunc(f func(*v1.Deployment) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		deployment := obj.(*v1.Deployment)
 
 		metricFamily := f(deployment)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descDeploymentLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{deployment.Namespace, deployment.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descDeploymentLabelsDefaultLabels, []string{deployment.Namespace, deployment.Name})
 
 		return metricFamily
 	}
 }
 
-func createDeploymentListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createDeploymentListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AppsV1().Deployments(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AppsV1().Deployments(ns).Watch(opts)
 		},
 	}
@@ -36,14 +36,12 @@ var (
 	endpointMetricFamilies = []generator.FamilyGenerator{
 		{
 			Name: "kube_endpoint_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about endpoint.",
 			GenerateFunc: wrapEndpointFunc(func(e *v1.Endpoints) *metric.Family {
 				return &metric.Family{
 					Metrics: []*metric.Metric{
-						{
-							Value: 1,
-						},
+						{},
 					},
 				}
 			}),
@@ -53,17 +51,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapEndpointFunc(func(e *v1.Endpoints) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !e.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-
-						Value: float64(e.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&e.CreationTimestamp),
 				}
 			}),
 		},
@@ -130,21 +119,20 @@ func wrapEndpointFunc(f func(*v1.Endpoints) *metric.Family) func(interface{}) *m
 
 		metricFamily := f(endpoint)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descEndpointLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{endpoint.Namespace, endpoint.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descEndpointLabelsDefaultLabels, []string{endpoint.Namespace, endpoint.Name})
 
 		return metricFamily
 	}
 }
 
-func createEndpointsListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createEndpointsListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Endpoints(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Endpoints(ns).Watch(opts)
 		},
 	}
@@ -50,6 +50,21 @@ var (
 	targetMetricLabels = []string{"metric_name", "metric_target_type"}
 
 	hpaMetricFamilies = []generator.FamilyGenerator{
+		{
+			Name: "kube_horizontalpodautoscaler_info",
+			Type: metric.Info,
+			Help: "Information about this autoscaler.",
+			GenerateFunc: wrapHPAFunc(func(a *autoscaling.HorizontalPodAutoscaler) *metric.Family {
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   []string{"scaletargetref_api_version", "scaletargetref_kind", "scaletargetref_name"},
+							LabelValues: []string{a.Spec.ScaleTargetRef.APIVersion, a.Spec.ScaleTargetRef.Kind, a.Spec.ScaleTargetRef.Name},
+						},
+					},
+				}
+			}),
+		},
 		{
 			Name: "kube_horizontalpodautoscaler_metadata_generation",
 			Type: metric.Gauge,
@@ -201,20 +216,13 @@ var (
 		},
 		{
 			Name: "kube_horizontalpodautoscaler_status_condition",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "The condition of this autoscaler.",
 			GenerateFunc: wrapHPAFunc(func(a *autoscaling.HorizontalPodAutoscaler) *metric.Family {
 				ms := make([]*metric.Metric, 0, len(a.Status.Conditions)*len(conditionStatuses))
 
-				for _, c := range a.Status.Conditions {
-					metrics := addConditionMetrics(c.Status)
-
-					for _, m := range metrics {
-						metric := m
-						metric.LabelKeys = []string{"condition", "status"}
-						metric.LabelValues = append([]string{string(c.Type)}, metric.LabelValues...)
-						ms = append(ms, metric)
-					}
+				for _, c := range latestConditions(hpaConditions(a)) {
+					ms = append(ms, addConditionMetricsWithType(c.conditionType, c.status)...)
 				}
 
 				return &metric.Family{
@@ -225,27 +233,35 @@ var (
 	}
 )
 
+// hpaConditions returns the status conditions of the given autoscaler.
+func hpaConditions(a *autoscaling.HorizontalPodAutoscaler) []condition {
+	cs := make([]condition, len(a.Status.Conditions))
+	for i, c := range a.Status.Conditions {
+		cs[i] = condition{conditionType: string(c.Type), status: c.Status, lastTransitionTime: c.LastTransitionTime}
+	}
+	return cs
+}
+
 func wrapHPAFunc(f func(*autoscaling.HorizontalPodAutoscaler) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		hpa := obj.(*autoscaling.HorizontalPodAutoscaler)
 
 		metricFamily := f(hpa)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descHorizontalPodAutoscalerLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{hpa.Namespace, hpa.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descHorizontalPodAutoscalerLabelsDefaultLabels, []string{hpa.Namespace, hpa.Name})
 
 		return metricFamily
 	}
 }
 
-func createHPAListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createHPAListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AutoscalingV2beta1().HorizontalPodAutoscalers(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AutoscalingV2beta1().HorizontalPodAutoscalers(ns).Watch(opts)
 		},
 	}
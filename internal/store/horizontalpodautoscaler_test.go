@@ -35,6 +35,7 @@ func TestHPAStore(t *testing.T) {
 	// Fixed metadata on type and help text. We prepend this to every expected
 	// output so we only have to modify a single place when doing adjustments.
 	const metadata = `
+		# HELP kube_horizontalpodautoscaler_info Information about this autoscaler.
 		# HELP kube_horizontalpodautoscaler_labels Kubernetes labels converted to Prometheus labels.
 		# HELP kube_horizontalpodautoscaler_metadata_generation The generation observed by the HorizontalPodAutoscaler controller.
 		# HELP kube_horizontalpodautoscaler_spec_max_replicas Upper limit for the number of pods that can be set by the autoscaler; cannot be smaller than MinReplicas.
@@ -43,6 +44,7 @@ func TestHPAStore(t *testing.T) {
 		# HELP kube_horizontalpodautoscaler_status_condition The condition of this autoscaler.
 		# HELP kube_horizontalpodautoscaler_status_current_replicas Current number of replicas of pods managed by this autoscaler.
 		# HELP kube_horizontalpodautoscaler_status_desired_replicas Desired number of replicas of pods managed by this autoscaler.
+		# TYPE kube_horizontalpodautoscaler_info gauge
 		# TYPE kube_horizontalpodautoscaler_labels gauge
 		# TYPE kube_horizontalpodautoscaler_metadata_generation gauge
 		# TYPE kube_horizontalpodautoscaler_spec_max_replicas gauge
@@ -157,6 +159,7 @@ func TestHPAStore(t *testing.T) {
 				},
 			},
 			Want: metadata + `
+				kube_horizontalpodautoscaler_info{horizontalpodautoscaler="hpa1",namespace="ns1",scaletargetref_api_version="apps/v1",scaletargetref_kind="Deployment",scaletargetref_name="deployment1"} 1
 				kube_horizontalpodautoscaler_labels{horizontalpodautoscaler="hpa1",label_app="foobar",namespace="ns1"} 1
 				kube_horizontalpodautoscaler_metadata_generation{horizontalpodautoscaler="hpa1",namespace="ns1"} 2
 				kube_horizontalpodautoscaler_spec_max_replicas{horizontalpodautoscaler="hpa1",namespace="ns1"} 4
@@ -176,6 +179,7 @@ func TestHPAStore(t *testing.T) {
 				kube_horizontalpodautoscaler_status_desired_replicas{horizontalpodautoscaler="hpa1",namespace="ns1"} 2
 			`,
 			MetricNames: []string{
+				"kube_horizontalpodautoscaler_info",
 				"kube_horizontalpodautoscaler_metadata_generation",
 				"kube_horizontalpodautoscaler_spec_max_replicas",
 				"kube_horizontalpodautoscaler_spec_min_replicas",
@@ -282,6 +286,7 @@ func TestHPAStore(t *testing.T) {
 				},
 			},
 			Want: metadata + `
+				kube_horizontalpodautoscaler_info{horizontalpodautoscaler="hpa2",namespace="ns1",scaletargetref_api_version="apps/v1",scaletargetref_kind="Deployment",scaletargetref_name="deployment1"} 1
 				kube_horizontalpodautoscaler_labels{horizontalpodautoscaler="hpa2",label_app="foobar",namespace="ns1"} 1
 				kube_horizontalpodautoscaler_metadata_generation{horizontalpodautoscaler="hpa2",namespace="ns1"} 2
 				kube_horizontalpodautoscaler_spec_max_replicas{horizontalpodautoscaler="hpa2",namespace="ns1"} 4
@@ -297,6 +302,7 @@ func TestHPAStore(t *testing.T) {
 				kube_horizontalpodautoscaler_status_desired_replicas{horizontalpodautoscaler="hpa2",namespace="ns1"} 2
 			`,
 			MetricNames: []string{
+				"kube_horizontalpodautoscaler_info",
 				"kube_horizontalpodautoscaler_metadata_generation",
 				"kube_horizontalpodautoscaler_spec_max_replicas",
 				"kube_horizontalpodautoscaler_spec_min_replicas",
@@ -307,6 +313,52 @@ func TestHPAStore(t *testing.T) {
 				"kube_horizontalpodautoscaler_labels",
 			},
 		},
+		{
+			// Only the latest condition of a type repeated by a buggy
+			// controller is exposed.
+			Obj: &autoscaling.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hpa3",
+					Namespace: "ns1",
+				},
+				Spec: autoscaling.HorizontalPodAutoscalerSpec{
+					MinReplicas: int32ptr(1),
+					MaxReplicas: 2,
+				},
+				Status: autoscaling.HorizontalPodAutoscalerStatus{
+					Conditions: []autoscaling.HorizontalPodAutoscalerCondition{
+						{
+							Type:               autoscaling.AbleToScale,
+							Status:             v1.ConditionFalse,
+							LastTransitionTime: metav1.Unix(1500000000, 0),
+						},
+						{
+							Type:               autoscaling.ScalingActive,
+							Status:             v1.ConditionTrue,
+							LastTransitionTime: metav1.Unix(1500000000, 0),
+						},
+						{
+							Type:               autoscaling.AbleToScale,
+							Status:             v1.ConditionTrue,
+							LastTransitionTime: metav1.Unix(1600000000, 0),
+						},
+					},
+				},
+			},
+			Want: `
+				# HELP kube_horizontalpodautoscaler_status_condition The condition of this autoscaler.
+				# TYPE kube_horizontalpodautoscaler_status_condition gauge
+				kube_horizontalpodautoscaler_status_condition{condition="AbleToScale",horizontalpodautoscaler="hpa3",namespace="ns1",status="false"} 0
+				kube_horizontalpodautoscaler_status_condition{condition="AbleToScale",horizontalpodautoscaler="hpa3",namespace="ns1",status="true"} 1
+				kube_horizontalpodautoscaler_status_condition{condition="AbleToScale",horizontalpodautoscaler="hpa3",namespace="ns1",status="unknown"} 0
+				kube_horizontalpodautoscaler_status_condition{condition="ScalingActive",horizontalpodautoscaler="hpa3",namespace="ns1",status="false"} 0
+				kube_horizontalpodautoscaler_status_condition{condition="ScalingActive",horizontalpodautoscaler="hpa3",namespace="ns1",status="true"} 1
+				kube_horizontalpodautoscaler_status_condition{condition="ScalingActive",horizontalpodautoscaler="hpa3",namespace="ns1",status="unknown"} 0
+			`,
+			MetricNames: []string{
+				"kube_horizontalpodautoscaler_status_condition",
+			},
+		},
 	}
 	for i, c := range cases {
 		c.Func = generator.ComposeMetricGenFuncs(hpaMetricFamilies)
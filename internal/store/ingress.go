@@ -17,11 +17,15 @@ limitations under the License.
 package store
 
 import (
+	"encoding/json"
+
 	"k8s.io/kube-state-metrics/pkg/metric"
 	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
 
 	"k8s.io/api/extensions/v1beta1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
@@ -37,14 +41,12 @@ var (
 	ingressMetricFamilies = []generator.FamilyGenerator{
 		{
 			Name: "kube_ingress_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about ingress.",
 			GenerateFunc: wrapIngressFunc(func(s *v1beta1.Ingress) *metric.Family {
 				return &metric.Family{
 					Metrics: []*metric.Metric{
-						{
-							Value: 1,
-						},
+						{},
 					}}
 			}),
 		},
@@ -70,16 +72,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapIngressFunc(func(i *v1beta1.Ingress) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !i.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						Value: float64(i.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&i.CreationTimestamp),
 				}
 			}),
 		},
@@ -144,22 +138,86 @@ func wrapIngressFunc(f func(*v1beta1.Ingress) *metric.Family) func(interface{})
 
 		metricFamily := f(ingress)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descIngressLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{ingress.Namespace, ingress.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descIngressLabelsDefaultLabels, []string{ingress.Namespace, ingress.Name})
 
 		return metricFamily
 	}
 }
 
-func createIngressListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createIngressListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.ExtensionsV1beta1().Ingresses(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.ExtensionsV1beta1().Ingresses(ns).Watch(opts)
 		},
 	}
 }
+
+// createNetworkingIngressListWatch lists and watches ingresses in the
+// networking.k8s.io/v1beta1 API group, for apiservers no longer serving them in
+// the extensions/v1beta1 one. The ingresses are converted to extensions/v1beta1
+// ingresses, so the same metric families apply.
+func createNetworkingIngressListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
+			list, err := kubeClient.NetworkingV1beta1().Ingresses(ns).List(opts)
+			if err != nil {
+				return nil, err
+			}
+
+			converted := &v1beta1.IngressList{
+				ListMeta: list.ListMeta,
+				Items:    make([]v1beta1.Ingress, 0, len(list.Items)),
+			}
+			for i := range list.Items {
+				ingress, err := convertNetworkingIngress(&list.Items[i])
+				if err != nil {
+					return nil, err
+				}
+				converted.Items = append(converted.Items, *ingress)
+			}
+			return converted, nil
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
+			w, err := kubeClient.NetworkingV1beta1().Ingresses(ns).Watch(opts)
+			if err != nil {
+				return nil, err
+			}
+
+			return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+				ingress, ok := event.Object.(*networkingv1beta1.Ingress)
+				if !ok {
+					return event, true
+				}
+				converted, err := convertNetworkingIngress(ingress)
+				if err != nil {
+					return watch.Event{Type: watch.Error, Object: &apierrors.NewInternalError(err).ErrStatus}, true
+				}
+				event.Object = converted
+				return event, true
+			}), nil
+		},
+	}
+}
+
+// convertNetworkingIngress converts a networking.k8s.io/v1beta1 ingress into an
+// extensions/v1beta1 one. Both share the same schema.
+func convertNetworkingIngress(in *networkingv1beta1.Ingress) (*v1beta1.Ingress, error) {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &v1beta1.Ingress{}
+	if err := json.Unmarshal(b, out); err != nil {
+		return nil, err
+	}
+	out.TypeMeta = metav1.TypeMeta{}
+	return out, nil
+}
@@ -55,14 +55,12 @@ var (
 		},
 		{
 			Name: "kube_job_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about job.",
 			GenerateFunc: wrapJobFunc(func(j *v1batch.Job) *metric.Family {
 				return &metric.Family{
 					Metrics: []*metric.Metric{
-						{
-							Value: 1,
-						},
+						{},
 					},
 				}
 			}),
@@ -72,16 +70,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapJobFunc(func(j *v1batch.Job) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !j.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						Value: float64(j.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&j.CreationTimestamp),
 				}
 			}),
 		},
@@ -183,18 +173,13 @@ var (
 		},
 		{
 			Name: "kube_job_complete",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "The job has completed its execution.",
 			GenerateFunc: wrapJobFunc(func(j *v1batch.Job) *metric.Family {
 				ms := []*metric.Metric{}
-				for _, c := range j.Status.Conditions {
-					if c.Type == v1batch.JobComplete {
-						metrics := addConditionMetrics(c.Status)
-						for _, m := range metrics {
-							metric := m
-							metric.LabelKeys = []string{"condition"}
-							ms = append(ms, metric)
-						}
+				for _, c := range latestConditions(jobConditions(j)) {
+					if c.conditionType == string(v1batch.JobComplete) {
+						ms = append(ms, addConditionMetrics("condition", c.status)...)
 					}
 				}
 
@@ -205,19 +190,14 @@ var (
 		},
 		{
 			Name: "kube_job_failed",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "The job has failed its execution.",
 			GenerateFunc: wrapJobFunc(func(j *v1batch.Job) *metric.Family {
 				ms := []*metric.Metric{}
 
-				for _, c := range j.Status.Conditions {
-					if c.Type == v1batch.JobFailed {
-						metrics := addConditionMetrics(c.Status)
-						for _, m := range metrics {
-							metric := m
-							metric.LabelKeys = []string{"condition"}
-							ms = append(ms, metric)
-						}
+				for _, c := range latestConditions(jobConditions(j)) {
+					if c.conditionType == string(v1batch.JobFailed) {
+						ms = append(ms, addConditionMetrics("condition", c.status)...)
 					}
 				}
 
@@ -231,17 +211,8 @@ var (
 			Type: metric.Gauge,
 			Help: "StartTime represents time when the job was acknowledged by the Job Manager.",
 			GenerateFunc: wrapJobFunc(func(j *v1batch.Job) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if j.Status.StartTime != nil {
-					ms = append(ms, &metric.Metric{
-
-						Value: float64(j.Status.StartTime.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(j.Status.StartTime),
 				}
 			}),
 		},
@@ -250,16 +221,8 @@ var (
 			Type: metric.Gauge,
 			Help: "CompletionTime represents time when the job was completed.",
 			GenerateFunc: wrapJobFunc(func(j *v1batch.Job) *metric.Family {
-				ms := []*metric.Metric{}
-				if j.Status.CompletionTime != nil {
-					ms = append(ms, &metric.Metric{
-
-						Value: float64(j.Status.CompletionTime.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(j.Status.CompletionTime),
 				}
 			}),
 		},
@@ -310,27 +273,35 @@ var (
 	}
 )
 
+// jobConditions returns the status conditions of the given job.
+func jobConditions(j *v1batch.Job) []condition {
+	cs := make([]condition, len(j.Status.Conditions))
+	for i, c := range j.Status.Conditions {
+		cs[i] = condition{conditionType: string(c.Type), status: c.Status, lastTransitionTime: c.LastTransitionTime}
+	}
+	return cs
+}
+
 func wrapJobFunc(f func(*v1batch.Job) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		job := obj.(*v1batch.Job)
 
 		metricFamily := f(job)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descJobLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{job.Namespace, job.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descJobLabelsDefaultLabels, []string{job.Namespace, job.Name})
 
 		return metricFamily
 	}
 }
 
-func createJobListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createJobListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.BatchV1().Jobs(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.BatchV1().Jobs(ns).Watch(opts)
 		},
 	}
@@ -71,15 +71,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Kube lease renew time.",
 			GenerateFunc: wrapLeaseFunc(func(l *coordinationv1.Lease) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !l.Spec.RenewTime.IsZero() {
-					ms = append(ms, &metric.Metric{
-						Value: float64(l.Spec.RenewTime.Unix()),
-					})
-				}
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(l.Spec.RenewTime),
 				}
 			}),
 		},
@@ -92,21 +85,20 @@ func wrapLeaseFunc(f func(*coordinationv1.Lease) *metric.Family) func(interface{
 
 		metricFamily := f(lease)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descLeaseLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{lease.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descLeaseLabelsDefaultLabels, []string{lease.Name})
 
 		return metricFamily
 	}
 }
 
-func createLeaseListWatch(kubeClient clientset.Interface, _ string) cache.ListerWatcher {
+func createLeaseListWatch(kubeClient clientset.Interface, _ string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoordinationV1().Leases("kube-node-lease").List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoordinationV1().Leases("kube-node-lease").Watch(opts)
 		},
 	}
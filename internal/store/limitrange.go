@@ -91,17 +91,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapLimitRangeFunc(func(r *v1.LimitRange) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !r.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-
-						Value: float64(r.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&r.CreationTimestamp),
 				}
 			}),
 		},
@@ -114,21 +105,20 @@ func wrapLimitRangeFunc(f func(*v1.LimitRange) *metric.Family) func(interface{})
 
 		metricFamily := f(limitRange)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descLimitRangeLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{limitRange.Namespace, limitRange.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descLimitRangeLabelsDefaultLabels, []string{limitRange.Namespace, limitRange.Name})
 
 		return metricFamily
 	}
 }
 
-func createLimitRangeListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createLimitRangeListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().LimitRanges(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().LimitRanges(ns).Watch(opts)
 		},
 	}
@@ -0,0 +1,163 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// partialObjectMetadataAccept and partialObjectMetadataListAccept make the
+	// apiserver return only the metadata of the requested objects.
+	partialObjectMetadataAccept     = "application/json;as=PartialObjectMetadata;g=meta.k8s.io;v=v1"
+	partialObjectMetadataListAccept = "application/json;as=PartialObjectMetadataList;g=meta.k8s.io;v=v1"
+)
+
+// metadataOnlyResource describes a resource the objects of which can be listed
+// and watched metadata only.
+type metadataOnlyResource struct {
+	// newObject returns an object of the listed type with the given metadata.
+	newObject func(metav1.ObjectMeta) runtime.Object
+	// dataFamilies are the metric families requiring more than the metadata
+	// of an object, which are not available when listing metadata only.
+	dataFamilies []string
+}
+
+// metadataOnlyResources are the resources which can be listed and watched
+// metadata only, see Builder.WithMetadataOnlyResources().
+var metadataOnlyResources = map[string]metadataOnlyResource{
+	"configmaps": {
+		newObject: func(m metav1.ObjectMeta) runtime.Object { return &v1.ConfigMap{ObjectMeta: m} },
+	},
+	"secrets": {
+		newObject:    func(m metav1.ObjectMeta) runtime.Object { return &v1.Secret{ObjectMeta: m} },
+		dataFamilies: []string{"kube_secret_type"},
+	},
+}
+
+// createMetadataOnlyListWatchFunc returns a function creating the list/watch of
+// the core/v1 resource of the given name, which only transfers the metadata of
+// the objects. The objects are of the listed type with only their metadata
+// set, so that the regular metric families apply to them.
+func createMetadataOnlyListWatchFunc(resource string, newObject func(metav1.ObjectMeta) runtime.Object) func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
+	return func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
+		return &cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				tweakListOptions(&opts)
+				body, err := kubeClient.CoreV1().RESTClient().Get().
+					Namespace(ns).
+					Resource(resource).
+					VersionedParams(&opts, scheme.ParameterCodec).
+					SetHeader("Accept", partialObjectMetadataListAccept).
+					Do().
+					Raw()
+				if err != nil {
+					return nil, err
+				}
+
+				partialList := &metav1.PartialObjectMetadataList{}
+				if err := json.Unmarshal(body, partialList); err != nil {
+					return nil, errors.Wrapf(err, "failed to decode metadata of %s", resource)
+				}
+
+				list := &metav1.List{
+					ListMeta: partialList.ListMeta,
+					Items:    make([]runtime.RawExtension, 0, len(partialList.Items)),
+				}
+				for _, item := range partialList.Items {
+					list.Items = append(list.Items, runtime.RawExtension{Object: newObject(item.ObjectMeta)})
+				}
+				return list, nil
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				tweakListOptions(&opts)
+				opts.Watch = true
+
+				req := kubeClient.CoreV1().RESTClient().Get().
+					Namespace(ns).
+					Resource(resource).
+					VersionedParams(&opts, scheme.ParameterCodec).
+					SetHeader("Accept", partialObjectMetadataAccept)
+				if opts.TimeoutSeconds != nil {
+					req = req.Timeout(time.Duration(*opts.TimeoutSeconds) * time.Second)
+				}
+				stream, err := req.Stream()
+				if err != nil {
+					return nil, err
+				}
+
+				decoder := &metadataWatchDecoder{
+					decoder:   json.NewDecoder(stream),
+					stream:    stream,
+					newObject: newObject,
+				}
+				return watch.NewStreamWatcher(decoder, apierrors.NewClientErrorReporter(http.StatusInternalServerError, "GET", "ClientWatchDecoding")), nil
+			},
+		}
+	}
+}
+
+// metadataWatchDecoder decodes the events of a watch of the metadata of
+// objects into objects of the watched type.
+type metadataWatchDecoder struct {
+	decoder   *json.Decoder
+	stream    io.Closer
+	newObject func(metav1.ObjectMeta) runtime.Object
+}
+
+// Decode implements the watch.Decoder interface.
+func (d *metadataWatchDecoder) Decode() (watch.EventType, runtime.Object, error) {
+	var event metav1.WatchEvent
+	if err := d.decoder.Decode(&event); err != nil {
+		return "", nil, err
+	}
+
+	switch t := watch.EventType(event.Type); t {
+	case watch.Added, watch.Modified, watch.Deleted, watch.Bookmark:
+		partial := &metav1.PartialObjectMetadata{}
+		if err := json.Unmarshal(event.Object.Raw, partial); err != nil {
+			return "", nil, errors.Wrap(err, "failed to decode metadata of watched object")
+		}
+		return t, d.newObject(partial.ObjectMeta), nil
+	case watch.Error:
+		status := &metav1.Status{}
+		if err := json.Unmarshal(event.Object.Raw, status); err != nil {
+			return "", nil, errors.Wrap(err, "failed to decode watch error")
+		}
+		return t, status, nil
+	default:
+		return "", nil, errors.Errorf("got invalid watch event type: %v", event.Type)
+	}
+}
+
+// Close implements the watch.Decoder interface.
+func (d *metadataWatchDecoder) Close() {
+	d.stream.Close()
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestMetadataOnlyListWatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/ns1/secrets" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("labelSelector") != "app=web" {
+			t.Errorf("expected list options to be tweaked, got query %q", r.URL.RawQuery)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") != "true" {
+			if accept := r.Header.Get("Accept"); accept != partialObjectMetadataListAccept {
+				t.Errorf("expected list of metadata to be requested, got Accept header %q", accept)
+			}
+			fmt.Fprint(w, `{"kind":"PartialObjectMetadataList","apiVersion":"meta.k8s.io/v1","metadata":{"resourceVersion":"10"},"items":[
+				{"kind":"PartialObjectMetadata","apiVersion":"meta.k8s.io/v1","metadata":{"name":"secret1","namespace":"ns1","uid":"uid1","labels":{"app":"web"}}}
+			]}`)
+			return
+		}
+
+		if accept := r.Header.Get("Accept"); accept != partialObjectMetadataAccept {
+			t.Errorf("expected watch of metadata to be requested, got Accept header %q", accept)
+		}
+		if r.URL.Query().Get("allowWatchBookmarks") != "true" {
+			t.Errorf("expected watch bookmarks to be requested, got query %q", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"type":"ADDED","object":{"kind":"PartialObjectMetadata","apiVersion":"meta.k8s.io/v1","metadata":{"name":"secret2","namespace":"ns1","uid":"uid2"}}}
+{"type":"DELETED","object":{"kind":"PartialObjectMetadata","apiVersion":"meta.k8s.io/v1","metadata":{"name":"secret1","namespace":"ns1","uid":"uid1"}}}
+`)
+	}))
+	defer srv.Close()
+
+	kubeClient, err := clientset.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tweakListOptions := func(opts *metav1.ListOptions) {
+		opts.LabelSelector = "app=web"
+	}
+	lw := createMetadataOnlyListWatchFunc("secrets", metadataOnlyResources["secrets"].newObject)(kubeClient, "ns1", tweakListOptions)
+
+	list, err := lw.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret1", Namespace: "ns1", UID: "uid1", Labels: map[string]string{"app": "web"}}}
+	if len(items) != 1 || !reflect.DeepEqual(items[0], expected) {
+		t.Errorf("expected list of %v, got %v", expected, items)
+	}
+	if rv := list.(*metav1.List).ResourceVersion; rv != "10" {
+		t.Errorf("expected resource version 10 of the list, got %q", rv)
+	}
+
+	w, err := lw.Watch(metav1.ListOptions{AllowWatchBookmarks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	expectedEvents := []watch.Event{
+		{Type: watch.Added, Object: &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret2", Namespace: "ns1", UID: "uid2"}}},
+		{Type: watch.Deleted, Object: &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret1", Namespace: "ns1", UID: "uid1"}}},
+	}
+	for _, expected := range expectedEvents {
+		got, ok := <-w.ResultChan()
+		if !ok {
+			t.Fatalf("expected event %v, watch was closed", expected)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected event %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestWithMetadataOnlyResources(t *testing.T) {
+	b := NewBuilder()
+	if err := b.WithMetadataOnlyResources([]string{"pods"}); err == nil {
+		t.Error("expected error for resource which cannot be listed metadata only")
+	}
+
+	if err := b.WithMetadataOnlyResources([]string{"secrets"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range b.metricFamilies("secrets") {
+		if f.Name == "kube_secret_type" {
+			t.Error("expected kube_secret_type to be unavailable for secrets listed metadata only")
+		}
+	}
+	if len(b.metricFamilies("secrets")) != len(secretMetricFamilies)-1 {
+		t.Errorf("expected all families but kube_secret_type for secrets listed metadata only, got %d families", len(b.metricFamilies("secrets")))
+	}
+	if len(b.metricFamilies("configmaps")) != len(configMapMetricFamilies) {
+		t.Errorf("expected all families for configmaps, got %d families", len(b.metricFamilies("configmaps")))
+	}
+}
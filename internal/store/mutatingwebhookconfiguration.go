@@ -35,14 +35,12 @@ var (
 	mutatingWebhookConfigurationMetricFamilies = []generator.FamilyGenerator{
 		{
 			Name: "kube_mutatingwebhookconfiguration_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about the MutatingWebhookConfiguration.",
 			GenerateFunc: wrapMutatingWebhookConfigurationFunc(func(mwc *admissionregistration.MutatingWebhookConfiguration) *metric.Family {
 				return &metric.Family{
 					Metrics: []*metric.Metric{
-						{
-							Value: 1,
-						},
+						{},
 					},
 				}
 			}),
@@ -52,15 +50,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp.",
 			GenerateFunc: wrapMutatingWebhookConfigurationFunc(func(mwc *admissionregistration.MutatingWebhookConfiguration) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !mwc.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						Value: float64(mwc.CreationTimestamp.Unix()),
-					})
-				}
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&mwc.CreationTimestamp),
 				}
 			}),
 		},
@@ -77,12 +68,14 @@ var (
 	}
 )
 
-func createMutatingWebhookConfigurationListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createMutatingWebhookConfigurationListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Watch(opts)
 		},
 	}
@@ -94,10 +87,7 @@ func wrapMutatingWebhookConfigurationFunc(f func(*admissionregistration.Mutating
 
 		metricFamily := f(mutatingWebhookConfiguration)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descMutatingWebhookConfigurationDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{mutatingWebhookConfiguration.Namespace, mutatingWebhookConfiguration.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descMutatingWebhookConfigurationDefaultLabels, []string{mutatingWebhookConfiguration.Namespace, mutatingWebhookConfiguration.Name})
 
 		return metricFamily
 	}
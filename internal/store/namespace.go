@@ -39,15 +39,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapNamespaceFunc(func(n *v1.Namespace) *metric.Family {
-				ms := []*metric.Metric{}
-				if !n.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						Value: float64(n.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&n.CreationTimestamp),
 				}
 			}),
 		},
@@ -70,46 +63,28 @@ var (
 		},
 		{
 			Name: "kube_namespace_status_phase",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "kubernetes namespace status phase.",
 			GenerateFunc: wrapNamespaceFunc(func(n *v1.Namespace) *metric.Family {
-				ms := []*metric.Metric{
-					{
-						LabelValues: []string{string(v1.NamespaceActive)},
-						Value:       boolFloat64(n.Status.Phase == v1.NamespaceActive),
-					},
-					{
-						LabelValues: []string{string(v1.NamespaceTerminating)},
-						Value:       boolFloat64(n.Status.Phase == v1.NamespaceTerminating),
-					},
-				}
-
-				for _, metric := range ms {
-					metric.LabelKeys = []string{"phase"}
+				phases := []string{
+					string(v1.NamespaceActive),
+					string(v1.NamespaceTerminating),
 				}
 
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: metric.StateSetMetrics("phase", phases, string(n.Status.Phase)),
 				}
 			}),
 		},
 		{
 			Name: "kube_namespace_status_condition",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "The condition of a namespace.",
 			GenerateFunc: wrapNamespaceFunc(func(n *v1.Namespace) *metric.Family {
-				ms := make([]*metric.Metric, len(n.Status.Conditions)*len(conditionStatuses))
-				for i, c := range n.Status.Conditions {
-					conditionMetrics := addConditionMetrics(c.Status)
-
-					for j, m := range conditionMetrics {
-						metric := m
-
-						metric.LabelKeys = []string{"condition", "status"}
-						metric.LabelValues = append([]string{string(c.Type)}, metric.LabelValues...)
+				ms := make([]*metric.Metric, 0, len(n.Status.Conditions)*len(conditionStatuses))
 
-						ms[i*len(conditionStatuses)+j] = metric
-					}
+				for _, c := range latestConditions(namespaceConditions(n)) {
+					ms = append(ms, addConditionMetricsWithType(c.conditionType, c.status)...)
 				}
 
 				return &metric.Family{
@@ -120,27 +95,35 @@ var (
 	}
 )
 
+// namespaceConditions returns the status conditions of the given namespace.
+func namespaceConditions(n *v1.Namespace) []condition {
+	cs := make([]condition, len(n.Status.Conditions))
+	for i, c := range n.Status.Conditions {
+		cs[i] = condition{conditionType: string(c.Type), status: c.Status, lastTransitionTime: c.LastTransitionTime}
+	}
+	return cs
+}
+
 func wrapNamespaceFunc(f func(*v1.Namespace) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		namespace := obj.(*v1.Namespace)
 
 		metricFamily := f(namespace)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descNamespaceLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{namespace.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descNamespaceLabelsDefaultLabels, []string{namespace.Name})
 
 		return metricFamily
 	}
 }
 
-func createNamespaceListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createNamespaceListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Namespaces().List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Namespaces().Watch(opts)
 		},
 	}
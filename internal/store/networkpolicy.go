@@ -38,13 +38,7 @@ var (
 			Help: "Unix creation timestamp of network policy",
 			GenerateFunc: wrapNetworkPolicyFunc(func(n *networkingv1.NetworkPolicy) *metric.Family {
 				return &metric.Family{
-					Metrics: []*metric.Metric{
-						{
-							LabelKeys:   []string{},
-							LabelValues: []string{},
-							Value:       float64(n.CreationTimestamp.Unix()),
-						},
-					},
+					Metrics: timestampMetrics(&n.CreationTimestamp),
 				}
 			}),
 		},
@@ -106,21 +100,20 @@ func wrapNetworkPolicyFunc(f func(*networkingv1.NetworkPolicy) *metric.Family) f
 
 		metricFamily := f(networkPolicy)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descNetworkPolicyLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{networkPolicy.Namespace, networkPolicy.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descNetworkPolicyLabelsDefaultLabels, []string{networkPolicy.Namespace, networkPolicy.Name})
 
 		return metricFamily
 	}
 }
 
-func createNetworkPolicyListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createNetworkPolicyListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.NetworkingV1().NetworkPolicies(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.NetworkingV1().NetworkPolicies(ns).Watch(opts)
 		},
 	}
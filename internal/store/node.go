@@ -19,7 +19,6 @@ package store
 import (
 	"strings"
 
-	"k8s.io/kube-state-metrics/pkg/constant"
 	"k8s.io/kube-state-metrics/pkg/metric"
 	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
 
@@ -39,7 +38,7 @@ var (
 	nodeMetricFamilies = []generator.FamilyGenerator{
 		{
 			Name: "kube_node_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about a cluster node.",
 			GenerateFunc: wrapNodeFunc(func(n *v1.Node) *metric.Family {
 				return &metric.Family{
@@ -63,7 +62,6 @@ var (
 								n.Spec.ProviderID,
 								n.Spec.PodCIDR,
 							},
-							Value: 1,
 						},
 					},
 				}
@@ -74,17 +72,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapNodeFunc(func(n *v1.Node) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !n.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-
-						Value: float64(n.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&n.CreationTimestamp),
 				}
 			}),
 		},
@@ -169,23 +158,13 @@ var (
 		// conditions in future.
 		{
 			Name: "kube_node_status_condition",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "The condition of a cluster node.",
 			GenerateFunc: wrapNodeFunc(func(n *v1.Node) *metric.Family {
-				ms := make([]*metric.Metric, len(n.Status.Conditions)*len(conditionStatuses))
+				ms := make([]*metric.Metric, 0, len(n.Status.Conditions)*len(conditionStatuses))
 
-				// Collect node conditions and while default to false.
-				for i, c := range n.Status.Conditions {
-					conditionMetrics := addConditionMetrics(c.Status)
-
-					for j, m := range conditionMetrics {
-						metric := m
-
-						metric.LabelKeys = []string{"condition", "status"}
-						metric.LabelValues = append([]string{string(c.Type)}, metric.LabelValues...)
-
-						ms[i*len(conditionStatuses)+j] = metric
-					}
+				for _, c := range latestConditions(nodeConditions(n)) {
+					ms = append(ms, addConditionMetricsWithType(c.conditionType, c.status)...)
 				}
 
 				return &metric.Family{
@@ -196,184 +175,122 @@ var (
 		{
 			Name: "kube_node_status_capacity",
 			Type: metric.Gauge,
-			Help: "The capacity for different resources of a node.",
+			Help: "The capacity for different resources of a node." + resourceUnitsHelp,
 			GenerateFunc: wrapNodeFunc(func(n *v1.Node) *metric.Family {
-				ms := []*metric.Metric{}
-
-				capacity := n.Status.Capacity
-				for resourceName, val := range capacity {
-					switch resourceName {
-					case v1.ResourceCPU:
-						ms = append(ms, &metric.Metric{
-							LabelValues: []string{
-								sanitizeLabelName(string(resourceName)),
-								string(constant.UnitCore),
-							},
-							Value: float64(val.MilliValue()) / 1000,
-						})
-					case v1.ResourceStorage:
-						fallthrough
-					case v1.ResourceEphemeralStorage:
-						fallthrough
-					case v1.ResourceMemory:
-						ms = append(ms, &metric.Metric{
-							LabelValues: []string{
-								sanitizeLabelName(string(resourceName)),
-								string(constant.UnitByte),
-							},
-							Value: float64(val.MilliValue()) / 1000,
-						})
-					case v1.ResourcePods:
-						ms = append(ms, &metric.Metric{
-							LabelValues: []string{
-								sanitizeLabelName(string(resourceName)),
-								string(constant.UnitInteger),
-							},
-							Value: float64(val.MilliValue()) / 1000,
-						})
-					default:
-						if isHugePageResourceName(resourceName) {
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{
-									sanitizeLabelName(string(resourceName)),
-									string(constant.UnitByte),
-								},
-								Value: float64(val.MilliValue()) / 1000,
-							})
-						}
-						if isAttachableVolumeResourceName(resourceName) {
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{
-									sanitizeLabelName(string(resourceName)),
-									string(constant.UnitByte),
-								},
-								Value: float64(val.MilliValue()) / 1000,
-							})
-						}
-						if isExtendedResourceName(resourceName) {
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{
-									sanitizeLabelName(string(resourceName)),
-									string(constant.UnitInteger),
-								},
-								Value: float64(val.MilliValue()) / 1000,
-							})
-						}
-					}
-				}
-
-				for _, metric := range ms {
-					metric.LabelKeys = []string{"resource", "unit"}
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: resourceMetrics(n.Status.Capacity, nil, nil),
 				}
 			}),
 		},
 		{
 			Name: "kube_node_status_allocatable",
 			Type: metric.Gauge,
-			Help: "The allocatable for different resources of a node that are available for scheduling.",
+			Help: "The allocatable for different resources of a node that are available for scheduling." + resourceUnitsHelp,
 			GenerateFunc: wrapNodeFunc(func(n *v1.Node) *metric.Family {
-				ms := []*metric.Metric{}
-
-				allocatable := n.Status.Allocatable
-
-				for resourceName, val := range allocatable {
-					switch resourceName {
-					case v1.ResourceCPU:
-						ms = append(ms, &metric.Metric{
-							LabelValues: []string{
-								sanitizeLabelName(string(resourceName)),
-								string(constant.UnitCore),
-							},
-							Value: float64(val.MilliValue()) / 1000,
-						})
-					case v1.ResourceStorage:
-						fallthrough
-					case v1.ResourceEphemeralStorage:
-						fallthrough
-					case v1.ResourceMemory:
-						ms = append(ms, &metric.Metric{
-							LabelValues: []string{
-								sanitizeLabelName(string(resourceName)),
-								string(constant.UnitByte),
-							},
-							Value: float64(val.MilliValue()) / 1000,
-						})
-					case v1.ResourcePods:
-						ms = append(ms, &metric.Metric{
-							LabelValues: []string{
-								sanitizeLabelName(string(resourceName)),
-								string(constant.UnitInteger),
-							},
-							Value: float64(val.MilliValue()) / 1000,
-						})
-					default:
-						if isHugePageResourceName(resourceName) {
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{
-									sanitizeLabelName(string(resourceName)),
-									string(constant.UnitByte),
-								},
-								Value: float64(val.MilliValue()) / 1000,
-							})
-						}
-						if isAttachableVolumeResourceName(resourceName) {
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{
-									sanitizeLabelName(string(resourceName)),
-									string(constant.UnitByte),
-								},
-								Value: float64(val.MilliValue()) / 1000,
-							})
-						}
-						if isExtendedResourceName(resourceName) {
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{
-									sanitizeLabelName(string(resourceName)),
-									string(constant.UnitInteger),
-								},
-								Value: float64(val.MilliValue()) / 1000,
-							})
-						}
-					}
-				}
-
-				for _, m := range ms {
-					m.LabelKeys = []string{"resource", "unit"}
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: resourceMetrics(n.Status.Allocatable, nil, nil),
 				}
 			}),
 		},
 	}
+
+	// nodeDeprecatedMetricFamilies are replaced by the resource and unit labels
+	// of kube_node_status_capacity and kube_node_status_allocatable, see
+	// deprecatedMetricFamilies.
+	nodeDeprecatedMetricFamilies = []generator.FamilyGenerator{
+		deprecatedNodeResourceFamily(
+			"kube_node_status_capacity_cpu_cores",
+			"The total CPU resources of the node.",
+			"kube_node_status_capacity", v1.ResourceCPU,
+			func(n *v1.Node) v1.ResourceList { return n.Status.Capacity },
+		),
+		deprecatedNodeResourceFamily(
+			"kube_node_status_capacity_memory_bytes",
+			"The total memory resources of the node.",
+			"kube_node_status_capacity", v1.ResourceMemory,
+			func(n *v1.Node) v1.ResourceList { return n.Status.Capacity },
+		),
+		deprecatedNodeResourceFamily(
+			"kube_node_status_capacity_pods",
+			"The total pod resources of the node.",
+			"kube_node_status_capacity", v1.ResourcePods,
+			func(n *v1.Node) v1.ResourceList { return n.Status.Capacity },
+		),
+		deprecatedNodeResourceFamily(
+			"kube_node_status_allocatable_cpu_cores",
+			"The CPU resources of a node that are available for scheduling.",
+			"kube_node_status_allocatable", v1.ResourceCPU,
+			func(n *v1.Node) v1.ResourceList { return n.Status.Allocatable },
+		),
+		deprecatedNodeResourceFamily(
+			"kube_node_status_allocatable_memory_bytes",
+			"The memory resources of a node that are available for scheduling.",
+			"kube_node_status_allocatable", v1.ResourceMemory,
+			func(n *v1.Node) v1.ResourceList { return n.Status.Allocatable },
+		),
+		deprecatedNodeResourceFamily(
+			"kube_node_status_allocatable_pods",
+			"The pod resources of a node that are available for scheduling.",
+			"kube_node_status_allocatable", v1.ResourcePods,
+			func(n *v1.Node) v1.ResourceList { return n.Status.Allocatable },
+		),
+	}
 )
 
+// deprecatedNodeResourceFamily returns the metric family of the given
+// deprecated name, generating the value of the given resource in the resource
+// list of the node, which is replaced by the given metric family.
+func deprecatedNodeResourceFamily(name, help, replacement string, resourceName v1.ResourceName, resources func(*v1.Node) v1.ResourceList) generator.FamilyGenerator {
+	return generator.FamilyGenerator{
+		Name: name,
+		Type: metric.Gauge,
+		Help: help + " Deprecated, use " + replacement + "{resource=\"" + string(resourceName) + "\"} instead.",
+		GenerateFunc: wrapNodeFunc(func(n *v1.Node) *metric.Family {
+			ms := []*metric.Metric{}
+
+			if q, ok := resources(n)[resourceName]; ok {
+				value, _ := resourceValue(resourceName, q)
+				ms = append(ms, &metric.Metric{
+					Value: value,
+				})
+			}
+
+			return &metric.Family{
+				Metrics: ms,
+			}
+		}),
+	}
+}
+
+// nodeConditions returns the status conditions of the given node.
+func nodeConditions(n *v1.Node) []condition {
+	cs := make([]condition, len(n.Status.Conditions))
+	for i, c := range n.Status.Conditions {
+		cs[i] = condition{conditionType: string(c.Type), status: c.Status, lastTransitionTime: c.LastTransitionTime}
+	}
+	return cs
+}
+
 func wrapNodeFunc(f func(*v1.Node) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		node := obj.(*v1.Node)
 
 		metricFamily := f(node)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descNodeLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{node.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descNodeLabelsDefaultLabels, []string{node.Name})
 
 		return metricFamily
 	}
 }
 
-func createNodeListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createNodeListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Nodes().List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Nodes().Watch(opts)
 		},
 	}
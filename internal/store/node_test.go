@@ -123,8 +123,8 @@ func TestNodeStore(t *testing.T) {
 		# HELP kube_node_labels Kubernetes labels converted to Prometheus labels.
 		# HELP kube_node_role The role of a cluster node.
 		# HELP kube_node_spec_unschedulable Whether a node can schedule new pods.
-		# HELP kube_node_status_allocatable The allocatable for different resources of a node that are available for scheduling.
-		# HELP kube_node_status_capacity The capacity for different resources of a node.
+		# HELP kube_node_status_allocatable The allocatable for different resources of a node that are available for scheduling. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
+		# HELP kube_node_status_capacity The capacity for different resources of a node. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
 		# TYPE kube_node_created gauge
 		# TYPE kube_node_info gauge
 		# TYPE kube_node_labels gauge
@@ -277,3 +277,61 @@ func TestNodeStore(t *testing.T) {
 		}
 	}
 }
+
+func TestNodeDeprecatedStore(t *testing.T) {
+	cases := []generateMetricsTestCase{
+		{
+			Obj: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "127.0.0.1",
+				},
+				Status: v1.NodeStatus{
+					Capacity: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("4.3"),
+						v1.ResourceMemory: resource.MustParse("2G"),
+						v1.ResourcePods:   resource.MustParse("1000"),
+						"nvidia.com/gpu":  resource.MustParse("4"),
+					},
+					Allocatable: v1.ResourceList{
+						v1.ResourceCPU:  resource.MustParse("3"),
+						v1.ResourcePods: resource.MustParse("555"),
+					},
+				},
+			},
+			Want: `
+				# HELP kube_node_status_allocatable_cpu_cores The CPU resources of a node that are available for scheduling. Deprecated, use kube_node_status_allocatable{resource="cpu"} instead.
+				# HELP kube_node_status_allocatable_memory_bytes The memory resources of a node that are available for scheduling. Deprecated, use kube_node_status_allocatable{resource="memory"} instead.
+				# HELP kube_node_status_allocatable_pods The pod resources of a node that are available for scheduling. Deprecated, use kube_node_status_allocatable{resource="pods"} instead.
+				# HELP kube_node_status_capacity_cpu_cores The total CPU resources of the node. Deprecated, use kube_node_status_capacity{resource="cpu"} instead.
+				# HELP kube_node_status_capacity_memory_bytes The total memory resources of the node. Deprecated, use kube_node_status_capacity{resource="memory"} instead.
+				# HELP kube_node_status_capacity_pods The total pod resources of the node. Deprecated, use kube_node_status_capacity{resource="pods"} instead.
+				# TYPE kube_node_status_allocatable_cpu_cores gauge
+				# TYPE kube_node_status_allocatable_memory_bytes gauge
+				# TYPE kube_node_status_allocatable_pods gauge
+				# TYPE kube_node_status_capacity_cpu_cores gauge
+				# TYPE kube_node_status_capacity_memory_bytes gauge
+				# TYPE kube_node_status_capacity_pods gauge
+				kube_node_status_allocatable_cpu_cores{node="127.0.0.1"} 3
+				kube_node_status_allocatable_pods{node="127.0.0.1"} 555
+				kube_node_status_capacity_cpu_cores{node="127.0.0.1"} 4.3
+				kube_node_status_capacity_memory_bytes{node="127.0.0.1"} 2e+09
+				kube_node_status_capacity_pods{node="127.0.0.1"} 1000
+			`,
+			MetricNames: []string{
+				"kube_node_status_capacity_cpu_cores",
+				"kube_node_status_capacity_memory_bytes",
+				"kube_node_status_capacity_pods",
+				"kube_node_status_allocatable_cpu_cores",
+				"kube_node_status_allocatable_memory_bytes",
+				"kube_node_status_allocatable_pods",
+			},
+		},
+	}
+	for i, c := range cases {
+		c.Func = generator.ComposeMetricGenFuncs(nodeDeprecatedMetricFamilies)
+		c.Headers = generator.ExtractMetricFamilyHeaders(nodeDeprecatedMetricFamilies)
+		if err := c.run(); err != nil {
+			t.Errorf("unexpected collecting result in %vth run:\n%s", i, err)
+		}
+	}
+}
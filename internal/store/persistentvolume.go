@@ -53,7 +53,7 @@ var (
 		},
 		{
 			Name: "kube_persistentvolume_status_phase",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "The phase indicates if a volume is available, bound to a claim, or released by a claim.",
 			GenerateFunc: wrapPersistentVolumeFunc(func(p *v1.PersistentVolume) *metric.Family {
 				phase := p.Status.Phase
@@ -65,41 +65,22 @@ var (
 				}
 
 				// Set current phase to 1, others to 0 if it is set.
-				ms := []*metric.Metric{
-					{
-						LabelValues: []string{string(v1.VolumePending)},
-						Value:       boolFloat64(phase == v1.VolumePending),
-					},
-					{
-						LabelValues: []string{string(v1.VolumeAvailable)},
-						Value:       boolFloat64(phase == v1.VolumeAvailable),
-					},
-					{
-						LabelValues: []string{string(v1.VolumeBound)},
-						Value:       boolFloat64(phase == v1.VolumeBound),
-					},
-					{
-						LabelValues: []string{string(v1.VolumeReleased)},
-						Value:       boolFloat64(phase == v1.VolumeReleased),
-					},
-					{
-						LabelValues: []string{string(v1.VolumeFailed)},
-						Value:       boolFloat64(phase == v1.VolumeFailed),
-					},
-				}
-
-				for _, m := range ms {
-					m.LabelKeys = []string{"phase"}
+				phases := []string{
+					string(v1.VolumePending),
+					string(v1.VolumeAvailable),
+					string(v1.VolumeBound),
+					string(v1.VolumeReleased),
+					string(v1.VolumeFailed),
 				}
 
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: metric.StateSetMetrics("phase", phases, string(phase)),
 				}
 			}),
 		},
 		{
 			Name: "kube_persistentvolume_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about persistentvolume.",
 			GenerateFunc: wrapPersistentVolumeFunc(func(p *v1.PersistentVolume) *metric.Family {
 				return &metric.Family{
@@ -107,7 +88,6 @@ var (
 						{
 							LabelKeys:   []string{"storageclass"},
 							LabelValues: []string{p.Spec.StorageClassName},
-							Value:       1,
 						},
 					},
 				}
@@ -137,21 +117,20 @@ func wrapPersistentVolumeFunc(f func(*v1.PersistentVolume) *metric.Family) func(
 
 		metricFamily := f(persistentVolume)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descPersistentVolumeLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{persistentVolume.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descPersistentVolumeLabelsDefaultLabels, []string{persistentVolume.Name})
 
 		return metricFamily
 	}
 }
 
-func createPersistentVolumeListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createPersistentVolumeListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().PersistentVolumes().List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().PersistentVolumes().Watch(opts)
 		},
 	}
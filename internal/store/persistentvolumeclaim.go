@@ -53,7 +53,7 @@ var (
 		},
 		{
 			Name: "kube_persistentvolumeclaim_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about persistent volume claim.",
 			GenerateFunc: wrapPersistentVolumeClaimFunc(func(p *v1.PersistentVolumeClaim) *metric.Family {
 				storageClassName := getPersistentVolumeClaimClass(p)
@@ -63,7 +63,6 @@ var (
 						{
 							LabelKeys:   []string{"storageclass", "volumename"},
 							LabelValues: []string{storageClassName, volumeName},
-							Value:       1,
 						},
 					},
 				}
@@ -71,7 +70,7 @@ var (
 		},
 		{
 			Name: "kube_persistentvolumeclaim_status_phase",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "The phase the persistent volume claim is currently in.",
 			GenerateFunc: wrapPersistentVolumeClaimFunc(func(p *v1.PersistentVolumeClaim) *metric.Family {
 				phase := p.Status.Phase
@@ -83,27 +82,14 @@ var (
 				}
 
 				// Set current phase to 1, others to 0 if it is set.
-				ms := []*metric.Metric{
-					{
-						LabelValues: []string{string(v1.ClaimLost)},
-						Value:       boolFloat64(phase == v1.ClaimLost),
-					},
-					{
-						LabelValues: []string{string(v1.ClaimBound)},
-						Value:       boolFloat64(phase == v1.ClaimBound),
-					},
-					{
-						LabelValues: []string{string(v1.ClaimPending)},
-						Value:       boolFloat64(phase == v1.ClaimPending),
-					},
-				}
-
-				for _, m := range ms {
-					m.LabelKeys = []string{"phase"}
+				phases := []string{
+					string(v1.ClaimLost),
+					string(v1.ClaimBound),
+					string(v1.ClaimPending),
 				}
 
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: metric.StateSetMetrics("phase", phases, string(phase)),
 				}
 			}),
 		},
@@ -148,21 +134,12 @@ var (
 		{
 			Name: "kube_persistentvolumeclaim_status_condition",
 			Help: "Information about status of different conditions of persistent volume claim.",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			GenerateFunc: wrapPersistentVolumeClaimFunc(func(p *v1.PersistentVolumeClaim) *metric.Family {
-				ms := make([]*metric.Metric, len(p.Status.Conditions)*len(conditionStatuses))
-
-				for i, c := range p.Status.Conditions {
-					conditionMetrics := addConditionMetrics(c.Status)
-
-					for j, m := range conditionMetrics {
-						metric := m
-
-						metric.LabelKeys = []string{"condition", "status"}
-						metric.LabelValues = append([]string{string(c.Type)}, metric.LabelValues...)
+				ms := make([]*metric.Metric, 0, len(p.Status.Conditions)*len(conditionStatuses))
 
-						ms[i*len(conditionStatuses)+j] = metric
-					}
+				for _, c := range latestConditions(persistentVolumeClaimConditions(p)) {
+					ms = append(ms, addConditionMetricsWithType(c.conditionType, c.status)...)
 				}
 
 				return &metric.Family{
@@ -173,27 +150,35 @@ var (
 	}
 )
 
+// persistentVolumeClaimConditions returns the status conditions of the given persistentvolumeclaim.
+func persistentVolumeClaimConditions(p *v1.PersistentVolumeClaim) []condition {
+	cs := make([]condition, len(p.Status.Conditions))
+	for i, c := range p.Status.Conditions {
+		cs[i] = condition{conditionType: string(c.Type), status: c.Status, lastTransitionTime: c.LastTransitionTime}
+	}
+	return cs
+}
+
 func wrapPersistentVolumeClaimFunc(f func(*v1.PersistentVolumeClaim) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		persistentVolumeClaim := obj.(*v1.PersistentVolumeClaim)
 
 		metricFamily := f(persistentVolumeClaim)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descPersistentVolumeClaimLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{persistentVolumeClaim.Namespace, persistentVolumeClaim.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descPersistentVolumeClaimLabelsDefaultLabels, []string{persistentVolumeClaim.Namespace, persistentVolumeClaim.Name})
 
 		return metricFamily
 	}
 }
 
-func createPersistentVolumeClaimListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createPersistentVolumeClaimListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().PersistentVolumeClaims(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().PersistentVolumeClaims(ns).Watch(opts)
 		},
 	}
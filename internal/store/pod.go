@@ -19,7 +19,6 @@ package store
 import (
 	"strconv"
 
-	"k8s.io/kube-state-metrics/pkg/constant"
 	"k8s.io/kube-state-metrics/pkg/metric"
 	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
 
@@ -40,7 +39,7 @@ var (
 	podMetricFamilies = []generator.FamilyGenerator{
 		{
 			Name: "kube_pod_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about pod.",
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				createdBy := metav1.GetControllerOf(p)
@@ -59,7 +58,6 @@ var (
 
 					LabelKeys:   []string{"host_ip", "pod_ip", "uid", "node", "created_by_kind", "created_by_name", "priority_class", "host_network"},
 					LabelValues: []string{p.Status.HostIP, p.Status.PodIP, string(p.UID), p.Spec.NodeName, createdByKind, createdByName, p.Spec.PriorityClassName, strconv.FormatBool(p.Spec.HostNetwork)},
-					Value:       1,
 				}
 
 				return &metric.Family{
@@ -72,18 +70,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Start time in unix timestamp for a pod.",
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if p.Status.StartTime != nil {
-					ms = append(ms, &metric.Metric{
-						LabelKeys:   []string{},
-						LabelValues: []string{},
-						Value:       float64((p.Status.StartTime).Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(p.Status.StartTime),
 				}
 			}),
 		},
@@ -181,18 +169,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !p.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						LabelKeys:   []string{},
-						LabelValues: []string{},
-						Value:       float64(p.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&p.CreationTimestamp),
 				}
 			}),
 		},
@@ -201,18 +179,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix deletion timestamp",
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if p.DeletionTimestamp != nil && !p.DeletionTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						LabelKeys:   []string{},
-						LabelValues: []string{},
-						Value:       float64(p.DeletionTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(p.DeletionTimestamp),
 				}
 			}),
 		},
@@ -243,11 +211,7 @@ var (
 					switch c.Type {
 					case v1.PodScheduled:
 						if c.Status == v1.ConditionTrue {
-							ms = append(ms, &metric.Metric{
-								LabelKeys:   []string{},
-								LabelValues: []string{},
-								Value:       float64(c.LastTransitionTime.Unix()),
-							})
+							ms = append(ms, timestampMetrics(&c.LastTransitionTime)...)
 						}
 					}
 				}
@@ -284,7 +248,7 @@ var (
 		},
 		{
 			Name: "kube_pod_status_phase",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "The pods current phase.",
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				phase := p.Status.Phase
@@ -294,50 +258,29 @@ var (
 					}
 				}
 
-				phases := []struct {
-					v bool
-					n string
-				}{
-					{phase == v1.PodPending, string(v1.PodPending)},
-					{phase == v1.PodSucceeded, string(v1.PodSucceeded)},
-					{phase == v1.PodFailed, string(v1.PodFailed)},
-					{phase == v1.PodUnknown, string(v1.PodUnknown)},
-					{phase == v1.PodRunning, string(v1.PodRunning)},
-				}
-
-				ms := make([]*metric.Metric, len(phases))
-
-				for i, p := range phases {
-					ms[i] = &metric.Metric{
-
-						LabelKeys:   []string{"phase"},
-						LabelValues: []string{p.n},
-						Value:       boolFloat64(p.v),
-					}
+				phases := []string{
+					string(v1.PodPending),
+					string(v1.PodSucceeded),
+					string(v1.PodFailed),
+					string(v1.PodUnknown),
+					string(v1.PodRunning),
 				}
 
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: metric.StateSetMetrics("phase", phases, string(phase)),
 				}
 			}),
 		},
 		{
 			Name: "kube_pod_status_ready",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "Describes whether the pod is ready to serve requests.",
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				ms := []*metric.Metric{}
 
-				for _, c := range p.Status.Conditions {
-					switch c.Type {
-					case v1.PodReady:
-						conditionMetrics := addConditionMetrics(c.Status)
-
-						for _, m := range conditionMetrics {
-							metric := m
-							metric.LabelKeys = []string{"condition"}
-							ms = append(ms, metric)
-						}
+				for _, c := range latestConditions(podConditions(p)) {
+					if c.conditionType == string(v1.PodReady) {
+						ms = append(ms, addConditionMetrics("condition", c.status)...)
 					}
 				}
 
@@ -348,21 +291,14 @@ var (
 		},
 		{
 			Name: "kube_pod_status_scheduled",
-			Type: metric.Gauge,
+			Type: metric.StateSet,
 			Help: "Describes the status of the scheduling process for the pod.",
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				ms := []*metric.Metric{}
 
-				for _, c := range p.Status.Conditions {
-					switch c.Type {
-					case v1.PodScheduled:
-						conditionMetrics := addConditionMetrics(c.Status)
-
-						for _, m := range conditionMetrics {
-							metric := m
-							metric.LabelKeys = []string{"condition"}
-							ms = append(ms, metric)
-						}
+				for _, c := range latestConditions(podConditions(p)) {
+					if c.conditionType == string(v1.PodScheduled) {
+						ms = append(ms, addConditionMetrics("condition", c.status)...)
 					}
 				}
 
@@ -397,7 +333,7 @@ var (
 		},
 		{
 			Name: "kube_pod_container_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about a container in a pod.",
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				ms := make([]*metric.Metric, len(p.Status.ContainerStatuses))
@@ -407,7 +343,6 @@ var (
 					ms[i] = &metric.Metric{
 						LabelKeys:   labelKeys,
 						LabelValues: []string{cs.Name, cs.Image, cs.ImageID, cs.ContainerID},
-						Value:       1,
 					}
 				}
 
@@ -418,7 +353,7 @@ var (
 		},
 		{
 			Name: "kube_pod_init_container_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about an init container in a pod.",
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				ms := make([]*metric.Metric, len(p.Status.InitContainerStatuses))
@@ -428,7 +363,6 @@ var (
 					ms[i] = &metric.Metric{
 						LabelKeys:   labelKeys,
 						LabelValues: []string{cs.Name, cs.Image, cs.ImageID, cs.ContainerID},
-						Value:       1,
 					}
 				}
 
@@ -749,6 +683,33 @@ var (
 				}
 			}),
 		},
+		{
+			Name: "kube_pod_container_status_last_restart_timestamp",
+			Type: metric.Gauge,
+			Help: "Approximate unix timestamp of the last restart of the container, only exposed for restarted containers. It is the start time of the running container or, if it is not running yet, the finish time of its last termination.",
+			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
+				ms := []*metric.Metric{}
+
+				for _, cs := range p.Status.ContainerStatuses {
+					if cs.RestartCount == 0 {
+						continue
+					}
+					t := lastRestartTime(cs)
+					if t.IsZero() {
+						continue
+					}
+					ms = append(ms, &metric.Metric{
+						LabelKeys:   []string{"container"},
+						LabelValues: []string{cs.Name},
+						Value:       float64(t.Unix()),
+					})
+				}
+
+				return &metric.Family{
+					Metrics: ms,
+				}
+			}),
+		},
 		{
 			Name: "kube_pod_init_container_status_restarts_total",
 			Type: metric.Counter,
@@ -772,54 +733,12 @@ var (
 		{
 			Name: "kube_pod_container_resource_requests",
 			Type: metric.Gauge,
-			Help: "The number of requested request resource by a container.",
+			Help: "The number of requested request resource by a container." + resourceUnitsHelp,
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				ms := []*metric.Metric{}
 
 				for _, c := range p.Spec.Containers {
-					req := c.Resources.Requests
-
-					for resourceName, val := range req {
-						switch resourceName {
-						case v1.ResourceCPU:
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitCore)},
-								Value:       float64(val.MilliValue()) / 1000,
-							})
-						case v1.ResourceStorage:
-							fallthrough
-						case v1.ResourceEphemeralStorage:
-							fallthrough
-						case v1.ResourceMemory:
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-								Value:       float64(val.Value()),
-							})
-						default:
-							if isHugePageResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-									Value:       float64(val.Value()),
-								})
-							}
-							if isAttachableVolumeResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-									Value:       float64(val.Value()),
-								})
-							}
-							if isExtendedResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitInteger)},
-									Value:       float64(val.Value()),
-								})
-							}
-						}
-					}
-				}
-
-				for _, metric := range ms {
-					metric.LabelKeys = []string{"container", "resource", "unit"}
+					ms = append(ms, resourceMetrics(c.Resources.Requests, []string{"container"}, []string{c.Name})...)
 				}
 
 				return &metric.Family{
@@ -830,54 +749,12 @@ var (
 		{
 			Name: "kube_pod_container_resource_limits",
 			Type: metric.Gauge,
-			Help: "The number of requested limit resource by a container.",
+			Help: "The number of requested limit resource by a container." + resourceUnitsHelp,
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				ms := []*metric.Metric{}
 
 				for _, c := range p.Spec.Containers {
-					lim := c.Resources.Limits
-
-					for resourceName, val := range lim {
-						switch resourceName {
-						case v1.ResourceCPU:
-							ms = append(ms, &metric.Metric{
-								Value:       float64(val.MilliValue()) / 1000,
-								LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitCore)},
-							})
-						case v1.ResourceStorage:
-							fallthrough
-						case v1.ResourceEphemeralStorage:
-							fallthrough
-						case v1.ResourceMemory:
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-								Value:       float64(val.Value()),
-							})
-						default:
-							if isHugePageResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-									Value:       float64(val.Value()),
-								})
-							}
-							if isAttachableVolumeResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									Value:       float64(val.Value()),
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-								})
-							}
-							if isExtendedResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									Value:       float64(val.Value()),
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitInteger)},
-								})
-							}
-						}
-					}
-				}
-
-				for _, metric := range ms {
-					metric.LabelKeys = []string{"container", "resource", "unit"}
+					ms = append(ms, resourceMetrics(c.Resources.Limits, []string{"container"}, []string{c.Name})...)
 				}
 
 				return &metric.Family{
@@ -888,54 +765,12 @@ var (
 		{
 			Name: "kube_pod_init_container_resource_requests",
 			Type: metric.Gauge,
-			Help: "The number of requested resources by the init container.",
+			Help: "The number of requested resources by the init container." + resourceUnitsHelp,
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				ms := []*metric.Metric{}
 
 				for _, c := range p.Spec.InitContainers {
-					req := c.Resources.Requests
-
-					for resourceName, val := range req {
-						switch resourceName {
-						case v1.ResourceCPU:
-							ms = append(ms, &metric.Metric{
-								Value:       float64(val.MilliValue()) / 1000,
-								LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitCore)},
-							})
-						case v1.ResourceStorage:
-							fallthrough
-						case v1.ResourceEphemeralStorage:
-							fallthrough
-						case v1.ResourceMemory:
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-								Value:       float64(val.Value()),
-							})
-						default:
-							if isHugePageResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-									Value:       float64(val.Value()),
-								})
-							}
-							if isAttachableVolumeResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									Value:       float64(val.Value()),
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-								})
-							}
-							if isExtendedResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									Value:       float64(val.Value()),
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitInteger)},
-								})
-							}
-						}
-					}
-				}
-
-				for _, metric := range ms {
-					metric.LabelKeys = []string{"container", "resource", "unit"}
+					ms = append(ms, resourceMetrics(c.Resources.Requests, []string{"container"}, []string{c.Name})...)
 				}
 
 				return &metric.Family{
@@ -946,54 +781,12 @@ var (
 		{
 			Name: "kube_pod_init_container_resource_limits",
 			Type: metric.Gauge,
-			Help: "The number of requested limit resource by the init container.",
+			Help: "The number of requested limit resource by the init container." + resourceUnitsHelp,
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				ms := []*metric.Metric{}
 
 				for _, c := range p.Spec.InitContainers {
-					lim := c.Resources.Limits
-
-					for resourceName, val := range lim {
-						switch resourceName {
-						case v1.ResourceCPU:
-							ms = append(ms, &metric.Metric{
-								Value:       float64(val.MilliValue()) / 1000,
-								LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitCore)},
-							})
-						case v1.ResourceStorage:
-							fallthrough
-						case v1.ResourceEphemeralStorage:
-							fallthrough
-						case v1.ResourceMemory:
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-								Value:       float64(val.Value()),
-							})
-						default:
-							if isHugePageResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-									Value:       float64(val.Value()),
-								})
-							}
-							if isAttachableVolumeResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									Value:       float64(val.Value()),
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-								})
-							}
-							if isExtendedResourceName(resourceName) {
-								ms = append(ms, &metric.Metric{
-									Value:       float64(val.Value()),
-									LabelValues: []string{c.Name, sanitizeLabelName(string(resourceName)), string(constant.UnitInteger)},
-								})
-							}
-						}
-					}
-				}
-
-				for _, metric := range ms {
-					metric.LabelKeys = []string{"container", "resource", "unit"}
+					ms = append(ms, resourceMetrics(c.Resources.Limits, []string{"container"}, []string{c.Name})...)
 				}
 
 				return &metric.Family{
@@ -1003,7 +796,7 @@ var (
 		},
 		{
 			Name: "kube_pod_spec_volumes_persistentvolumeclaims_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about persistentvolumeclaim volumes in a pod.",
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				ms := []*metric.Metric{}
@@ -1013,7 +806,6 @@ var (
 						ms = append(ms, &metric.Metric{
 							LabelKeys:   []string{"volume", "persistentvolumeclaim"},
 							LabelValues: []string{v.Name, v.PersistentVolumeClaim.ClaimName},
-							Value:       1,
 						})
 					}
 				}
@@ -1052,23 +844,15 @@ var (
 			GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
 				ms := []*metric.Metric{}
 
-				if p.Spec.Overhead != nil {
-					for resourceName, val := range p.Spec.Overhead {
-						switch resourceName {
-						case v1.ResourceCPU:
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{sanitizeLabelName(string(resourceName)), string(constant.UnitCore)},
-								Value:       float64(val.MilliValue()) / 1000,
-							})
-
-						case v1.ResourceMemory:
-							ms = append(ms, &metric.Metric{
-								LabelValues: []string{sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-								Value:       float64(val.Value()),
-							})
-						}
+				for resourceName, val := range p.Spec.Overhead {
+					if resourceName != v1.ResourceCPU && resourceName != v1.ResourceMemory {
+						continue
 					}
-
+					value, unit := resourceValue(resourceName, val)
+					ms = append(ms, &metric.Metric{
+						LabelValues: []string{sanitizeLabelName(string(resourceName)), string(unit)},
+						Value:       value,
+					})
 				}
 
 				for _, metric := range ms {
@@ -1081,29 +865,96 @@ var (
 			}),
 		},
 	}
+
+	// podDeprecatedMetricFamilies are replaced by the resource and unit labels
+	// of kube_pod_container_resource_requests and
+	// kube_pod_container_resource_limits, see deprecatedMetricFamilies.
+	podDeprecatedMetricFamilies = []generator.FamilyGenerator{
+		deprecatedContainerResourceFamily(
+			"kube_pod_container_resource_requests_cpu_cores",
+			"The number of requested cpu cores by a container.",
+			"kube_pod_container_resource_requests", v1.ResourceCPU,
+			func(c v1.Container) v1.ResourceList { return c.Resources.Requests },
+		),
+		deprecatedContainerResourceFamily(
+			"kube_pod_container_resource_requests_memory_bytes",
+			"The number of requested memory bytes by a container.",
+			"kube_pod_container_resource_requests", v1.ResourceMemory,
+			func(c v1.Container) v1.ResourceList { return c.Resources.Requests },
+		),
+		deprecatedContainerResourceFamily(
+			"kube_pod_container_resource_limits_cpu_cores",
+			"The limit on cpu cores to be used by a container.",
+			"kube_pod_container_resource_limits", v1.ResourceCPU,
+			func(c v1.Container) v1.ResourceList { return c.Resources.Limits },
+		),
+		deprecatedContainerResourceFamily(
+			"kube_pod_container_resource_limits_memory_bytes",
+			"The limit on memory to be used by a container in bytes.",
+			"kube_pod_container_resource_limits", v1.ResourceMemory,
+			func(c v1.Container) v1.ResourceList { return c.Resources.Limits },
+		),
+	}
 )
 
+// deprecatedContainerResourceFamily returns the metric family of the given
+// deprecated name, generating the value of the given resource in the resource
+// list of each container, which is replaced by the given metric family.
+func deprecatedContainerResourceFamily(name, help, replacement string, resourceName v1.ResourceName, resources func(v1.Container) v1.ResourceList) generator.FamilyGenerator {
+	return generator.FamilyGenerator{
+		Name: name,
+		Type: metric.Gauge,
+		Help: help + " Deprecated, use " + replacement + "{resource=\"" + string(resourceName) + "\"} instead.",
+		GenerateFunc: wrapPodFunc(func(p *v1.Pod) *metric.Family {
+			ms := []*metric.Metric{}
+
+			for _, c := range p.Spec.Containers {
+				if q, ok := resources(c)[resourceName]; ok {
+					value, _ := resourceValue(resourceName, q)
+					ms = append(ms, &metric.Metric{
+						LabelKeys:   []string{"container"},
+						LabelValues: []string{c.Name},
+						Value:       value,
+					})
+				}
+			}
+
+			return &metric.Family{
+				Metrics: ms,
+			}
+		}),
+	}
+}
+
+// podConditions returns the status conditions of the given pod.
+func podConditions(p *v1.Pod) []condition {
+	cs := make([]condition, len(p.Status.Conditions))
+	for i, c := range p.Status.Conditions {
+		cs[i] = condition{conditionType: string(c.Type), status: c.Status, lastTransitionTime: c.LastTransitionTime}
+	}
+	return cs
+}
+
 func wrapPodFunc(f func(*v1.Pod) *metric.Family) func(interface{}) *metric.Family {
 	return func(obj interface{}) *metric.Family {
 		pod := obj.(*v1.Pod)
 
 		metricFamily := f(pod)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descPodLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{pod.Namespace, pod.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descPodLabelsDefaultLabels, []string{pod.Namespace, pod.Name})
 
 		return metricFamily
 	}
 }
 
-func createPodListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createPodListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Pods(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Pods(ns).Watch(opts)
 		},
 	}
@@ -1123,6 +974,19 @@ func terminationReason(cs v1.ContainerStatus, reason string) bool {
 	return cs.State.Terminated.Reason == reason
 }
 
+// lastRestartTime returns the best available time of the last restart of the
+// container: the start of its current run or, if it is not running (yet), the
+// end of its last run.
+func lastRestartTime(cs v1.ContainerStatus) metav1.Time {
+	if cs.State.Running != nil {
+		return cs.State.Running.StartedAt
+	}
+	if cs.LastTerminationState.Terminated != nil {
+		return cs.LastTerminationState.Terminated.FinishedAt
+	}
+	return metav1.Time{}
+}
+
 func lastTerminationReason(cs v1.ContainerStatus, reason string) bool {
 	if cs.LastTerminationState.Terminated == nil {
 		return false
@@ -17,14 +17,18 @@ limitations under the License.
 package store
 
 import (
+	"fmt"
+	"runtime"
 	"testing"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
 )
 
 func TestPodStore(t *testing.T) {
@@ -56,6 +60,34 @@ func TestPodStore(t *testing.T) {
 			kube_pod_container_info{container="container1",container_id="docker://ab123",image="k8s.gcr.io/hyperkube1",image_id="docker://sha256:aaa",namespace="ns1",pod="pod1"} 1`,
 			MetricNames: []string{"kube_pod_container_info"},
 		},
+		{
+			// The image and container IDs are only set once the container
+			// has been created.
+			Obj: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod1",
+					Namespace: "ns1",
+				},
+				Status: v1.PodStatus{
+					ContainerStatuses: []v1.ContainerStatus{
+						{
+							Name:  "container1",
+							Image: "k8s.gcr.io/hyperkube1",
+							State: v1.ContainerState{
+								Waiting: &v1.ContainerStateWaiting{
+									Reason: "ContainerCreating",
+								},
+							},
+						},
+					},
+				},
+			},
+			Want: `
+			# HELP kube_pod_container_info Information about a container in a pod.
+			# TYPE kube_pod_container_info gauge
+			kube_pod_container_info{container="container1",container_id="",image="k8s.gcr.io/hyperkube1",image_id="",namespace="ns1",pod="pod1"} 1`,
+			MetricNames: []string{"kube_pod_container_info"},
+		},
 		{
 			Obj: &v1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
@@ -253,6 +285,62 @@ func TestPodStore(t *testing.T) {
 				`,
 			MetricNames: []string{"kube_pod_container_status_restarts_total"},
 		},
+		{
+			Obj: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod2",
+					Namespace: "ns2",
+				},
+				Status: v1.PodStatus{
+					ContainerStatuses: []v1.ContainerStatus{
+						{
+							Name:         "container2",
+							RestartCount: 0,
+							State: v1.ContainerState{
+								Running: &v1.ContainerStateRunning{
+									StartedAt: metav1.Unix(1501000000, 0),
+								},
+							},
+						},
+						{
+							Name:         "container3",
+							RestartCount: 2,
+							State: v1.ContainerState{
+								Running: &v1.ContainerStateRunning{
+									StartedAt: metav1.Unix(1502000000, 0),
+								},
+							},
+							LastTerminationState: v1.ContainerState{
+								Terminated: &v1.ContainerStateTerminated{
+									FinishedAt: metav1.Unix(1501900000, 0),
+								},
+							},
+						},
+						{
+							Name:         "container4",
+							RestartCount: 1,
+							State: v1.ContainerState{
+								Waiting: &v1.ContainerStateWaiting{
+									Reason: "CrashLoopBackOff",
+								},
+							},
+							LastTerminationState: v1.ContainerState{
+								Terminated: &v1.ContainerStateTerminated{
+									FinishedAt: metav1.Unix(1503000000, 0),
+								},
+							},
+						},
+					},
+				},
+			},
+			Want: `
+				# HELP kube_pod_container_status_last_restart_timestamp Approximate unix timestamp of the last restart of the container, only exposed for restarted containers. It is the start time of the running container or, if it is not running yet, the finish time of its last termination.
+				# TYPE kube_pod_container_status_last_restart_timestamp gauge
+				kube_pod_container_status_last_restart_timestamp{container="container3",namespace="ns2",pod="pod2"} 1.502e+09
+				kube_pod_container_status_last_restart_timestamp{container="container4",namespace="ns2",pod="pod2"} 1.503e+09
+				`,
+			MetricNames: []string{"kube_pod_container_status_last_restart_timestamp"},
+		},
 		{
 			Obj: &v1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
@@ -1328,10 +1416,10 @@ kube_pod_container_status_last_terminated_reason{container="container7",namespac
 				},
 			},
 			Want: `
-				# HELP kube_pod_container_resource_limits The number of requested limit resource by a container.
-				# HELP kube_pod_container_resource_requests The number of requested request resource by a container.
-				# HELP kube_pod_init_container_resource_limits The number of requested limit resource by the init container.
-				# HELP kube_pod_init_container_resource_requests The number of requested resources by the init container.
+				# HELP kube_pod_container_resource_limits The number of requested limit resource by a container. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
+				# HELP kube_pod_container_resource_requests The number of requested request resource by a container. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
+				# HELP kube_pod_init_container_resource_limits The number of requested limit resource by the init container. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
+				# HELP kube_pod_init_container_resource_requests The number of requested resources by the init container. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
 				# HELP kube_pod_init_container_status_last_terminated_reason Describes the last reason the init container was in terminated state.
 				# TYPE kube_pod_container_resource_limits gauge
 				# TYPE kube_pod_container_resource_requests gauge
@@ -1429,9 +1517,9 @@ kube_pod_container_status_last_terminated_reason{container="container7",namespac
 				},
 			},
 			Want: `
-				# HELP kube_pod_container_resource_limits The number of requested limit resource by a container.
-				# HELP kube_pod_container_resource_requests The number of requested request resource by a container.
-				# HELP kube_pod_init_container_resource_limits The number of requested limit resource by the init container.
+				# HELP kube_pod_container_resource_limits The number of requested limit resource by a container. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
+				# HELP kube_pod_container_resource_requests The number of requested request resource by a container. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
+				# HELP kube_pod_init_container_resource_limits The number of requested limit resource by the init container. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
 				# TYPE kube_pod_container_resource_limits gauge
 				# TYPE kube_pod_container_resource_requests gauge
 				# TYPE kube_pod_init_container_resource_limits gauge
@@ -1548,6 +1636,74 @@ kube_pod_container_status_last_terminated_reason{container="container7",namespac
 	}
 }
 
+func TestPodDeprecatedStore(t *testing.T) {
+	cases := []generateMetricsTestCase{
+		{
+			Obj: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod1",
+					Namespace: "ns1",
+				},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						{
+							Name: "pod1_con1",
+							Resources: v1.ResourceRequirements{
+								Requests: map[v1.ResourceName]resource.Quantity{
+									v1.ResourceCPU:    resource.MustParse("200m"),
+									v1.ResourceMemory: resource.MustParse("100M"),
+									"nvidia.com/gpu":  resource.MustParse("1"),
+								},
+								Limits: map[v1.ResourceName]resource.Quantity{
+									v1.ResourceCPU:    resource.MustParse("400m"),
+									v1.ResourceMemory: resource.MustParse("1Gi"),
+								},
+							},
+						},
+						{
+							Name: "pod1_con2",
+							Resources: v1.ResourceRequirements{
+								Requests: map[v1.ResourceName]resource.Quantity{
+									v1.ResourceCPU: resource.MustParse("1"),
+								},
+							},
+						},
+					},
+				},
+			},
+			Want: `
+				# HELP kube_pod_container_resource_limits_cpu_cores The limit on cpu cores to be used by a container. Deprecated, use kube_pod_container_resource_limits{resource="cpu"} instead.
+				# HELP kube_pod_container_resource_limits_memory_bytes The limit on memory to be used by a container in bytes. Deprecated, use kube_pod_container_resource_limits{resource="memory"} instead.
+				# HELP kube_pod_container_resource_requests_cpu_cores The number of requested cpu cores by a container. Deprecated, use kube_pod_container_resource_requests{resource="cpu"} instead.
+				# HELP kube_pod_container_resource_requests_memory_bytes The number of requested memory bytes by a container. Deprecated, use kube_pod_container_resource_requests{resource="memory"} instead.
+				# TYPE kube_pod_container_resource_limits_cpu_cores gauge
+				# TYPE kube_pod_container_resource_limits_memory_bytes gauge
+				# TYPE kube_pod_container_resource_requests_cpu_cores gauge
+				# TYPE kube_pod_container_resource_requests_memory_bytes gauge
+				kube_pod_container_resource_limits_cpu_cores{container="pod1_con1",namespace="ns1",pod="pod1"} 0.4
+				kube_pod_container_resource_limits_memory_bytes{container="pod1_con1",namespace="ns1",pod="pod1"} 1.073741824e+09
+				kube_pod_container_resource_requests_cpu_cores{container="pod1_con1",namespace="ns1",pod="pod1"} 0.2
+				kube_pod_container_resource_requests_cpu_cores{container="pod1_con2",namespace="ns1",pod="pod1"} 1
+				kube_pod_container_resource_requests_memory_bytes{container="pod1_con1",namespace="ns1",pod="pod1"} 1e+08
+			`,
+			MetricNames: []string{
+				"kube_pod_container_resource_requests_cpu_cores",
+				"kube_pod_container_resource_requests_memory_bytes",
+				"kube_pod_container_resource_limits_cpu_cores",
+				"kube_pod_container_resource_limits_memory_bytes",
+			},
+		},
+	}
+
+	for i, c := range cases {
+		c.Func = generator.ComposeMetricGenFuncs(podDeprecatedMetricFamilies)
+		c.Headers = generator.ExtractMetricFamilyHeaders(podDeprecatedMetricFamilies)
+		if err := c.run(); err != nil {
+			t.Errorf("unexpected collecting result in %vth run:\n%s", i, err)
+		}
+	}
+}
+
 func BenchmarkPodStore(b *testing.B) {
 	b.ReportAllocs()
 
@@ -1620,3 +1776,129 @@ func BenchmarkPodStore(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkPodStoreMemory reports the heap retained by a metrics store of
+// 100k pods spread over 100 namespaces. The store only retains the rendered
+// metrics of each pod, the metric.Metric values they are generated from are
+// garbage right after being rendered.
+func BenchmarkPodStoreMemory(b *testing.B) {
+	const podCount = 100000
+
+	pods := make([]*v1.Pod, podCount)
+	for i := range pods {
+		pods[i] = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("pod-%d", i),
+				Namespace: fmt.Sprintf("ns-%d", i%100),
+				UID:       types.UID(fmt.Sprintf("uid-%d", i)),
+			},
+			Spec: v1.PodSpec{
+				NodeName:   "node-1",
+				Containers: []v1.Container{{Name: "container1"}},
+			},
+			Status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				Conditions: []v1.PodCondition{
+					{Type: v1.PodReady, Status: v1.ConditionTrue},
+					{Type: v1.PodScheduled, Status: v1.ConditionTrue},
+				},
+				ContainerStatuses: []v1.ContainerStatus{
+					{
+						Name:        "container1",
+						Image:       "k8s.gcr.io/hyperkube1",
+						ImageID:     "docker://sha256:aaa",
+						ContainerID: "docker://ab123",
+						Ready:       true,
+						State: v1.ContainerState{
+							Running: &v1.ContainerStateRunning{},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	headers := generator.ExtractMetricFamilyHeaders(podMetricFamilies)
+	f := generator.ComposeMetricGenFuncs(podMetricFamilies)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		s := metricsstore.NewMetricsStore(headers, f)
+		for _, p := range pods {
+			if err := s.Add(p); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/podCount, "retained-B/pod")
+		runtime.KeepAlive(s)
+	}
+}
+
+func BenchmarkPodStoreGenerate(b *testing.B) {
+	const podCount = 10000
+
+	pods := make([]*v1.Pod, podCount)
+	for i := range pods {
+		pods[i] = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("pod-%d", i),
+				Namespace: fmt.Sprintf("ns-%d", i%100),
+				UID:       types.UID(fmt.Sprintf("uid-%d", i)),
+				Labels:    map[string]string{"app": "web", "tier": "frontend"},
+			},
+			Spec: v1.PodSpec{
+				NodeName: "node-1",
+				Containers: []v1.Container{
+					{
+						Name: "container1",
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU:    resource.MustParse("100m"),
+								v1.ResourceMemory: resource.MustParse("128Mi"),
+							},
+						},
+					},
+				},
+			},
+			Status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				Conditions: []v1.PodCondition{
+					{Type: v1.PodReady, Status: v1.ConditionTrue},
+					{Type: v1.PodScheduled, Status: v1.ConditionTrue},
+				},
+				ContainerStatuses: []v1.ContainerStatus{
+					{
+						Name:        "container1",
+						Image:       "k8s.gcr.io/hyperkube1",
+						ImageID:     "docker://sha256:aaa",
+						ContainerID: "docker://ab123",
+						Ready:       true,
+						State: v1.ContainerState{
+							Running: &v1.ContainerStateRunning{},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	f := generator.ComposeMetricGenFuncs(podMetricFamilies)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, p := range pods {
+			for _, family := range f(p) {
+				family.ByteSlice()
+			}
+		}
+	}
+}
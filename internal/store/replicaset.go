@@ -41,17 +41,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapReplicaSetFunc(func(r *v1.ReplicaSet) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !r.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-
-						Value: float64(r.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&r.CreationTimestamp),
 				}
 			}),
 		},
@@ -212,21 +203,20 @@ func wrapReplicaSetFunc(f func(*v1.ReplicaSet) *metric.Family) func(interface{})
 
 		metricFamily := f(replicaSet)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descReplicaSetLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{replicaSet.Namespace, replicaSet.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descReplicaSetLabelsDefaultLabels, []string{replicaSet.Namespace, replicaSet.Name})
 
 		return metricFamily
 	}
 }
 
-func createReplicaSetListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createReplicaSetListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AppsV1().ReplicaSets(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AppsV1().ReplicaSets(ns).Watch(opts)
 		},
 	}
@@ -39,16 +39,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapReplicationControllerFunc(func(r *v1.ReplicationController) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !r.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						Value: float64(r.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&r.CreationTimestamp),
 				}
 			}),
 		},
@@ -198,21 +190,20 @@ func wrapReplicationControllerFunc(f func(*v1.ReplicationController) *metric.Fam
 
 		metricFamily := f(replicationController)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descReplicationControllerLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{replicationController.Namespace, replicationController.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descReplicationControllerLabelsDefaultLabels, []string{replicationController.Namespace, replicationController.Name})
 
 		return metricFamily
 	}
 }
 
-func createReplicationControllerListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createReplicationControllerListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().ReplicationControllers(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().ReplicationControllers(ns).Watch(opts)
 		},
 	}
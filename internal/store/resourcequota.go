@@ -37,17 +37,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapResourceQuotaFunc(func(r *v1.ResourceQuota) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !r.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-
-						Value: float64(r.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&r.CreationTimestamp),
 				}
 			}),
 		},
@@ -89,21 +80,20 @@ func wrapResourceQuotaFunc(f func(*v1.ResourceQuota) *metric.Family) func(interf
 
 		metricFamily := f(resourceQuota)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descResourceQuotaLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{resourceQuota.Namespace, resourceQuota.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descResourceQuotaLabelsDefaultLabels, []string{resourceQuota.Namespace, resourceQuota.Name})
 
 		return metricFamily
 	}
 }
 
-func createResourceQuotaListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createResourceQuotaListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().ResourceQuotas(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().ResourceQuotas(ns).Watch(opts)
 		},
 	}
@@ -36,14 +36,12 @@ var (
 	secretMetricFamilies = []generator.FamilyGenerator{
 		{
 			Name: "kube_secret_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about secret.",
 			GenerateFunc: wrapSecretFunc(func(s *v1.Secret) *metric.Family {
 				return &metric.Family{
 					Metrics: []*metric.Metric{
-						{
-							Value: 1,
-						},
+						{},
 					},
 				}
 			}),
@@ -87,16 +85,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapSecretFunc(func(s *v1.Secret) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !s.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						Value: float64(s.CreationTimestamp.Unix()),
-					})
-				}
-
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&s.CreationTimestamp),
 				}
 			}),
 		},
@@ -119,21 +109,20 @@ func wrapSecretFunc(f func(*v1.Secret) *metric.Family) func(interface{}) *metric
 
 		metricFamily := f(secret)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descSecretLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{secret.Namespace, secret.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descSecretLabelsDefaultLabels, []string{secret.Namespace, secret.Name})
 
 		return metricFamily
 	}
 }
 
-func createSecretListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createSecretListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Secrets(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Secrets(ns).Watch(opts)
 		},
 	}
@@ -34,15 +34,18 @@ var (
 	descServiceLabelsDefaultLabels = []string{"namespace", "service"}
 
 	serviceMetricFamilies = []generator.FamilyGenerator{
+		// TODO: Add a load_balancer_class label to kube_service_info and
+		// kube_service_spec_allocate_load_balancer_node_ports once k8s.io/api
+		// is bumped to v0.21, the first release with both
+		// ServiceSpec.LoadBalancerClass and AllocateLoadBalancerNodePorts.
 		{
 			Name: "kube_service_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about service.",
 			GenerateFunc: wrapSvcFunc(func(s *v1.Service) *metric.Family {
 				m := metric.Metric{
 					LabelKeys:   []string{"cluster_ip", "external_name", "load_balancer_ip"},
 					LabelValues: []string{s.Spec.ClusterIP, s.Spec.ExternalName, s.Spec.LoadBalancerIP},
-					Value:       1,
 				}
 				return &metric.Family{Metrics: []*metric.Metric{&m}}
 			}),
@@ -52,15 +55,9 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapSvcFunc(func(s *v1.Service) *metric.Family {
-				if !s.CreationTimestamp.IsZero() {
-					m := metric.Metric{
-						LabelKeys:   nil,
-						LabelValues: nil,
-						Value:       float64(s.CreationTimestamp.Unix()),
-					}
-					return &metric.Family{Metrics: []*metric.Metric{&m}}
+				return &metric.Family{
+					Metrics: timestampMetrics(&s.CreationTimestamp),
 				}
-				return &metric.Family{Metrics: []*metric.Metric{}}
 			}),
 		},
 		{
@@ -153,21 +150,20 @@ func wrapSvcFunc(f func(*v1.Service) *metric.Family) func(interface{}) *metric.F
 
 		metricFamily := f(svc)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descServiceLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{svc.Namespace, svc.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descServiceLabelsDefaultLabels, []string{svc.Namespace, svc.Name})
 
 		return metricFamily
 	}
 }
 
-func createServiceListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createServiceListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Services(ns).List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.CoreV1().Services(ns).Watch(opts)
 		},
 	}
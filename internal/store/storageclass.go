@@ -36,7 +36,7 @@ var (
 	storageClassMetricFamilies = []generator.FamilyGenerator{
 		{
 			Name: "kube_storageclass_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about storageclass.",
 			GenerateFunc: wrapStorageClassFunc(func(s *storagev1.StorageClass) *metric.Family {
 
@@ -52,7 +52,6 @@ var (
 				m := metric.Metric{
 					LabelKeys:   []string{"provisioner", "reclaim_policy", "volume_binding_mode"},
 					LabelValues: []string{s.Provisioner, string(*s.ReclaimPolicy), string(*s.VolumeBindingMode)},
-					Value:       1,
 				}
 				return &metric.Family{Metrics: []*metric.Metric{&m}}
 			}),
@@ -62,14 +61,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapStorageClassFunc(func(s *storagev1.StorageClass) *metric.Family {
-				ms := []*metric.Metric{}
-				if !s.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						Value: float64(s.CreationTimestamp.Unix()),
-					})
-				}
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&s.CreationTimestamp),
 				}
 			}),
 		},
@@ -99,21 +92,20 @@ func wrapStorageClassFunc(f func(*storagev1.StorageClass) *metric.Family) func(i
 
 		metricFamily := f(storageClass)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descStorageClassLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{storageClass.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descStorageClassLabelsDefaultLabels, []string{storageClass.Name})
 
 		return metricFamily
 	}
 }
 
-func createStorageClassListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createStorageClassListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.StorageV1().StorageClasses().List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.StorageV1().StorageClasses().Watch(opts)
 		},
 	}
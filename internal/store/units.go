@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"k8s.io/kube-state-metrics/pkg/constant"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
+)
+
+// unitSuffixedName is the name of a metric family ending with the unit of its
+// values, replacing a deprecated name lacking the unit.
+type unitSuffixedName struct {
+	name string
+	unit string
+}
+
+// unitSuffixedNames maps the deprecated names of the metric families lacking
+// the unit of their values to the names replacing them. The values are the
+// same under both names. Timestamps are in seconds since the epoch.
+var unitSuffixedNames = map[string]unitSuffixedName{
+	"kube_certificatesigningrequest_cert_length":  {name: "kube_certificatesigningrequest_cert_length_bytes", unit: "bytes"},
+	"kube_certificatesigningrequest_created":      {name: "kube_certificatesigningrequest_created_timestamp_seconds", unit: "seconds"},
+	"kube_configmap_created":                      {name: "kube_configmap_created_timestamp_seconds", unit: "seconds"},
+	"kube_cronjob_created":                        {name: "kube_cronjob_created_timestamp_seconds", unit: "seconds"},
+	"kube_cronjob_next_schedule_time":             {name: "kube_cronjob_next_schedule_time_seconds", unit: "seconds"},
+	"kube_cronjob_status_last_schedule_time":      {name: "kube_cronjob_status_last_schedule_time_seconds", unit: "seconds"},
+	"kube_daemonset_created":                      {name: "kube_daemonset_created_timestamp_seconds", unit: "seconds"},
+	"kube_deployment_created":                     {name: "kube_deployment_created_timestamp_seconds", unit: "seconds"},
+	"kube_endpoint_created":                       {name: "kube_endpoint_created_timestamp_seconds", unit: "seconds"},
+	"kube_ingress_created":                        {name: "kube_ingress_created_timestamp_seconds", unit: "seconds"},
+	"kube_job_created":                            {name: "kube_job_created_timestamp_seconds", unit: "seconds"},
+	"kube_job_status_completion_time":             {name: "kube_job_status_completion_time_seconds", unit: "seconds"},
+	"kube_job_status_start_time":                  {name: "kube_job_status_start_time_seconds", unit: "seconds"},
+	"kube_lease_renew_time":                       {name: "kube_lease_renew_time_seconds", unit: "seconds"},
+	"kube_limitrange_created":                     {name: "kube_limitrange_created_timestamp_seconds", unit: "seconds"},
+	"kube_mutatingwebhookconfiguration_created":   {name: "kube_mutatingwebhookconfiguration_created_timestamp_seconds", unit: "seconds"},
+	"kube_namespace_created":                      {name: "kube_namespace_created_timestamp_seconds", unit: "seconds"},
+	"kube_networkpolicy_created":                  {name: "kube_networkpolicy_created_timestamp_seconds", unit: "seconds"},
+	"kube_node_created":                           {name: "kube_node_created_timestamp_seconds", unit: "seconds"},
+	"kube_pod_completion_time":                    {name: "kube_pod_completion_time_seconds", unit: "seconds"},
+	"kube_pod_created":                            {name: "kube_pod_created_timestamp_seconds", unit: "seconds"},
+	"kube_pod_deletion_timestamp":                 {name: "kube_pod_deletion_timestamp_seconds", unit: "seconds"},
+	"kube_pod_start_time":                         {name: "kube_pod_start_time_seconds", unit: "seconds"},
+	"kube_pod_status_scheduled_time":              {name: "kube_pod_status_scheduled_time_seconds", unit: "seconds"},
+	"kube_poddisruptionbudget_created":            {name: "kube_poddisruptionbudget_created_timestamp_seconds", unit: "seconds"},
+	"kube_replicaset_created":                     {name: "kube_replicaset_created_timestamp_seconds", unit: "seconds"},
+	"kube_replicationcontroller_created":          {name: "kube_replicationcontroller_created_timestamp_seconds", unit: "seconds"},
+	"kube_resourcequota_created":                  {name: "kube_resourcequota_created_timestamp_seconds", unit: "seconds"},
+	"kube_secret_created":                         {name: "kube_secret_created_timestamp_seconds", unit: "seconds"},
+	"kube_service_created":                        {name: "kube_service_created_timestamp_seconds", unit: "seconds"},
+	"kube_statefulset_created":                    {name: "kube_statefulset_created_timestamp_seconds", unit: "seconds"},
+	"kube_storageclass_created":                   {name: "kube_storageclass_created_timestamp_seconds", unit: "seconds"},
+	"kube_validatingwebhookconfiguration_created": {name: "kube_validatingwebhookconfiguration_created_timestamp_seconds", unit: "seconds"},
+	"kube_volumeattachment_created":               {name: "kube_volumeattachment_created_timestamp_seconds", unit: "seconds"},
+}
+
+// unitSuffixMetricFamilies returns the given metric families, the deprecated
+// names of which are replaced by the names ending with their unit, see
+// unitSuffixedNames. If emitDeprecated is set, the metric families are
+// generated under their deprecated names as well, so that dashboards can be
+// migrated at their own pace.
+func unitSuffixMetricFamilies(families []generator.FamilyGenerator, emitDeprecated bool) []generator.FamilyGenerator {
+	renamed := make([]generator.FamilyGenerator, 0, len(families))
+	for _, f := range families {
+		n, ok := unitSuffixedNames[f.Name]
+		if !ok {
+			renamed = append(renamed, f)
+			continue
+		}
+
+		deprecated := f
+		f.Name = n.name
+		renamed = append(renamed, f)
+		if emitDeprecated {
+			deprecated.Help = strings.TrimSuffix(deprecated.Help, ".") + ". Deprecated, use " + n.name + " instead."
+			renamed = append(renamed, deprecated)
+		}
+	}
+	return renamed
+}
+
+// deprecatedMetricFamilies maps the resources to the metric families replaced
+// by metric families labeled with the resource and unit of their values, see
+// resourceMetrics(). They are only generated if the deprecated names are
+// emitted.
+var deprecatedMetricFamilies = map[string][]generator.FamilyGenerator{
+	"nodes": nodeDeprecatedMetricFamilies,
+	"pods":  podDeprecatedMetricFamilies,
+}
+
+// resourceUnitsHelp documents the unit of the values of the resources, see
+// resourceValue(), in the help of the metric families generated by
+// resourceMetrics().
+const resourceUnitsHelp = " The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu."
+
+// resourceValue returns the value of the given quantity of the given resource
+// in the unit of the resource, see resourceUnitsHelp.
+func resourceValue(name v1.ResourceName, q resource.Quantity) (float64, constant.ResourceUnit) {
+	switch {
+	case name == v1.ResourceCPU:
+		return float64(q.MilliValue()) / 1000, constant.UnitCore
+	case name == v1.ResourceMemory, name == v1.ResourceStorage, name == v1.ResourceEphemeralStorage,
+		isHugePageResourceName(name), isAttachableVolumeResourceName(name):
+		return float64(q.Value()), constant.UnitByte
+	default:
+		return float64(q.Value()), constant.UnitInteger
+	}
+}
+
+// resourceMetrics returns a metric for each resource of the given list,
+// labeled with the given labels followed by the resource and its unit.
+func resourceMetrics(resources v1.ResourceList, labelKeys, labelValues []string) []*metric.Metric {
+	keys := append(append(make([]string, 0, len(labelKeys)+2), labelKeys...), "resource", "unit")
+	ms := make([]*metric.Metric, 0, len(resources))
+	for name, q := range resources {
+		value, unit := resourceValue(name, q)
+		values := append(append(make([]string, 0, len(keys)), labelValues...), sanitizeLabelName(string(name)), string(unit))
+		ms = append(ms, &metric.Metric{
+			LabelKeys:   keys,
+			LabelValues: values,
+			Value:       value,
+		})
+	}
+	return ms
+}
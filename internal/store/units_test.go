@@ -0,0 +1,170 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"k8s.io/kube-state-metrics/pkg/constant"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
+)
+
+func TestUnitSuffixedNames(t *testing.T) {
+	families := map[string]generator.FamilyGenerator{}
+	for _, fs := range availableMetricFamilies {
+		for _, f := range fs {
+			families[f.Name] = f
+		}
+	}
+
+	for deprecated, n := range unitSuffixedNames {
+		f, ok := families[deprecated]
+		if !ok {
+			t.Errorf("%s: no metric family of the deprecated name", deprecated)
+			continue
+		}
+		if _, ok := families[n.name]; ok {
+			t.Errorf("%s: name %s collides with an existing metric family", deprecated, n.name)
+		}
+		if !strings.HasPrefix(n.name, deprecated+"_") || !strings.HasSuffix(n.name, "_"+n.unit) {
+			t.Errorf("%s: expected name %s to extend the deprecated name with unit %s", deprecated, n.name, n.unit)
+		}
+		if f.Type != metric.Gauge {
+			t.Errorf("%s: expected gauge, got %s", deprecated, f.Type)
+		}
+		if want := "# UNIT " + n.name + " " + n.unit; !strings.Contains(metric.OpenMetricsHeader(n.name, f.Help, f.Type), want) {
+			t.Errorf("%s: expected OpenMetrics header to contain %q", deprecated, want)
+		}
+	}
+}
+
+func TestUnitSuffixMetricFamilies(t *testing.T) {
+	families := []generator.FamilyGenerator{
+		{Name: "kube_pod_info", Help: "Information about pod."},
+		{Name: "kube_pod_created", Help: "Unix creation timestamp"},
+	}
+
+	tests := []struct {
+		emitDeprecated bool
+		want           []generator.FamilyGenerator
+	}{
+		{
+			emitDeprecated: false,
+			want: []generator.FamilyGenerator{
+				{Name: "kube_pod_info", Help: "Information about pod."},
+				{Name: "kube_pod_created_timestamp_seconds", Help: "Unix creation timestamp"},
+			},
+		},
+		{
+			emitDeprecated: true,
+			want: []generator.FamilyGenerator{
+				{Name: "kube_pod_info", Help: "Information about pod."},
+				{Name: "kube_pod_created_timestamp_seconds", Help: "Unix creation timestamp"},
+				{Name: "kube_pod_created", Help: "Unix creation timestamp. Deprecated, use kube_pod_created_timestamp_seconds instead."},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got := unitSuffixMetricFamilies(families, test.emitDeprecated)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("emitDeprecated %t: want %+v, got %+v", test.emitDeprecated, test.want, got)
+		}
+	}
+	if families[1].Name != "kube_pod_created" {
+		t.Errorf("expected given metric families to be left untouched, got %+v", families)
+	}
+}
+
+func TestDeprecatedMetricFamilies(t *testing.T) {
+	for resource, deprecated := range deprecatedMetricFamilies {
+		families := map[string]struct{}{}
+		for _, f := range unitSuffixMetricFamilies(availableMetricFamilies[resource], true) {
+			families[f.Name] = struct{}{}
+		}
+		for _, f := range deprecated {
+			if _, ok := families[f.Name]; ok {
+				t.Errorf("%s: deprecated metric family %s collides with an existing metric family", resource, f.Name)
+			}
+		}
+
+		for _, emitDeprecated := range []bool{false, true} {
+			b := NewBuilder()
+			b.WithEmitDeprecatedMetricNames(emitDeprecated)
+			got := map[string]struct{}{}
+			for _, f := range b.metricFamilies(resource) {
+				got[f.Name] = struct{}{}
+			}
+			for _, f := range deprecated {
+				if _, ok := got[f.Name]; ok != emitDeprecated {
+					t.Errorf("%s: expected %s to be generated only with emitDeprecated, got %t with emitDeprecated %t", resource, f.Name, ok, emitDeprecated)
+				}
+			}
+		}
+	}
+}
+
+func TestResourceValue(t *testing.T) {
+	tests := []struct {
+		name      v1.ResourceName
+		quantity  string
+		wantValue float64
+		wantUnit  constant.ResourceUnit
+	}{
+		{v1.ResourceCPU, "250m", 0.25, constant.UnitCore},
+		{v1.ResourceCPU, "2", 2, constant.UnitCore},
+		{v1.ResourceMemory, "1Gi", 1 << 30, constant.UnitByte},
+		{v1.ResourceStorage, "10G", 1e10, constant.UnitByte},
+		{v1.ResourceEphemeralStorage, "500M", 5e8, constant.UnitByte},
+		{"hugepages-2Mi", "4Mi", 4 << 20, constant.UnitByte},
+		{"attachable-volumes-aws-ebs", "39", 39, constant.UnitByte},
+		{"nvidia.com/gpu", "2", 2, constant.UnitInteger},
+		{v1.ResourcePods, "110", 110, constant.UnitInteger},
+		{"example.com/foo", "3", 3, constant.UnitInteger},
+		{"unknown", "1k", 1000, constant.UnitInteger},
+	}
+
+	for _, test := range tests {
+		value, unit := resourceValue(test.name, resource.MustParse(test.quantity))
+		if value != test.wantValue || unit != test.wantUnit {
+			t.Errorf("%s %s: want %v %s, got %v %s", test.name, test.quantity, test.wantValue, test.wantUnit, value, unit)
+		}
+	}
+}
+
+func TestResourceMetrics(t *testing.T) {
+	got := resourceMetrics(v1.ResourceList{
+		v1.ResourceCPU:   resource.MustParse("100m"),
+		"nvidia.com/gpu": resource.MustParse("1"),
+	}, []string{"container"}, []string{"c"})
+	sort.Slice(got, func(i, j int) bool { return got[i].LabelValues[1] < got[j].LabelValues[1] })
+
+	want := []*metric.Metric{
+		{LabelKeys: []string{"container", "resource", "unit"}, LabelValues: []string{"c", "cpu", "core"}, Value: 0.1},
+		{LabelKeys: []string{"container", "resource", "unit"}, LabelValues: []string{"c", "nvidia_com_gpu", "integer"}, Value: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
@@ -18,14 +18,17 @@ package store
 
 import (
 	"fmt"
+	"hash/fnv"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"k8s.io/apimachinery/pkg/util/validation"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/kube-state-metrics/pkg/metric"
 )
@@ -35,6 +38,46 @@ var (
 	conditionStatuses  = []v1.ConditionStatus{v1.ConditionTrue, v1.ConditionFalse, v1.ConditionUnknown}
 )
 
+// addDefaultLabels prepends the default labels of a resource, identifying the
+// object, to the labels of every metric of the given family. Every metric gets
+// its own copy of the keys, so that they can be renamed per metric, see
+// remapDefaultLabels. The keys and values of a metric share a single
+// allocation, capped so that appending to the keys cannot overwrite the
+// values, and neither aliases the given default labels.
+func addDefaultLabels(f *metric.Family, keys, values []string) {
+	for _, m := range f.Metrics {
+		n := len(keys) + len(m.LabelKeys)
+		labels := make([]string, 0, 2*n)
+		labels = append(append(labels, keys...), m.LabelKeys...)
+		labels = append(append(labels, values...), m.LabelValues...)
+		m.LabelKeys = labels[:n:n]
+		m.LabelValues = labels[n:]
+	}
+}
+
+// timestamp is implemented by *metav1.Time and *metav1.MicroTime, which are
+// zero if nil.
+type timestamp interface {
+	IsZero() bool
+	Unix() int64
+}
+
+// timestampMetrics returns a metric with the given timestamp as Unix time, or
+// no metric if the timestamp is unset. A nil or zero timestamp means that the
+// event has not happened, which is not exposed as the Unix epoch. All metric
+// families of timestamps are generated by it.
+func timestampMetrics(t timestamp) []*metric.Metric {
+	if t.IsZero() {
+		return []*metric.Metric{}
+	}
+
+	return []*metric.Metric{
+		{
+			Value: float64(t.Unix()),
+		},
+	}
+}
+
 func resourceVersionMetric(rv string) []*metric.Metric {
 	v, err := strconv.ParseFloat(rv, 64)
 	if err != nil {
@@ -56,17 +99,55 @@ func boolFloat64(b bool) float64 {
 	return 0
 }
 
-// addConditionMetrics generates one metric for each possible condition
-// status. For this function to work properly, the last label in the metric
-// description must be the condition.
-func addConditionMetrics(cs v1.ConditionStatus) []*metric.Metric {
-	ms := make([]*metric.Metric, len(conditionStatuses))
+// condition is the status condition of an object, with the fields common to
+// the condition types of all resources.
+type condition struct {
+	conditionType      string
+	status             v1.ConditionStatus
+	reason             string
+	lastTransitionTime metav1.Time
+}
 
-	for i, status := range conditionStatuses {
-		ms[i] = &metric.Metric{
-			LabelValues: []string{strings.ToLower(string(status))},
-			Value:       boolFloat64(cs == status),
+// latestConditions returns the given conditions with only the latest
+// transitioned condition of each type, in the order of the first condition of
+// each type, so that the metric families of conditions expose each type once.
+func latestConditions(conditions []condition) []condition {
+	latest := make([]condition, 0, len(conditions))
+next:
+	for _, c := range conditions {
+		for i := range latest {
+			if latest[i].conditionType != c.conditionType {
+				continue
+			}
+			if latest[i].lastTransitionTime.Before(&c.lastTransitionTime) {
+				latest[i] = c
+			}
+			continue next
 		}
+		latest = append(latest, c)
+	}
+	return latest
+}
+
+// addConditionMetrics generates one metric for each possible condition
+// status as a state set, labelled with the given key and the status.
+func addConditionMetrics(labelKey string, cs v1.ConditionStatus) []*metric.Metric {
+	states := make([]string, len(conditionStatuses))
+	for i, status := range conditionStatuses {
+		states[i] = strings.ToLower(string(status))
+	}
+
+	return metric.StateSetMetrics(labelKey, states, strings.ToLower(string(cs)))
+}
+
+// addConditionMetricsWithType generates the metrics of addConditionMetrics for
+// a condition of the given type, labelled with the condition and its status.
+func addConditionMetricsWithType(conditionType string, cs v1.ConditionStatus) []*metric.Metric {
+	ms := addConditionMetrics("status", cs)
+
+	for _, m := range ms {
+		m.LabelKeys = []string{"condition", "status"}
+		m.LabelValues = []string{conditionType, m.LabelValues[0]}
 	}
 
 	return ms
@@ -76,6 +157,51 @@ func kubeLabelsToPrometheusLabels(labels map[string]string) ([]string, []string)
 	return mapToPrometheusLabels(labels, "label")
 }
 
+// convertedLabelPrefixes are the prefixes of the label names converted from
+// Kubernetes labels and annotations, the values of which are arbitrary user
+// data and therefore subject to truncateLabelValue.
+var convertedLabelPrefixes = []string{"label_", "annotation_"}
+
+// isConvertedLabel reports whether the label of the given name is converted
+// from a Kubernetes label or annotation.
+func isConvertedLabel(name string) bool {
+	for _, prefix := range convertedLabelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncatedLabelValueMarker is appended to label values truncated by
+// truncateLabelValue.
+const truncatedLabelValueMarker = "..."
+
+// truncateLabelValue truncates the given label value to at most max bytes,
+// including the appended truncatedLabelValueMarker, without splitting a UTF-8
+// encoded character, and reports whether it did. Values are truncated before
+// being escaped, so escape sequences are never split, but escaping may make
+// the exposed value longer again. A max of 0 disables truncation.
+func truncateLabelValue(v string, max int) (string, bool) {
+	if max <= 0 || len(v) <= max {
+		return v, false
+	}
+
+	end := max - len(truncatedLabelValueMarker)
+	if end < 0 {
+		end = 0
+	}
+	for end > 0 && !utf8.RuneStart(v[end]) {
+		end--
+	}
+	return v[:end] + truncatedLabelValueMarker, true
+}
+
+// mapToPrometheusLabels converts the given map into Prometheus labels, ordered
+// by key, the sanitized keys of which are prefixed with the given prefix. Keys
+// sanitized to the name of a preceding label, e.g. app_kubernetes_io/name
+// following app.kubernetes.io/name, get a suffix hashed from the original key,
+// so that label names are unique and stable across restarts.
 func mapToPrometheusLabels(labels map[string]string, prefix string) ([]string, []string) {
 	labelKeys := make([]string, 0, len(labels))
 	for k := range labels {
@@ -83,18 +209,60 @@ func mapToPrometheusLabels(labels map[string]string, prefix string) ([]string, [
 	}
 	sort.Strings(labelKeys)
 
+	// Only keys changed by sanitization can collide.
+	var seen map[string]struct{}
+	for _, k := range labelKeys {
+		if !isValidLabelName(k) {
+			seen = make(map[string]struct{}, len(labelKeys))
+			break
+		}
+	}
+
 	labelValues := make([]string, 0, len(labels))
 	for i, k := range labelKeys {
 		labelKeys[i] = prefix + "_" + sanitizeLabelName(k)
 		labelValues = append(labelValues, labels[k])
+		if seen == nil {
+			continue
+		}
+		for {
+			if _, ok := seen[labelKeys[i]]; !ok {
+				break
+			}
+			labelKeys[i] = fmt.Sprintf("%s_%08x", labelKeys[i], labelNameHash(k))
+		}
+		seen[labelKeys[i]] = struct{}{}
 	}
 	return labelKeys, labelValues
 }
 
+// labelNameHash returns the hash of a label name, which disambiguates labels
+// sanitized to the same name.
+func labelNameHash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
 func sanitizeLabelName(s string) string {
+	if isValidLabelName(s) {
+		return s
+	}
 	return invalidLabelCharRE.ReplaceAllString(s, "_")
 }
 
+// isValidLabelName returns whether the given label name contains no
+// characters matched by invalidLabelCharRE, without allocating.
+func isValidLabelName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
 func isHugePageResourceName(name v1.ResourceName) bool {
 	return strings.HasPrefix(string(name), v1.ResourceHugePagesPrefix)
 }
@@ -17,10 +17,24 @@ limitations under the License.
 package store
 
 import (
+	"bytes"
 	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
+	"github.com/prometheus/common/expfmt"
+	v1batch "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/kube-state-metrics/pkg/metric"
+	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
 )
 
 func TestIsHugePageSizeFromResourceName(t *testing.T) {
@@ -176,6 +190,23 @@ func TestKubeLabelsToPrometheusLabels(t *testing.T) {
 			expectKeys:   []string{"label_an", "label_order", "label_test"},
 			expectValues: []string{"", "", ""},
 		},
+		{
+			kubeLabels: map[string]string{
+				"app.kubernetes.io/name": "first",
+				"app_kubernetes_io/name": "second",
+				"app_kubernetes_io_name": "third",
+			},
+			expectKeys:   []string{"label_app_kubernetes_io_name", "label_app_kubernetes_io_name_5d48bb14", "label_app_kubernetes_io_name_905936e4"},
+			expectValues: []string{"first", "second", "third"},
+		},
+		{
+			kubeLabels: map[string]string{
+				"app_kubernetes_io_name": "valid",
+				"app.kubernetes.io/name": "sorted_first",
+			},
+			expectKeys:   []string{"label_app_kubernetes_io_name", "label_app_kubernetes_io_name_905936e4"},
+			expectValues: []string{"sorted_first", "valid"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -198,3 +229,266 @@ func TestKubeLabelsToPrometheusLabels(t *testing.T) {
 	}
 
 }
+
+func TestKubeLabelsToPrometheusLabelsEscaping(t *testing.T) {
+	// Label keys and values as well as help texts are generated from
+	// characters which need escaping or are meaningful in the text format.
+	alphabet := []rune("ab_09\"\\\n{}=, #\t/.:-\u00e9\u2603")
+	hostile := func(r *rand.Rand) string {
+		s := make([]rune, r.Intn(16))
+		for i := range s {
+			s[i] = alphabet[r.Intn(len(alphabet))]
+		}
+		return string(s)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		labels := map[string]string{}
+		for j := r.Intn(5); j > 0; j-- {
+			labels[hostile(r)] = hostile(r)
+		}
+		labels["json"] = `{"key": "value\nwith \"quotes\"", "path": "C:\\dir"}`
+		help := hostile(r)
+
+		keys, values := kubeLabelsToPrometheusLabels(labels)
+		f := metric.Family{
+			Name:    "kube_pod_labels",
+			Metrics: []*metric.Metric{{LabelKeys: keys, LabelValues: values, Value: 1}},
+		}
+		exposition := metric.Header(f.Name, help, metric.Gauge) + "\n" + string(f.ByteSlice())
+
+		var parser expfmt.TextParser
+		families, err := parser.TextToMetricFamilies(bytes.NewBufferString(exposition))
+		if err != nil {
+			t.Fatalf("failed to parse labels %q with help %q: %v\n%s", labels, help, err, exposition)
+		}
+		family, ok := families["kube_pod_labels"]
+		if !ok || len(family.GetMetric()) != 1 {
+			t.Fatalf("expected a single metric, got %v\n%s", families, exposition)
+		}
+		// The parser drops leading blanks of help texts.
+		if expected := strings.TrimLeft(help, " \t"); family.GetHelp() != expected {
+			t.Errorf("expected help %q, got %q", expected, family.GetHelp())
+		}
+		got := map[string]string{}
+		for _, l := range family.GetMetric()[0].GetLabel() {
+			got[l.GetName()] = l.GetValue()
+		}
+		if len(got) != len(labels) {
+			t.Errorf("expected %d unique label names, got %q", len(labels), keys)
+		}
+		for i, k := range keys {
+			if got[k] != values[i] {
+				t.Errorf("expected label %s=%q, got %q\n%s", k, values[i], got[k], exposition)
+			}
+			if strings.ContainsAny(k, "\"\\\n{}=, ") {
+				t.Errorf("expected sanitized label name, got %q", k)
+			}
+		}
+	}
+}
+
+func TestTruncateLabelValue(t *testing.T) {
+	tests := []struct {
+		desc      string
+		value     string
+		max       int
+		want      string
+		truncated bool
+	}{
+		{desc: "unlimited", value: "abcdefghij", max: 0, want: "abcdefghij"},
+		{desc: "shorter", value: "abc", max: 8, want: "abc"},
+		{desc: "exact", value: "abcdefgh", max: 8, want: "abcdefgh"},
+		{desc: "longer", value: "abcdefghij", max: 8, want: "abcde...", truncated: true},
+		// "é" is encoded in 2 bytes, "☃" in 3 bytes.
+		{desc: "two-byte character at the boundary", value: "abcdéfghij", max: 8, want: "abcd...", truncated: true},
+		{desc: "three-byte character at the boundary", value: "abc☃defghij", max: 8, want: "abc...", truncated: true},
+		{desc: "three-byte character before the boundary", value: "ab☃defghij", max: 8, want: "ab☃...", truncated: true},
+		{desc: "multi-byte characters only", value: "☃☃☃☃", max: 8, want: "☃...", truncated: true},
+		{desc: "multi-byte character longer than the value kept", value: "☃☃☃☃", max: 5, want: "...", truncated: true},
+		{desc: "escaped characters", value: "a\"b\\c\nd\"e", max: 8, want: "a\"b\\c...", truncated: true},
+	}
+
+	for _, test := range tests {
+		got, truncated := truncateLabelValue(test.value, test.max)
+		if got != test.want || truncated != test.truncated {
+			t.Errorf("%s: expected %q (truncated %v), got %q (truncated %v)", test.desc, test.want, test.truncated, got, truncated)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("%s: expected valid UTF-8, got %q", test.desc, got)
+		}
+		if test.max > 0 && len(got) > test.max {
+			t.Errorf("%s: expected at most %d bytes, got %d", test.desc, test.max, len(got))
+		}
+	}
+}
+
+func TestTruncateLabelValueEscaping(t *testing.T) {
+	// Values ending in characters which need escaping are truncated before
+	// escaping, so that no escape sequence is split.
+	alphabet := []rune("ab\"\\\n\u00e9\u2603")
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		value := make([]rune, r.Intn(32))
+		for j := range value {
+			value[j] = alphabet[r.Intn(len(alphabet))]
+		}
+		truncated, _ := truncateLabelValue(string(value), 4+r.Intn(16))
+
+		f := metric.Family{
+			Name:    "kube_pod_labels",
+			Metrics: []*metric.Metric{{LabelKeys: []string{"label_app"}, LabelValues: []string{truncated}, Value: 1}},
+		}
+		exposition := metric.Header(f.Name, "Kubernetes labels converted to Prometheus labels.", metric.Gauge) + "\n" + string(f.ByteSlice())
+
+		var parser expfmt.TextParser
+		families, err := parser.TextToMetricFamilies(bytes.NewBufferString(exposition))
+		if err != nil {
+			t.Fatalf("failed to parse truncated value %q: %v\n%s", truncated, err, exposition)
+		}
+		if got := families["kube_pod_labels"].GetMetric()[0].GetLabel()[0].GetValue(); got != truncated {
+			t.Errorf("expected label value %q, got %q", truncated, got)
+		}
+	}
+}
+
+func TestAddDefaultLabels(t *testing.T) {
+	// The default labels have spare capacity, which appending to them in
+	// place would overwrite.
+	defaultKeys := make([]string, 2, 8)
+	copy(defaultKeys, []string{"namespace", "pod"})
+	defaultValues := make([]string, 2, 8)
+	copy(defaultValues, []string{"ns1", "pod1"})
+
+	first := &metric.Family{Metrics: []*metric.Metric{
+		{LabelKeys: []string{"container"}, LabelValues: []string{"c1"}},
+		{LabelKeys: []string{"container"}, LabelValues: []string{"c2"}},
+	}}
+	second := &metric.Family{Metrics: []*metric.Metric{
+		{LabelKeys: []string{"phase"}, LabelValues: []string{"Running"}},
+	}}
+	addDefaultLabels(first, defaultKeys, defaultValues)
+	addDefaultLabels(second, defaultKeys, defaultValues)
+
+	// Renaming a default label of a single metric and appending custom labels
+	// must neither affect other metrics nor the values of the metric.
+	first.Metrics[0].LabelKeys[1] = "renamed"
+	for _, m := range append(first.Metrics, second.Metrics...) {
+		m.AppendLabels([]string{"cluster"}, []string{"prod"})
+	}
+
+	expected := []metric.Metric{
+		{LabelKeys: []string{"namespace", "renamed", "container", "cluster"}, LabelValues: []string{"ns1", "pod1", "c1", "prod"}},
+		{LabelKeys: []string{"namespace", "pod", "container", "cluster"}, LabelValues: []string{"ns1", "pod1", "c2", "prod"}},
+		{LabelKeys: []string{"namespace", "pod", "phase", "cluster"}, LabelValues: []string{"ns1", "pod1", "Running", "prod"}},
+	}
+	for i, m := range append(first.Metrics, second.Metrics...) {
+		if !reflect.DeepEqual(*m, expected[i]) {
+			t.Errorf("metric %d: expected %v, got %v", i, expected[i], *m)
+		}
+	}
+	if !reflect.DeepEqual(defaultKeys[:cap(defaultKeys)], []string{"namespace", "pod", "", "", "", "", "", ""}) ||
+		!reflect.DeepEqual(defaultValues[:cap(defaultValues)], []string{"ns1", "pod1", "", "", "", "", "", ""}) {
+		t.Errorf("expected the default labels to stay unchanged, got %q and %q", defaultKeys[:cap(defaultKeys)], defaultValues[:cap(defaultValues)])
+	}
+}
+
+func TestTimestampMetrics(t *testing.T) {
+	ts := metav1.Unix(1501569018, 0)
+	microTS := metav1.NewMicroTime(ts.Time)
+
+	tests := []struct {
+		desc      string
+		timestamp timestamp
+		want      []*metric.Metric
+	}{
+		{"nil time", (*metav1.Time)(nil), []*metric.Metric{}},
+		{"zero time", &metav1.Time{}, []*metric.Metric{}},
+		{"nil micro time", (*metav1.MicroTime)(nil), []*metric.Metric{}},
+		{"time", &ts, []*metric.Metric{{Value: 1501569018}}},
+		{"micro time", &microTS, []*metric.Metric{{Value: 1501569018}}},
+	}
+
+	for _, test := range tests {
+		if got := timestampMetrics(test.timestamp); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: expected %v, got %v", test.desc, test.want, got)
+		}
+	}
+}
+
+func TestTimestampMetricFamilies(t *testing.T) {
+	ts := metav1.Unix(1501569018, 0)
+	microTS := metav1.NewMicroTime(ts.Time)
+
+	// Each family is generated for an object without and with the
+	// timestamp set. Unset timestamps must not be exposed as 0.
+	tests := []struct {
+		families []generator.FamilyGenerator
+		family   string
+		unset    interface{}
+		set      interface{}
+	}{
+		{podMetricFamilies, "kube_pod_created", &v1.Pod{}, &v1.Pod{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: ts}}},
+		{podMetricFamilies, "kube_pod_start_time", &v1.Pod{}, &v1.Pod{Status: v1.PodStatus{StartTime: &ts}}},
+		{podMetricFamilies, "kube_pod_deletion_timestamp", &v1.Pod{}, &v1.Pod{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &ts}}},
+		{
+			podMetricFamilies, "kube_pod_status_scheduled_time",
+			&v1.Pod{Status: v1.PodStatus{Conditions: []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}}}},
+			&v1.Pod{Status: v1.PodStatus{Conditions: []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue, LastTransitionTime: ts}}}},
+		},
+		{jobMetricFamilies, "kube_job_created", &v1batch.Job{}, &v1batch.Job{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: ts}}},
+		{jobMetricFamilies, "kube_job_status_start_time", &v1batch.Job{}, &v1batch.Job{Status: v1batch.JobStatus{StartTime: &ts}}},
+		{jobMetricFamilies, "kube_job_status_completion_time", &v1batch.Job{}, &v1batch.Job{Status: v1batch.JobStatus{CompletionTime: &ts}}},
+		{cronJobMetricFamilies, "kube_cronjob_created", &batchv1beta1.CronJob{}, &batchv1beta1.CronJob{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: ts}}},
+		{
+			cronJobMetricFamilies, "kube_cronjob_status_last_schedule_time",
+			&batchv1beta1.CronJob{}, &batchv1beta1.CronJob{Status: batchv1beta1.CronJobStatus{LastScheduleTime: &ts}},
+		},
+		{leaseMetricFamilies, "kube_lease_renew_time", &coordinationv1.Lease{}, &coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{RenewTime: &microTS}}},
+		{
+			networkpolicyMetricFamilies, "kube_networkpolicy_created",
+			&networkingv1.NetworkPolicy{}, &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: ts}},
+		},
+	}
+
+	for _, test := range tests {
+		var family *generator.FamilyGenerator
+		for i := range test.families {
+			if test.families[i].Name == test.family {
+				family = &test.families[i]
+			}
+		}
+		if family == nil {
+			t.Fatalf("unknown metric family %s", test.family)
+		}
+
+		if got := family.Generate(test.unset).Metrics; len(got) != 0 {
+			t.Errorf("%s: expected no metric for unset timestamp, got %v", test.family, got)
+		}
+		if got := family.Generate(test.set).Metrics; len(got) != 1 || got[0].Value != 1501569018 {
+			t.Errorf("%s: expected a single metric with the timestamp, got %v", test.family, got)
+		}
+	}
+}
+
+func TestLatestConditions(t *testing.T) {
+	older := metav1.Unix(1500000000, 0)
+	newer := metav1.Unix(1600000000, 0)
+
+	conditions := []condition{
+		{conditionType: "Ready", status: v1.ConditionFalse, lastTransitionTime: older},
+		{conditionType: "Scheduled", status: v1.ConditionTrue, lastTransitionTime: older},
+		{conditionType: "Ready", status: v1.ConditionTrue, lastTransitionTime: newer},
+		{conditionType: "Ready", status: v1.ConditionUnknown, lastTransitionTime: older},
+	}
+	want := []condition{
+		{conditionType: "Ready", status: v1.ConditionTrue, lastTransitionTime: newer},
+		{conditionType: "Scheduled", status: v1.ConditionTrue, lastTransitionTime: older},
+	}
+
+	got := latestConditions(conditions)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected conditions %v, got %v", want, got)
+	}
+}
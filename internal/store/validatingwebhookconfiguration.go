@@ -35,14 +35,12 @@ var (
 	validatingWebhookConfigurationMetricFamilies = []generator.FamilyGenerator{
 		{
 			Name: "kube_validatingwebhookconfiguration_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about the ValidatingWebhookConfiguration.",
 			GenerateFunc: wrapValidatingWebhookConfigurationFunc(func(vwc *admissionregistration.ValidatingWebhookConfiguration) *metric.Family {
 				return &metric.Family{
 					Metrics: []*metric.Metric{
-						{
-							Value: 1,
-						},
+						{},
 					},
 				}
 			}),
@@ -52,15 +50,8 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp.",
 			GenerateFunc: wrapValidatingWebhookConfigurationFunc(func(vwc *admissionregistration.ValidatingWebhookConfiguration) *metric.Family {
-				ms := []*metric.Metric{}
-
-				if !vwc.CreationTimestamp.IsZero() {
-					ms = append(ms, &metric.Metric{
-						Value: float64(vwc.CreationTimestamp.Unix()),
-					})
-				}
 				return &metric.Family{
-					Metrics: ms,
+					Metrics: timestampMetrics(&vwc.CreationTimestamp),
 				}
 			}),
 		},
@@ -77,12 +68,14 @@ var (
 	}
 )
 
-func createValidatingWebhookConfigurationListWatch(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createValidatingWebhookConfigurationListWatch(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().Watch(opts)
 		},
 	}
@@ -94,10 +87,7 @@ func wrapValidatingWebhookConfigurationFunc(f func(*admissionregistration.Valida
 
 		metricFamily := f(mutatingWebhookConfiguration)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descValidatingWebhookConfigurationDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{mutatingWebhookConfiguration.Namespace, mutatingWebhookConfiguration.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descValidatingWebhookConfigurationDefaultLabels, []string{mutatingWebhookConfiguration.Namespace, mutatingWebhookConfiguration.Name})
 
 		return metricFamily
 	}
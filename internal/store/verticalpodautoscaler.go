@@ -26,7 +26,6 @@ import (
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 
-	"k8s.io/kube-state-metrics/pkg/constant"
 	"k8s.io/kube-state-metrics/pkg/metric"
 	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
 )
@@ -217,19 +216,11 @@ func vpaResourcesToMetrics(containerName string, resources v1.ResourceList) []*m
 	ms := []*metric.Metric{}
 	for resourceName, val := range resources {
 		switch resourceName {
-		case v1.ResourceCPU:
+		case v1.ResourceCPU, v1.ResourceStorage, v1.ResourceEphemeralStorage, v1.ResourceMemory:
+			value, unit := resourceValue(resourceName, val)
 			ms = append(ms, &metric.Metric{
-				LabelValues: []string{containerName, sanitizeLabelName(string(resourceName)), string(constant.UnitCore)},
-				Value:       float64(val.MilliValue()) / 1000,
-			})
-		case v1.ResourceStorage:
-			fallthrough
-		case v1.ResourceEphemeralStorage:
-			fallthrough
-		case v1.ResourceMemory:
-			ms = append(ms, &metric.Metric{
-				LabelValues: []string{containerName, sanitizeLabelName(string(resourceName)), string(constant.UnitByte)},
-				Value:       float64(val.Value()),
+				LabelValues: []string{containerName, sanitizeLabelName(string(resourceName)), string(unit)},
+				Value:       value,
 			})
 		}
 	}
@@ -246,22 +237,21 @@ func wrapVPAFunc(f func(*autoscaling.VerticalPodAutoscaler) *metric.Family) func
 		metricFamily := f(vpa)
 		targetRef := vpa.Spec.TargetRef
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descVerticalPodAutoscalerLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{vpa.Namespace, vpa.Name, targetRef.APIVersion, targetRef.Kind, targetRef.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descVerticalPodAutoscalerLabelsDefaultLabels, []string{vpa.Namespace, vpa.Name, targetRef.APIVersion, targetRef.Kind, targetRef.Name})
 
 		return metricFamily
 	}
 }
 
-func createVPAListWatchFunc(vpaClient vpaclientset.Interface) func(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
-	return func(kubeClient clientset.Interface, ns string) cache.ListerWatcher {
+func createVPAListWatchFunc(vpaClient vpaclientset.Interface) func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
+	return func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 		return &cache.ListWatch{
 			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				tweakListOptions(&opts)
 				return vpaClient.AutoscalingV1beta2().VerticalPodAutoscalers(ns).List(opts)
 			},
 			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				tweakListOptions(&opts)
 				return vpaClient.AutoscalingV1beta2().VerticalPodAutoscalers(ns).Watch(opts)
 			},
 		}
@@ -53,7 +53,7 @@ var (
 		},
 		{
 			Name: "kube_volumeattachment_info",
-			Type: metric.Gauge,
+			Type: metric.Info,
 			Help: "Information about volumeattachment.",
 			GenerateFunc: wrapVolumeAttachmentFunc(func(va *storagev1beta1.VolumeAttachment) *metric.Family {
 				return &metric.Family{
@@ -61,7 +61,6 @@ var (
 						{
 							LabelKeys:   []string{"attacher", "node"},
 							LabelValues: []string{va.Spec.Attacher, va.Spec.NodeName},
-							Value:       1,
 						},
 					},
 				}
@@ -72,15 +71,9 @@ var (
 			Type: metric.Gauge,
 			Help: "Unix creation timestamp",
 			GenerateFunc: wrapVolumeAttachmentFunc(func(va *storagev1beta1.VolumeAttachment) *metric.Family {
-				if !va.CreationTimestamp.IsZero() {
-					m := metric.Metric{
-						LabelKeys:   nil,
-						LabelValues: nil,
-						Value:       float64(va.CreationTimestamp.Unix()),
-					}
-					return &metric.Family{Metrics: []*metric.Metric{&m}}
+				return &metric.Family{
+					Metrics: timestampMetrics(&va.CreationTimestamp),
 				}
-				return &metric.Family{Metrics: []*metric.Metric{}}
 			}),
 		},
 		{
@@ -144,21 +137,20 @@ func wrapVolumeAttachmentFunc(f func(*storagev1beta1.VolumeAttachment) *metric.F
 
 		metricFamily := f(va)
 
-		for _, m := range metricFamily.Metrics {
-			m.LabelKeys = append(descVolumeAttachmentLabelsDefaultLabels, m.LabelKeys...)
-			m.LabelValues = append([]string{va.Name}, m.LabelValues...)
-		}
+		addDefaultLabels(metricFamily, descVolumeAttachmentLabelsDefaultLabels, []string{va.Name})
 
 		return metricFamily
 	}
 }
 
-func createVolumeAttachmentListWatch(kubeClient clientset.Interface, _ string) cache.ListerWatcher {
+func createVolumeAttachmentListWatch(kubeClient clientset.Interface, _ string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
 	return &cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&opts)
 			return kubeClient.StorageV1beta1().VolumeAttachments().List(opts)
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&opts)
 			return kubeClient.StorageV1beta1().VolumeAttachments().Watch(opts)
 		},
 	}
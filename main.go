@@ -18,13 +18,19 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"io"
 	"net"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
@@ -32,13 +38,19 @@ import (
 	vpaclientset "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/client/clientset/versioned"
 	clientset "k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/klog"
 
-	"k8s.io/kube-state-metrics/internal/store"
 	"k8s.io/kube-state-metrics/pkg/allowdenylist"
+	"k8s.io/kube-state-metrics/pkg/auth"
+	"k8s.io/kube-state-metrics/pkg/builder"
+	"k8s.io/kube-state-metrics/pkg/leaderelection"
 	"k8s.io/kube-state-metrics/pkg/metricshandler"
 	"k8s.io/kube-state-metrics/pkg/options"
+	"k8s.io/kube-state-metrics/pkg/remotewrite"
+	"k8s.io/kube-state-metrics/pkg/tlsconfig"
 	"k8s.io/kube-state-metrics/pkg/util/proc"
 	"k8s.io/kube-state-metrics/pkg/version"
 )
@@ -46,6 +58,18 @@ import (
 const (
 	metricsPath = "/metrics"
 	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+	pprofPath   = "/debug/pprof/"
+
+	// configCheckInterval is the interval in which the configuration file
+	// is checked for changes.
+	configCheckInterval = 10 * time.Second
+	// certReloadInterval is the interval in which the TLS certificate is
+	// re-read.
+	certReloadInterval = 10 * time.Second
+	// authCacheTTL is the duration allowed authorization decisions are
+	// cached for.
+	authCacheTTL = time.Minute
 )
 
 // promLogger implements promhttp.Logger
@@ -59,8 +83,11 @@ func main() {
 	opts := options.NewOptions()
 	opts.AddFlags()
 
+	// ctx is cancelled once the servers are shut down, stopping the
+	// reflectors and all other background tasks.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	shutdownCtx := shutdownOnSignal()
 
 	err := opts.Parse()
 	if err != nil {
@@ -76,11 +103,148 @@ func main() {
 		opts.Usage()
 		os.Exit(0)
 	}
-	storeBuilder := store.NewBuilder()
+	tlsConfig, err := createTLSConfig(ctx, opts.TLSCertFile, opts.TLSPrivateKeyFile, opts.TLSMinVersion)
+	if err != nil {
+		klog.Fatalf("Failed to set up TLS: %v", err)
+	}
+
+	storeBuilder := builder.NewBuilder()
 
 	ksmMetricsRegistry := prometheus.NewRegistry()
 	storeBuilder.WithMetrics(ksmMetricsRegistry)
+	if err := storeBuilder.WithFamilyGenerationDurationSampling(opts.FamilyGenerationDurationSampling); err != nil {
+		klog.Fatal(err)
+	}
+
+	if err := configureStoreBuilder(storeBuilder, opts); err != nil {
+		klog.Fatal(err)
+	}
+
+	storeBuilder.WithGenerateStoreFunc(storeBuilder.DefaultGenerateStoreFunc())
+
+	proc.StartReaper()
+
+	config, err := createRESTConfig(opts.Apiserver, opts.Kubeconfig, opts.Context, opts.KubeAPIQPS, opts.KubeAPIBurst)
+	if err != nil {
+		klog.Fatalf("Failed to create client config: %v", err)
+	}
+
+	kubeClient, vpaClient, err := createKubeClient(config)
+	if err != nil {
+		klog.Fatalf("Failed to create client: %v", err)
+	}
+	storeBuilder.WithKubeClient(kubeClient)
+	storeBuilder.WithVPAClient(vpaClient)
+	storeBuilder.WithSharding(opts.Shard, opts.TotalShards)
+
+	ksmMetricsRegistry.MustRegister(
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		prometheus.NewGoCollector(),
+		version.NewBuildInfoCollector(),
+	)
+	authFilter := noAuth
+	if opts.EnableAuth {
+		klog.Infof("Authenticating and authorizing requests to %s", metricsPath)
+		authFilter = auth.New(kubeClient, auth.ParseAttributes(opts.AuthVerb, opts.AuthResource), authCacheTTL).WithAuth
+	}
+
+	serverErrs := make(chan error, 2)
+	go func() {
+		serverErrs <- telemetryServer(shutdownCtx, ksmMetricsRegistry, opts.TelemetryHost, opts.TelemetryPort, tlsConfig, opts.ShutdownDrainTimeout, authFilter, opts.EnablePprof)
+	}()
+
+	// background holds tasks that need to finish before exiting.
+	var background sync.WaitGroup
+
+	metricsFilter := authFilter
+	isLeader := func() bool { return true }
+	if opts.EnableLeaderElection {
+		elector, err := createLeaderElector(kubeClient, opts, ksmMetricsRegistry)
+		if err != nil {
+			klog.Fatalf("Failed to set up leader election: %v", err)
+		}
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			elector.Run(ctx)
+		}()
+		metricsFilter = func(h http.Handler) http.Handler {
+			return authFilter(elector.WithLeadership(h))
+		}
+		isLeader = elector.IsLeader
+	}
+
+	m := metricshandler.New(
+		opts,
+		kubeClient,
+		storeBuilder,
+		opts.EnableGZIPEncoding,
+	)
+	m.WithMetrics(ksmMetricsRegistry)
+	go func() {
+		if err := m.Run(ctx); err != nil && ctx.Err() == nil {
+			klog.Fatalf("Failed to run metrics handler: %v", err)
+		}
+	}()
 
+	if opts.RemoteWriteURL != "" {
+		writer, err := createRemoteWriter(opts, m, isLeader, ksmMetricsRegistry)
+		if err != nil {
+			klog.Fatalf("Failed to set up remote-write: %v", err)
+		}
+		klog.Infof("Pushing metrics to %s every %v", opts.RemoteWriteURL, opts.PushInterval)
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			writer.Run(ctx)
+		}()
+	}
+
+	configMetrics := newConfigMetrics(ksmMetricsRegistry)
+	configMetrics.hash.Set(configHashAsMetricValue(opts.ConfigHash()))
+	configMetrics.lastReloadSuccessful.Set(1)
+	configMetrics.lastReloadSuccessTimestamp.SetToCurrentTime()
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	go reloadOnConfigChange(ctx, opts, m, configMetrics, reloadSignals)
+
+	go func() {
+		serverErrs <- serveMetrics(shutdownCtx, m, opts.Host, opts.Port, tlsConfig, opts.ShutdownDrainTimeout, metricsFilter)
+	}()
+
+	for i := 0; i < cap(serverErrs); i++ {
+		if err := <-serverErrs; err != nil {
+			if shutdownCtx.Err() == nil {
+				klog.Fatal(err)
+			}
+			klog.Errorf("Failed to shut down server gracefully: %v", err)
+		}
+	}
+
+	cancel()
+	background.Wait()
+	klog.Info("Shut down")
+}
+
+// shutdownOnSignal returns a context which is done once SIGTERM or SIGINT is
+// received.
+func shutdownOnSignal() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-sigs
+		klog.Infof("Received %s, shutting down", sig)
+		cancel()
+	}()
+
+	return ctx
+}
+
+// configureStoreBuilder applies all options that can be changed at runtime by
+// reloading the configuration file to the given store builder.
+func configureStoreBuilder(storeBuilder *builder.Builder, opts *options.Options) error {
 	var resources []string
 	if len(opts.Resources) == 0 {
 		klog.Info("Using default resources")
@@ -91,7 +255,7 @@ func main() {
 	}
 
 	if err := storeBuilder.WithEnabledResources(resources); err != nil {
-		klog.Fatalf("Failed to set up resources: %v", err)
+		return errors.Wrap(err, "failed to set up resources")
 	}
 
 	if len(opts.Namespaces) == 0 {
@@ -108,49 +272,215 @@ func main() {
 
 	allowDenyList, err := allowdenylist.New(opts.MetricAllowlist, opts.MetricDenylist)
 	if err != nil {
-		klog.Fatal(err)
+		return err
 	}
 
 	err = allowDenyList.Parse()
 	if err != nil {
-		klog.Fatalf("error initializing the allowdeny list : %v", err)
+		return errors.Wrap(err, "error initializing the allowdeny list")
 	}
 
 	klog.Infof("metric allow-denylisting: %v", allowDenyList.Status())
 
 	storeBuilder.WithAllowDenyList(allowDenyList)
 
-	storeBuilder.WithGenerateStoreFunc(storeBuilder.DefaultGenerateStoreFunc())
+	if err := storeBuilder.WithMetricPrefix(opts.MetricPrefix); err != nil {
+		return errors.Wrap(err, "failed to set up metric prefix")
+	}
 
-	proc.StartReaper()
+	storeBuilder.WithEmitDeprecatedMetricNames(opts.EmitDeprecatedMetricNames)
+	storeBuilder.WithUIDLabel(opts.AddUIDLabel)
 
-	kubeClient, vpaClient, err := createKubeClient(opts.Apiserver, opts.Kubeconfig)
-	if err != nil {
-		klog.Fatalf("Failed to create client: %v", err)
+	skipAnnotation := ""
+	if opts.EnableSkipAnnotation {
+		skipAnnotation = opts.SkipAnnotation
+	}
+	if err := storeBuilder.WithSkipAnnotation(skipAnnotation); err != nil {
+		return errors.Wrap(err, "failed to set up skip annotation")
 	}
-	storeBuilder.WithKubeClient(kubeClient)
-	storeBuilder.WithVPAClient(vpaClient)
-	storeBuilder.WithSharding(opts.Shard, opts.TotalShards)
 
-	ksmMetricsRegistry.MustRegister(
-		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
-		prometheus.NewGoCollector(),
-	)
-	go telemetryServer(ksmMetricsRegistry, opts.TelemetryHost, opts.TelemetryPort)
+	if err := storeBuilder.WithMaxLabelValueLength(opts.MaxLabelValueLength); err != nil {
+		return errors.Wrap(err, "failed to set up maximum label value length")
+	}
+
+	if err := storeBuilder.WithCustomLabels(opts.CustomLabels); err != nil {
+		return errors.Wrap(err, "failed to set up custom labels")
+	}
 
-	serveMetrics(ctx, kubeClient, storeBuilder, opts, opts.Host, opts.Port, opts.EnableGZIPEncoding)
+	if err := storeBuilder.WithLabelSelectors(opts.LabelSelector, opts.ResourceLabelSelectors); err != nil {
+		return errors.Wrap(err, "failed to set up label selectors")
+	}
+
+	if err := storeBuilder.WithFieldSelectors(opts.ResourceFieldSelectors); err != nil {
+		return errors.Wrap(err, "failed to set up field selectors")
+	}
+
+	if err := storeBuilder.WithResyncPeriods(opts.ResyncPeriods); err != nil {
+		return errors.Wrap(err, "failed to set up resync periods")
+	}
+
+	storeBuilder.WithUseAPIServerCache(opts.UseAPIServerCache)
+
+	if err := storeBuilder.WithMetadataOnlyResources(opts.MetadataOnlyResources.AsSlice()); err != nil {
+		return errors.Wrap(err, "failed to set up metadata only resources")
+	}
+
+	return nil
 }
 
-func createKubeClient(apiserver string, kubeconfig string) (clientset.Interface, vpaclientset.Interface, error) {
-	config, err := clientcmd.BuildConfigFromFlags(apiserver, kubeconfig)
+// configMetrics stores the pointers of the kube_state_metrics_config_* metrics.
+type configMetrics struct {
+	reloadsTotal               *prometheus.CounterVec
+	hash                       prometheus.Gauge
+	lastReloadSuccessful       prometheus.Gauge
+	lastReloadSuccessTimestamp prometheus.Gauge
+}
+
+func newConfigMetrics(r prometheus.Registerer) *configMetrics {
+	m := &configMetrics{
+		reloadsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "kube_state_metrics_config_reloads_total",
+				Help: "Number of total configuration file reloads in kube-state-metrics",
+			},
+			[]string{"result"},
+		),
+		hash: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "kube_state_metrics_config_hash",
+				Help: "Hash of the currently loaded configuration file.",
+			},
+		),
+		lastReloadSuccessful: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "kube_state_metrics_config_last_reload_successful",
+				Help: "Whether the last configuration reload attempt was successful.",
+			},
+		),
+		lastReloadSuccessTimestamp: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "kube_state_metrics_config_last_reload_success_timestamp_seconds",
+				Help: "Timestamp of the last successful configuration reload.",
+			},
+		),
+	}
+	r.MustRegister(m.reloadsTotal, m.hash, m.lastReloadSuccessful, m.lastReloadSuccessTimestamp)
+	return m
+}
+
+// configHashAsMetricValue converts the first 48 bits of the given hex encoded
+// hash into a float64, which has a 53 bit mantissa.
+func configHashAsMetricValue(hash string) float64 {
+	if len(hash) < 12 {
+		return 0
+	}
+	v, err := strconv.ParseUint(hash[:12], 16, 64)
 	if err != nil {
-		return nil, nil, err
+		return 0
+	}
+	return float64(v)
+}
+
+// reloadOnConfigChange reloads the configuration whenever a signal is received
+// on the given channel or the configuration file changed, which is checked
+// periodically. The stores of the given MetricsHandler affected by the reloaded
+// options are rebuilt. A configuration file that failed to load is not retried
+// until it changes again or a signal is received.
+func reloadOnConfigChange(ctx context.Context, opts *options.Options, m *metricshandler.MetricsHandler, metrics *configMetrics, signals <-chan os.Signal) {
+	var checks <-chan time.Time
+	if opts.Config != "" {
+		ticker := time.NewTicker(configCheckInterval)
+		defer ticker.Stop()
+		checks = ticker.C
+	}
+
+	lastHash := opts.ConfigHash()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			klog.Infof("Received %s, reloading configuration", sig)
+			if opts.Config != "" {
+				if hash, err := options.ConfigFileHash(opts.Config); err == nil {
+					lastHash = hash
+				}
+			}
+		case <-checks:
+			hash, err := options.ConfigFileHash(opts.Config)
+			if err != nil {
+				klog.Errorf("Failed to check config file %s for changes: %v", opts.Config, err)
+				continue
+			}
+			if hash == lastHash {
+				continue
+			}
+			lastHash = hash
+			klog.Infof("Config file %s changed, reloading", opts.Config)
+		}
+
+		newOpts, err := opts.Reload()
+		if err == nil {
+			err = m.Reconfigure(ctx, func(b *builder.Builder) error {
+				return configureStoreBuilder(b, newOpts)
+			})
+		}
+		if err != nil {
+			klog.Errorf("Failed to reload configuration: %v", err)
+			metrics.reloadsTotal.WithLabelValues("error").Inc()
+			metrics.lastReloadSuccessful.Set(0)
+			continue
+		}
+
+		opts = newOpts
+		metrics.reloadsTotal.WithLabelValues("success").Inc()
+		metrics.lastReloadSuccessful.Set(1)
+		metrics.lastReloadSuccessTimestamp.SetToCurrentTime()
+		metrics.hash.Set(configHashAsMetricValue(opts.ConfigHash()))
+		klog.Info("Reloaded configuration")
+	}
+}
+
+// inClusterConfig returns the rest.Config of the service account
+// kube-state-metrics runs as. It is a variable to be replaced in tests.
+var inClusterConfig = rest.InClusterConfig
+
+// createRESTConfig returns the configuration of the clients talking to the
+// apiserver. Unless an apiserver or kubeconfig is given, the in-cluster
+// configuration is used. The context selects the kubeconfig context, defaulting
+// to the current one.
+func createRESTConfig(apiserver, kubeconfig, context string, qps float32, burst int) (*rest.Config, error) {
+	var config *rest.Config
+	if apiserver == "" && kubeconfig == "" {
+		var err error
+		config, err = inClusterConfig()
+		if err != nil {
+			klog.Warningf("Neither --kubeconfig nor --apiserver was specified and the in-cluster configuration failed: %v", err)
+			config = nil
+		}
+	}
+
+	if config == nil {
+		var err error
+		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
+			&clientcmd.ConfigOverrides{ClusterInfo: clientcmdapi.Cluster{Server: apiserver}, CurrentContext: context},
+		).ClientConfig()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	config.UserAgent = version.GetVersion().String()
 	config.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
 	config.ContentType = "application/vnd.kubernetes.protobuf"
+	config.QPS = qps
+	config.Burst = burst
+
+	return config, nil
+}
 
+func createKubeClient(config *rest.Config) (clientset.Interface, vpaclientset.Interface, error) {
 	kubeClient, err := clientset.NewForConfig(config)
 	if err != nil {
 		return nil, nil, err
@@ -175,16 +505,157 @@ func createKubeClient(apiserver string, kubeconfig string) (clientset.Interface,
 	return kubeClient, vpaClient, nil
 }
 
-func telemetryServer(registry prometheus.Gatherer, host string, port int) {
+// createTLSConfig returns the configuration to serve HTTPS with the given
+// certificate and private key, which is reloaded until the given context is
+// done. It returns nil if neither is given.
+func createTLSConfig(ctx context.Context, certFile, keyFile, minVersion string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both --tls-cert-file and --tls-private-key-file have to be given")
+	}
+
+	version, err := tlsconfig.ParseVersion(minVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	reloader, err := tlsconfig.NewCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	go reloader.Run(ctx, certReloadInterval)
+
+	return tlsconfig.New(reloader, version), nil
+}
+
+// createLeaderElector returns an Elector for the Lease configured by the given
+// options, identified by the name of the pod or, if not given, the hostname.
+func createLeaderElector(kubeClient clientset.Interface, opts *options.Options, r prometheus.Registerer) (*leaderelection.Elector, error) {
+	namespace := opts.LeaderElectionNamespace
+	if namespace == "" {
+		namespace = opts.Namespace
+	}
+	if namespace == "" {
+		return nil, errors.New("either --leader-election-namespace or --pod-namespace has to be given")
+	}
+
+	identity := opts.Pod
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, errors.Wrap(err, "get hostname as identity")
+		}
+		identity = hostname
+	}
+
+	return leaderelection.New(kubeClient, leaderelection.Config{
+		LeaseName:      opts.LeaderElectionLeaseName,
+		LeaseNamespace: namespace,
+		Identity:       identity,
+		LeaseDuration:  opts.LeaderElectionLeaseDuration,
+		RenewDeadline:  opts.LeaderElectionRenewDeadline,
+		RetryPeriod:    opts.LeaderElectionRetryPeriod,
+	}, r)
+}
+
+// createRemoteWriter returns a Writer pushing the metrics of the given
+// MetricsHandler to the receiver configured by the given options, as long as
+// isLeader returns true.
+func createRemoteWriter(opts *options.Options, m *metricshandler.MetricsHandler, isLeader func() bool, r prometheus.Registerer) (*remotewrite.Writer, error) {
+	source := remotewrite.SourceFunc(func(w io.Writer) bool {
+		return isLeader() && m.WriteMetrics(w)
+	})
+	return remotewrite.New(remotewrite.Config{
+		URL:                   opts.RemoteWriteURL,
+		Interval:              opts.PushInterval,
+		Username:              opts.RemoteWriteUsername,
+		PasswordFile:          opts.RemoteWritePasswordFile,
+		BearerTokenFile:       opts.RemoteWriteBearerTokenFile,
+		TLSCAFile:             opts.RemoteWriteTLSCAFile,
+		TLSCertFile:           opts.RemoteWriteTLSCertFile,
+		TLSKeyFile:            opts.RemoteWriteTLSKeyFile,
+		TLSInsecureSkipVerify: opts.RemoteWriteTLSInsecureSkipVerify,
+	}, source, r)
+}
+
+// listenAndServe serves the given handler on the given address until the given
+// context is done, via HTTPS if a TLS configuration is given.
+func listenAndServe(ctx context.Context, listenAddress string, handler http.Handler, tlsConfig *tls.Config, drainTimeout time.Duration) error {
+	l, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return err
+	}
+	return serve(ctx, l, handler, tlsConfig, drainTimeout)
+}
+
+// serve serves the given handler on the given listener, via HTTPS if a TLS
+// configuration is given. Once the given context is done, the listener is
+// closed and in-flight requests are given drainTimeout to complete.
+func serve(ctx context.Context, l net.Listener, handler http.Handler, tlsConfig *tls.Config, drainTimeout time.Duration) error {
+	server := &http.Server{
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		if tlsConfig == nil {
+			errs <- server.Serve(l)
+			return
+		}
+		errs <- server.ServeTLS(l, "", "")
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if err := server.Shutdown(drainCtx); err != nil {
+		return errors.Wrapf(err, "drain requests to %s", l.Addr())
+	}
+	return nil
+}
+
+// noAuth passes all requests on to the given handler.
+func noAuth(h http.Handler) http.Handler {
+	return h
+}
+
+func telemetryServer(ctx context.Context, registry prometheus.Gatherer, host string, port int, tlsConfig *tls.Config, drainTimeout time.Duration, authFilter func(http.Handler) http.Handler, enablePprof bool) error {
 	// Address to listen on for web interface and telemetry
 	listenAddress := net.JoinHostPort(host, strconv.Itoa(port))
 
 	klog.Infof("Starting kube-state-metrics self metrics server: %s", listenAddress)
 
+	return listenAndServe(ctx, listenAddress, buildTelemetryServerMux(registry, authFilter, enablePprof), tlsConfig, drainTimeout)
+}
+
+// buildTelemetryServerMux returns the handler of the telemetry server, serving
+// the metrics of kube-state-metrics itself gathered from the given registry.
+// If enablePprof is set, it serves the profiling endpoints of net/http/pprof as
+// well. Requests for both are passed through the given authFilter.
+func buildTelemetryServerMux(registry prometheus.Gatherer, authFilter func(http.Handler) http.Handler, enablePprof bool) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	// Add metricsPath
-	mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorLog: promLogger{}}))
+	mux.Handle(metricsPath, authFilter(promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorLog: promLogger{}})))
+
+	pprofLink := ""
+	if enablePprof {
+		mux.Handle(pprofPath, authFilter(http.HandlerFunc(pprof.Index)))
+		mux.Handle(pprofPath+"cmdline", authFilter(http.HandlerFunc(pprof.Cmdline)))
+		mux.Handle(pprofPath+"profile", authFilter(http.HandlerFunc(pprof.Profile)))
+		mux.Handle(pprofPath+"symbol", authFilter(http.HandlerFunc(pprof.Symbol)))
+		mux.Handle(pprofPath+"trace", authFilter(http.HandlerFunc(pprof.Trace)))
+		pprofLink = `<li><a href='` + pprofPath + `'>pprof</a></li>`
+	}
+
 	// Add index
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
@@ -193,42 +664,52 @@ func telemetryServer(registry prometheus.Gatherer, host string, port int) {
              <h1>Kube-State-Metrics Metrics</h1>
 			 <ul>
              <li><a href='` + metricsPath + `'>metrics</a></li>
+             ` + pprofLink + `
 			 </ul>
              </body>
              </html>`))
 	})
-	log.Fatal(http.ListenAndServe(listenAddress, mux))
+	return mux
 }
 
-func serveMetrics(ctx context.Context, kubeClient clientset.Interface, storeBuilder *store.Builder, opts *options.Options, host string, port int, enableGZIPEncoding bool) {
+func serveMetrics(ctx context.Context, m *metricshandler.MetricsHandler, host string, port int, tlsConfig *tls.Config, drainTimeout time.Duration, metricsFilter func(http.Handler) http.Handler) error {
 	// Address to listen on for web interface and telemetry
 	listenAddress := net.JoinHostPort(host, strconv.Itoa(port))
 
 	klog.Infof("Starting metrics server: %s", listenAddress)
 
-	mux := http.NewServeMux()
+	return listenAndServe(ctx, listenAddress, buildMetricsServerMux(m, metricsFilter), tlsConfig, drainTimeout)
+}
 
-	// TODO: This doesn't belong into serveMetrics
-	mux.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
-	mux.Handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
-	mux.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
-	mux.Handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
-	mux.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
+// buildMetricsServerMux returns the handler of the metrics server, serving the
+// metrics of the Kubernetes objects only. Requests for them are passed through
+// the given metricsFilter.
+func buildMetricsServerMux(m *metricshandler.MetricsHandler, metricsFilter func(http.Handler) http.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
 
-	m := metricshandler.New(
-		opts,
-		kubeClient,
-		storeBuilder,
-		enableGZIPEncoding,
-	)
-	go m.Run(ctx)
-	mux.Handle(metricsPath, m)
+	mux.Handle(metricsPath, metricsFilter(m))
 
 	// Add healthzPath
 	mux.HandleFunc(healthzPath, func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(http.StatusText(http.StatusOK)))
 	})
+	// Add readyzPath
+	mux.HandleFunc(readyzPath, func(w http.ResponseWriter, r *http.Request) {
+		notSynced, built := m.NotSyncedResources()
+		if !built {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("stores not built yet\n"))
+			return
+		}
+		if len(notSynced) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("resources not synced yet: " + strings.Join(notSynced, ",") + "\n"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(http.StatusText(http.StatusOK)))
+	})
 	// Add index
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
@@ -238,9 +719,10 @@ func serveMetrics(ctx context.Context, kubeClient clientset.Interface, storeBuil
 			 <ul>
              <li><a href='` + metricsPath + `'>metrics</a></li>
              <li><a href='` + healthzPath + `'>healthz</a></li>
+             <li><a href='` + readyzPath + `'>readyz</a></li>
 			 </ul>
              </body>
              </html>`))
 	})
-	log.Fatal(http.ListenAndServe(listenAddress, mux))
+	return mux
 }
@@ -19,16 +19,34 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
 	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
-	"k8s.io/kube-state-metrics/internal/store"
 	"k8s.io/kube-state-metrics/pkg/allowdenylist"
+	"k8s.io/kube-state-metrics/pkg/builder"
+	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
 	"k8s.io/kube-state-metrics/pkg/metricshandler"
 	"k8s.io/kube-state-metrics/pkg/options"
 
@@ -36,8 +54,13 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 )
 
 func BenchmarkKubeStateMetrics(b *testing.B) {
@@ -59,24 +82,24 @@ func BenchmarkKubeStateMetrics(b *testing.B) {
 	defer cancel()
 	reg := prometheus.NewRegistry()
 
-	builder := store.NewBuilder()
-	builder.WithMetrics(reg)
-	builder.WithEnabledResources(options.DefaultResources.AsSlice())
-	builder.WithKubeClient(kubeClient)
-	builder.WithSharding(0, 1)
-	builder.WithContext(ctx)
-	builder.WithNamespaces(options.DefaultNamespaces)
-	builder.WithGenerateStoreFunc(builder.DefaultGenerateStoreFunc())
+	storeBuilder := builder.NewBuilder()
+	storeBuilder.WithMetrics(reg)
+	storeBuilder.WithEnabledResources(options.DefaultResources.AsSlice())
+	storeBuilder.WithKubeClient(kubeClient)
+	storeBuilder.WithSharding(0, 1)
+	storeBuilder.WithContext(ctx)
+	storeBuilder.WithNamespaces(options.DefaultNamespaces)
+	storeBuilder.WithGenerateStoreFunc(storeBuilder.DefaultGenerateStoreFunc())
 
 	l, err := allowdenylist.New(map[string]struct{}{}, map[string]struct{}{})
 	if err != nil {
 		b.Fatal(err)
 	}
-	builder.WithAllowDenyList(l)
+	storeBuilder.WithAllowDenyList(l)
 
 	// This test is not suitable to be compared in terms of time, as it includes
 	// a one second wait. Use for memory allocation comparisons, profiling, ...
-	handler := metricshandler.New(&options.Options{}, kubeClient, builder, false)
+	handler := metricshandler.New(&options.Options{}, kubeClient, storeBuilder, false)
 	b.Run("GenerateMetrics", func(b *testing.B) {
 		handler.ConfigureSharding(ctx, 0, 1)
 
@@ -124,20 +147,20 @@ func TestFullScrapeCycle(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	reg := prometheus.NewRegistry()
-	builder := store.NewBuilder()
-	builder.WithMetrics(reg)
-	builder.WithEnabledResources(options.DefaultResources.AsSlice())
-	builder.WithKubeClient(kubeClient)
-	builder.WithNamespaces(options.DefaultNamespaces)
-	builder.WithGenerateStoreFunc(builder.DefaultGenerateStoreFunc())
+	storeBuilder := builder.NewBuilder()
+	storeBuilder.WithMetrics(reg)
+	storeBuilder.WithEnabledResources(options.DefaultResources.AsSlice())
+	storeBuilder.WithKubeClient(kubeClient)
+	storeBuilder.WithNamespaces(options.DefaultNamespaces)
+	storeBuilder.WithGenerateStoreFunc(storeBuilder.DefaultGenerateStoreFunc())
 
 	l, err := allowdenylist.New(map[string]struct{}{}, map[string]struct{}{})
 	if err != nil {
 		t.Fatal(err)
 	}
-	builder.WithAllowDenyList(l)
+	storeBuilder.WithAllowDenyList(l)
 
-	handler := metricshandler.New(&options.Options{}, kubeClient, builder, false)
+	handler := metricshandler.New(&options.Options{}, kubeClient, storeBuilder, false)
 	handler.ConfigureSharding(ctx, 0, 1)
 
 	// Wait for caches to fill
@@ -158,26 +181,26 @@ func TestFullScrapeCycle(t *testing.T) {
 	expected := `# HELP kube_pod_info Information about pod.
 # TYPE kube_pod_info gauge
 kube_pod_info{namespace="default",pod="pod0",host_ip="1.1.1.1",pod_ip="1.2.3.4",uid="abc-0",node="node1",created_by_kind="<none>",created_by_name="<none>",priority_class="",host_network="false"} 1
-# HELP kube_pod_start_time Start time in unix timestamp for a pod.
-# TYPE kube_pod_start_time gauge
-# HELP kube_pod_completion_time Completion time in unix timestamp for a pod.
-# TYPE kube_pod_completion_time gauge
+# HELP kube_pod_start_time_seconds Start time in unix timestamp for a pod.
+# TYPE kube_pod_start_time_seconds gauge
+# HELP kube_pod_completion_time_seconds Completion time in unix timestamp for a pod.
+# TYPE kube_pod_completion_time_seconds gauge
 # HELP kube_pod_owner Information about the Pod's owner.
 # TYPE kube_pod_owner gauge
 kube_pod_owner{namespace="default",pod="pod0",owner_kind="<none>",owner_name="<none>",owner_is_controller="<none>"} 1
 # HELP kube_pod_labels Kubernetes labels converted to Prometheus labels.
 # TYPE kube_pod_labels gauge
 kube_pod_labels{namespace="default",pod="pod0"} 1
-# HELP kube_pod_created Unix creation timestamp
-# TYPE kube_pod_created gauge
-kube_pod_created{namespace="default",pod="pod0"} 1.5e+09
-# HELP kube_pod_deletion_timestamp Unix deletion timestamp
-# TYPE kube_pod_deletion_timestamp gauge
+# HELP kube_pod_created_timestamp_seconds Unix creation timestamp
+# TYPE kube_pod_created_timestamp_seconds gauge
+kube_pod_created_timestamp_seconds{namespace="default",pod="pod0"} 1.5e+09
+# HELP kube_pod_deletion_timestamp_seconds Unix deletion timestamp
+# TYPE kube_pod_deletion_timestamp_seconds gauge
 # HELP kube_pod_restart_policy Describes the restart policy in use by this pod.
 # TYPE kube_pod_restart_policy gauge
 kube_pod_restart_policy{namespace="default",pod="pod0",type="Always"} 1
-# HELP kube_pod_status_scheduled_time Unix timestamp when pod moved into scheduled status
-# TYPE kube_pod_status_scheduled_time gauge
+# HELP kube_pod_status_scheduled_time_seconds Unix timestamp when pod moved into scheduled status
+# TYPE kube_pod_status_scheduled_time_seconds gauge
 # HELP kube_pod_status_phase The pods current phase.
 # TYPE kube_pod_status_phase gauge
 # HELP kube_pod_status_unschedulable Describes the unschedulable status for the pod.
@@ -279,9 +302,11 @@ kube_pod_container_status_ready{namespace="default",pod="pod0",container="contai
 # TYPE kube_pod_container_status_restarts_total counter
 kube_pod_container_status_restarts_total{namespace="default",pod="pod0",container="container2"} 0
 kube_pod_container_status_restarts_total{namespace="default",pod="pod0",container="container3"} 0
+# HELP kube_pod_container_status_last_restart_timestamp Approximate unix timestamp of the last restart of the container, only exposed for restarted containers. It is the start time of the running container or, if it is not running yet, the finish time of its last termination.
+# TYPE kube_pod_container_status_last_restart_timestamp gauge
 # HELP kube_pod_init_container_status_restarts_total The number of restarts for the init container.
 # TYPE kube_pod_init_container_status_restarts_total counter
-# HELP kube_pod_container_resource_requests The number of requested request resource by a container.
+# HELP kube_pod_container_resource_requests The number of requested request resource by a container. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
 # TYPE kube_pod_container_resource_requests gauge
 kube_pod_container_resource_requests{namespace="default",pod="pod0",container="pod1_con1",resource="nvidia_com_gpu",unit="integer"} 1
 kube_pod_container_resource_requests{namespace="default",pod="pod0",container="pod1_con1",resource="cpu",unit="core"} 0.2
@@ -290,11 +315,11 @@ kube_pod_container_resource_requests{namespace="default",pod="pod0",container="p
 kube_pod_container_resource_requests{namespace="default",pod="pod0",container="pod1_con1",resource="storage",unit="byte"} 4e+08
 kube_pod_container_resource_requests{namespace="default",pod="pod0",container="pod1_con2",resource="cpu",unit="core"} 0.3
 kube_pod_container_resource_requests{namespace="default",pod="pod0",container="pod1_con2",resource="memory",unit="byte"} 2e+08
-# HELP kube_pod_init_container_resource_limits The number of requested limit resource by the init container.
+# HELP kube_pod_init_container_resource_limits The number of requested limit resource by the init container. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
 # TYPE kube_pod_init_container_resource_limits gauge
-# HELP kube_pod_init_container_resource_requests The number of requested resources by the init container.
+# HELP kube_pod_init_container_resource_requests The number of requested resources by the init container. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
 # TYPE kube_pod_init_container_resource_requests gauge
-# HELP kube_pod_container_resource_limits The number of requested limit resource by a container.
+# HELP kube_pod_container_resource_limits The number of requested limit resource by a container. The unit label is core for cpu; byte for memory, storage, ephemeral-storage, hugepages-* and attachable-volumes-*; integer for any other resource, e.g. pods or nvidia.com/gpu.
 # TYPE kube_pod_container_resource_limits gauge
 kube_pod_container_resource_limits{namespace="default",pod="pod0",container="pod1_con1",resource="nvidia_com_gpu",unit="integer"} 1
 kube_pod_container_resource_limits{namespace="default",pod="pod0",container="pod1_con1",resource="cpu",unit="core"} 0.2
@@ -337,6 +362,524 @@ kube_pod_container_resource_limits{namespace="default",pod="pod0",container="pod
 
 // TestShardingEquivalenceScrapeCycle is a simple smoke test covering the entire cycle from
 // cache filling to scraping comparing a sharded with an unsharded setup.
+// TestServerSplit ensures the metrics server serves the metrics of the
+// Kubernetes objects only, while the telemetry server serves the metrics of
+// kube-state-metrics itself.
+func TestServerSplit(t *testing.T) {
+	t.Parallel()
+
+	kubeClient := fake.NewSimpleClientset()
+
+	err := pod(kubeClient, 0)
+	if err != nil {
+		t.Fatalf("failed to insert sample pod %v", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		prometheus.NewGoCollector(),
+	)
+	storeBuilder := builder.NewBuilder()
+	storeBuilder.WithMetrics(reg)
+	storeBuilder.WithEnabledResources([]string{"pods"})
+	storeBuilder.WithKubeClient(kubeClient)
+	storeBuilder.WithNamespaces(options.DefaultNamespaces)
+	storeBuilder.WithGenerateStoreFunc(storeBuilder.DefaultGenerateStoreFunc())
+
+	l, err := allowdenylist.New(map[string]struct{}{}, map[string]struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeBuilder.WithAllowDenyList(l)
+
+	handler := metricshandler.New(&options.Options{}, kubeClient, storeBuilder, false)
+	handler.ConfigureSharding(ctx, 0, 1)
+
+	// Wait for caches to fill
+	time.Sleep(time.Second)
+
+	metricsServer := httptest.NewServer(buildMetricsServerMux(handler, noAuth))
+	defer metricsServer.Close()
+	telemetryServer := httptest.NewServer(buildTelemetryServerMux(reg, noAuth, false))
+	defer telemetryServer.Close()
+
+	get := func(url string) string {
+		resp, err := http.Get(url + metricsPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200 status code but got %v", resp.StatusCode)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	metrics := get(metricsServer.URL)
+	if !strings.Contains(metrics, "kube_pod_info{") {
+		t.Errorf("expected metrics server to serve kube_pod_info, got:\n%s", metrics)
+	}
+	for _, prefix := range []string{"go_", "process_", "kube_state_metrics_"} {
+		if strings.Contains(metrics, "\n"+prefix) || strings.HasPrefix(metrics, prefix) {
+			t.Errorf("expected metrics server not to serve %s* metrics, got:\n%s", prefix, metrics)
+		}
+	}
+
+	telemetry := get(telemetryServer.URL)
+	for _, name := range []string{"go_goroutines", "kube_state_metrics_list_total"} {
+		if !strings.Contains(telemetry, "\n"+name) {
+			t.Errorf("expected telemetry server to serve %s, got:\n%s", name, telemetry)
+		}
+	}
+	if strings.Contains(telemetry, "kube_pod_info") {
+		t.Errorf("expected telemetry server not to serve kube_pod_info, got:\n%s", telemetry)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	t.Parallel()
+
+	kubeClient := fake.NewSimpleClientset()
+	if err := pod(kubeClient, 0); err != nil {
+		t.Fatalf("failed to insert sample pod %v", err.Error())
+	}
+
+	// Lists fail while failLists is set, keeping the stores from syncing.
+	var failLists int32 = 1
+	kubeClient.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if atomic.LoadInt32(&failLists) == 1 {
+			return true, nil, errors.New("list failed")
+		}
+		return false, nil, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	storeBuilder := builder.NewBuilder()
+	storeBuilder.WithMetrics(prometheus.NewRegistry())
+	storeBuilder.WithEnabledResources([]string{"pods"})
+	storeBuilder.WithKubeClient(kubeClient)
+	storeBuilder.WithNamespaces(options.DefaultNamespaces)
+	storeBuilder.WithGenerateStoreFunc(storeBuilder.DefaultGenerateStoreFunc())
+
+	l, err := allowdenylist.New(map[string]struct{}{}, map[string]struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeBuilder.WithAllowDenyList(l)
+
+	handler := metricshandler.New(&options.Options{}, kubeClient, storeBuilder, false)
+	mux := buildMetricsServerMux(handler, noAuth)
+
+	readyz := func() (int, string) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:8080"+readyzPath, nil))
+		return w.Code, w.Body.String()
+	}
+	waitFor := func(code int) string {
+		var body string
+		for i := 0; i < 50; i++ {
+			var got int
+			got, body = readyz()
+			if got == code {
+				return body
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("expected %s to return %d, last response: %s", readyzPath, code, body)
+		return ""
+	}
+
+	if code, _ := readyz(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected %s to return 503 before the stores are built, got %d", readyzPath, code)
+	}
+
+	handler.ConfigureSharding(ctx, 0, 1)
+	if code, body := readyz(); code != http.StatusServiceUnavailable || !strings.Contains(body, "pods") {
+		t.Errorf("expected %s to return 503 listing pods, got %d: %s", readyzPath, code, body)
+	}
+
+	atomic.StoreInt32(&failLists, 0)
+	waitFor(http.StatusOK)
+
+	// Rebuilt stores make the handler unready until they synced.
+	atomic.StoreInt32(&failLists, 1)
+	go handler.Reconfigure(ctx, func(b *builder.Builder) error {
+		b.WithNamespaces(options.NamespaceList{"default"})
+		return nil
+	})
+	if body := waitFor(http.StatusServiceUnavailable); !strings.Contains(body, "pods") {
+		t.Errorf("expected %s to list pods as not synced, got: %s", readyzPath, body)
+	}
+
+	atomic.StoreInt32(&failLists, 0)
+	waitFor(http.StatusOK)
+}
+
+// TestPprof ensures the profiling endpoints are served on the telemetry port
+// only if enabled, behind the configured authFilter, and never on the metrics
+// port.
+func TestPprof(t *testing.T) {
+	t.Parallel()
+
+	deny := func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+	handler := metricshandler.New(&options.Options{}, fake.NewSimpleClientset(), builder.NewBuilder(), false)
+	reg := prometheus.NewRegistry()
+
+	tests := []struct {
+		Desc         string
+		Mux          http.Handler
+		WantedStatus int
+		WantedPprof  bool
+	}{
+		{
+			Desc:         "telemetry server by default",
+			Mux:          buildTelemetryServerMux(reg, noAuth, false),
+			WantedStatus: http.StatusOK,
+			WantedPprof:  false,
+		},
+		{
+			Desc:         "telemetry server with pprof enabled",
+			Mux:          buildTelemetryServerMux(reg, noAuth, true),
+			WantedStatus: http.StatusOK,
+			WantedPprof:  true,
+		},
+		{
+			Desc:         "telemetry server with pprof enabled and requests denied",
+			Mux:          buildTelemetryServerMux(reg, deny, true),
+			WantedStatus: http.StatusForbidden,
+			WantedPprof:  false,
+		},
+		{
+			Desc:         "metrics server",
+			Mux:          buildMetricsServerMux(handler, noAuth),
+			WantedStatus: http.StatusOK,
+			WantedPprof:  false,
+		},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		test.Mux.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:8081"+pprofPath, nil))
+		gotPprof := strings.Contains(w.Body.String(), "Types of profiles available")
+		if w.Code != test.WantedStatus || gotPprof != test.WantedPprof {
+			t.Errorf("Test error for Desc: %s. Want: status %d, pprof %v. Got: status %d, pprof %v.", test.Desc, test.WantedStatus, test.WantedPprof, w.Code, gotPprof)
+		}
+	}
+}
+
+// TestReconfigureKeepsUnaffectedStores ensures reconfiguration only rebuilds
+// the stores of the resources affected by the new configuration.
+func TestReconfigureKeepsUnaffectedStores(t *testing.T) {
+	t.Parallel()
+
+	kubeClient := fake.NewSimpleClientset()
+	if err := pod(kubeClient, 0); err != nil {
+		t.Fatalf("failed to insert sample pod %v", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	storeBuilder := builder.NewBuilder()
+	storeBuilder.WithMetrics(prometheus.NewRegistry())
+	storeBuilder.WithEnabledResources([]string{"nodes", "pods"})
+	storeBuilder.WithKubeClient(kubeClient)
+	storeBuilder.WithNamespaces(options.DefaultNamespaces)
+	storeBuilder.WithGenerateStoreFunc(storeBuilder.DefaultGenerateStoreFunc())
+
+	l, err := allowdenylist.New(map[string]struct{}{}, map[string]struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeBuilder.WithAllowDenyList(l)
+
+	handler := metricshandler.New(&options.Options{}, kubeClient, storeBuilder, false)
+	handler.ConfigureSharding(ctx, 0, 1)
+
+	lists := func() map[string]int {
+		n := map[string]int{}
+		for _, a := range kubeClient.Actions() {
+			if a.GetVerb() == "list" {
+				n[a.GetResource().Resource]++
+			}
+		}
+		return n
+	}
+	waitForLists := func(want map[string]int) {
+		var got map[string]int
+		for i := 0; i < 50; i++ {
+			if got = lists(); reflect.DeepEqual(got, want) {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("expected lists %v, got %v", want, got)
+	}
+	waitForLists(map[string]int{"nodes": 1, "pods": 1})
+
+	tests := []struct {
+		Desc        string
+		Configure   func(*builder.Builder) error
+		WantedLists map[string]int
+	}{
+		{
+			Desc:        "unchanged configuration",
+			Configure:   func(*builder.Builder) error { return nil },
+			WantedLists: map[string]int{"nodes": 1, "pods": 1},
+		},
+		{
+			Desc: "namespaces affecting pods only",
+			Configure: func(b *builder.Builder) error {
+				b.WithNamespaces(options.NamespaceList{"default"})
+				return nil
+			},
+			WantedLists: map[string]int{"nodes": 1, "pods": 2},
+		},
+		{
+			Desc: "allowlist affecting nodes only",
+			Configure: func(b *builder.Builder) error {
+				l, err := allowdenylist.New(map[string]struct{}{"kube_node_info": {}, "kube_pod_.*": {}}, map[string]struct{}{})
+				if err != nil {
+					return err
+				}
+				if err := l.Parse(); err != nil {
+					return err
+				}
+				b.WithAllowDenyList(l)
+				return nil
+			},
+			WantedLists: map[string]int{"nodes": 2, "pods": 2},
+		},
+	}
+
+	for _, test := range tests {
+		if err := handler.Reconfigure(ctx, test.Configure); err != nil {
+			t.Fatalf("Test error for Desc: %s. Got Error: %v", test.Desc, err)
+		}
+		if got := lists(); !reflect.DeepEqual(got, test.WantedLists) {
+			t.Errorf("Test error for Desc: %s. Want lists: %v. Got: %v", test.Desc, test.WantedLists, got)
+		}
+	}
+}
+
+// neverSyncedStore is a store the initial sync of which never completes.
+type neverSyncedStore struct {
+	cache.Store
+}
+
+func (s *neverSyncedStore) HasSynced() bool {
+	return false
+}
+
+func (s *neverSyncedStore) WriteAll(w io.Writer) {}
+
+// TestReloadSyncTimeout ensures reloads the stores of which do not sync in time
+// fail, keep the previous configuration and do not block subsequent reloads.
+func TestReloadSyncTimeout(t *testing.T) {
+	t.Parallel()
+
+	kubeClient := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	storeBuilder := builder.NewBuilder()
+	storeBuilder.WithMetrics(prometheus.NewRegistry())
+	storeBuilder.WithEnabledResources([]string{"pods"})
+	storeBuilder.WithKubeClient(kubeClient)
+	storeBuilder.WithNamespaces(options.DefaultNamespaces)
+
+	var neverSync int32
+	defaultGenerateStoreFunc := storeBuilder.DefaultGenerateStoreFunc()
+	storeBuilder.WithGenerateStoreFunc(func(
+		resource string,
+		metricFamilies []generator.FamilyGenerator,
+		expectedType interface{},
+		listWatchFunc func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher,
+	) cache.Store {
+		if atomic.LoadInt32(&neverSync) == 1 {
+			return &neverSyncedStore{Store: cache.NewStore(cache.MetaNamespaceKeyFunc)}
+		}
+		return defaultGenerateStoreFunc(resource, metricFamilies, expectedType, listWatchFunc)
+	})
+
+	l, err := allowdenylist.New(map[string]struct{}{}, map[string]struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeBuilder.WithAllowDenyList(l)
+
+	handler := metricshandler.New(&options.Options{ReloadSyncTimeout: 100 * time.Millisecond}, kubeClient, storeBuilder, false)
+	if err := handler.ConfigureSharding(ctx, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&neverSync, 1)
+
+	reg := prometheus.NewRegistry()
+	metrics := newConfigMetrics(reg)
+	signals := make(chan os.Signal)
+	go reloadOnConfigChange(ctx, &options.Options{}, handler, metrics, signals)
+
+	// The reloaded default options enable further resources, the stores of
+	// which never sync. The second signal is only received once the first
+	// reload gave up.
+	for i := 0; i < 2; i++ {
+		select {
+		case signals <- syscall.SIGHUP:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("reload %d blocked by the previous one", i+1)
+		}
+	}
+
+	gauges := func() map[string]float64 {
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := map[string]float64{}
+		for _, f := range families {
+			for _, m := range f.GetMetric() {
+				name := f.GetName()
+				for _, l := range m.GetLabel() {
+					name += "/" + l.GetName() + "=" + l.GetValue()
+				}
+				if m.GetCounter() != nil {
+					values[name] = m.GetCounter().GetValue()
+				} else {
+					values[name] = m.GetGauge().GetValue()
+				}
+			}
+		}
+		return values
+	}
+	var got map[string]float64
+	for i := 0; i < 50; i++ {
+		if got = gauges(); got["kube_state_metrics_config_reloads_total/result=error"] == 2 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	want := map[string]float64{
+		"kube_state_metrics_config_reloads_total/result=error":            2,
+		"kube_state_metrics_config_last_reload_successful":                0,
+		"kube_state_metrics_config_last_reload_success_timestamp_seconds": 0,
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("Want %s %v. Got: %v", name, value, got[name])
+		}
+	}
+
+	// The previous configuration was restored, so that reconfiguring without
+	// changes rebuilds no stores, which would never sync.
+	if err := handler.Reconfigure(ctx, func(*builder.Builder) error { return nil }); err != nil {
+		t.Errorf("expected the previous configuration to be restored, got %v", err)
+	}
+	if notSynced, ok := handler.NotSyncedResources(); !ok || len(notSynced) != 0 {
+		t.Errorf("expected the previous stores to be kept, got not synced resources %v", notSynced)
+	}
+}
+
+// slowStore is a store taking a while to write its metrics, simulating a slow
+// scrape.
+type slowStore struct {
+	cache.Store
+	started chan struct{}
+	delay   time.Duration
+}
+
+func (s *slowStore) WriteAll(w io.Writer) {
+	close(s.started)
+	time.Sleep(s.delay)
+	w.Write([]byte("kube_slow_metric 1\n"))
+}
+
+// TestGracefulShutdown ensures in-flight requests complete while the metrics
+// server shuts down, and new requests are refused.
+func TestGracefulShutdown(t *testing.T) {
+	t.Parallel()
+
+	kubeClient := fake.NewSimpleClientset()
+	slow := &slowStore{
+		Store:   cache.NewStore(cache.MetaNamespaceKeyFunc),
+		started: make(chan struct{}),
+		delay:   500 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	storeBuilder := builder.NewBuilder()
+	storeBuilder.WithMetrics(prometheus.NewRegistry())
+	storeBuilder.WithEnabledResources([]string{"pods"})
+	storeBuilder.WithKubeClient(kubeClient)
+	storeBuilder.WithNamespaces(options.DefaultNamespaces)
+	storeBuilder.WithGenerateStoreFunc(func(string, []generator.FamilyGenerator, interface{}, func(clientset.Interface, string, func(*metav1.ListOptions)) cache.ListerWatcher) cache.Store {
+		return slow
+	})
+
+	l, err := allowdenylist.New(map[string]struct{}{}, map[string]struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeBuilder.WithAllowDenyList(l)
+
+	handler := metricshandler.New(&options.Options{}, kubeClient, storeBuilder, false)
+	handler.ConfigureSharding(ctx, 0, 1)
+
+	server := httptest.NewUnstartedServer(nil)
+	url := "http://" + server.Listener.Addr().String()
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve(shutdownCtx, server.Listener, buildMetricsServerMux(handler, noAuth), nil, 5*time.Second)
+	}()
+
+	type result struct {
+		code int
+		body string
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url + metricsPath)
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		results <- result{code: resp.StatusCode, body: string(body), err: err}
+	}()
+
+	<-slow.started
+	shutdown()
+
+	if err := <-serveErr; err != nil {
+		t.Errorf("expected the server to shut down gracefully, got: %v", err)
+	}
+
+	res := <-results
+	if res.err != nil {
+		t.Fatalf("expected the in-flight request to complete, got: %v", res.err)
+	}
+	if res.code != http.StatusOK || !strings.Contains(res.body, "kube_slow_metric 1") {
+		t.Errorf("expected the in-flight request to return the metrics, got %d: %s", res.code, res.body)
+	}
+
+	if _, err := http.Get(url + healthzPath); err == nil {
+		t.Error("expected requests after the shutdown to be refused")
+	}
+}
+
 func TestShardingEquivalenceScrapeCycle(t *testing.T) {
 	t.Parallel()
 
@@ -357,7 +900,7 @@ func TestShardingEquivalenceScrapeCycle(t *testing.T) {
 	}
 
 	reg := prometheus.NewRegistry()
-	unshardedBuilder := store.NewBuilder()
+	unshardedBuilder := builder.NewBuilder()
 	unshardedBuilder.WithMetrics(reg)
 	unshardedBuilder.WithEnabledResources(options.DefaultResources.AsSlice())
 	unshardedBuilder.WithKubeClient(kubeClient)
@@ -369,7 +912,7 @@ func TestShardingEquivalenceScrapeCycle(t *testing.T) {
 	unshardedHandler.ConfigureSharding(ctx, 0, 1)
 
 	regShard1 := prometheus.NewRegistry()
-	shardedBuilder1 := store.NewBuilder()
+	shardedBuilder1 := builder.NewBuilder()
 	shardedBuilder1.WithMetrics(regShard1)
 	shardedBuilder1.WithEnabledResources(options.DefaultResources.AsSlice())
 	shardedBuilder1.WithKubeClient(kubeClient)
@@ -381,7 +924,7 @@ func TestShardingEquivalenceScrapeCycle(t *testing.T) {
 	shardedHandler1.ConfigureSharding(ctx, 0, 2)
 
 	regShard2 := prometheus.NewRegistry()
-	shardedBuilder2 := store.NewBuilder()
+	shardedBuilder2 := builder.NewBuilder()
 	shardedBuilder2.WithMetrics(regShard2)
 	shardedBuilder2.WithEnabledResources(options.DefaultResources.AsSlice())
 	shardedBuilder2.WithKubeClient(kubeClient)
@@ -625,3 +1168,274 @@ func pod(client *fake.Clientset, index int) error {
 	_, err := client.CoreV1().Pods(metav1.NamespaceDefault).Create(&pod)
 	return err
 }
+
+func TestCreateRESTConfig(t *testing.T) {
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+- name: staging
+  cluster:
+    server: https://staging.example.com
+contexts:
+- name: prod
+  context:
+    cluster: prod
+- name: staging
+  context:
+    cluster: staging
+current-context: prod
+`
+	dir, err := ioutil.TempDir("", "ksm-kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	kubeconfigPath := filepath.Join(dir, "kubeconfig")
+	if err := ioutil.WriteFile(kubeconfigPath, []byte(kubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(f func() (*rest.Config, error)) { inClusterConfig = f }(inClusterConfig)
+	inClusterConfig = func() (*rest.Config, error) {
+		return &rest.Config{Host: "https://10.0.0.1:443"}, nil
+	}
+
+	tests := []struct {
+		Desc       string
+		Apiserver  string
+		Kubeconfig string
+		Context    string
+		WantedHost string
+	}{
+		{
+			Desc:       "in-cluster",
+			WantedHost: "https://10.0.0.1:443",
+		},
+		{
+			Desc:       "kubeconfig current context",
+			Kubeconfig: kubeconfigPath,
+			WantedHost: "https://prod.example.com",
+		},
+		{
+			Desc:       "kubeconfig explicit context",
+			Kubeconfig: kubeconfigPath,
+			Context:    "staging",
+			WantedHost: "https://staging.example.com",
+		},
+		{
+			Desc:       "apiserver overrides kubeconfig",
+			Apiserver:  "https://other.example.com",
+			Kubeconfig: kubeconfigPath,
+			WantedHost: "https://other.example.com",
+		},
+	}
+
+	for _, test := range tests {
+		config, err := createRESTConfig(test.Apiserver, test.Kubeconfig, test.Context, 42, 84)
+		if err != nil {
+			t.Errorf("Test error for Desc: %s. Unexpected error: %v", test.Desc, err)
+			continue
+		}
+		if config.Host != test.WantedHost {
+			t.Errorf("Test error for Desc: %s. Want host %s, got %s", test.Desc, test.WantedHost, config.Host)
+		}
+		if config.QPS != 42 || config.Burst != 84 {
+			t.Errorf("Test error for Desc: %s. Want QPS 42 and burst 84, got %v and %v", test.Desc, config.QPS, config.Burst)
+		}
+	}
+
+	if _, err := createRESTConfig("", kubeconfigPath, "unknown", 42, 84); err == nil {
+		t.Error("expected an error for an unknown context")
+	}
+}
+
+func TestHTTPSScrape(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ksm-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kube-state-metrics"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := createTLSConfig(ctx, certFile, "", "VersionTLS12"); err == nil {
+		t.Error("expected an error if only a certificate is given")
+	}
+	if _, err := createTLSConfig(ctx, "", keyFile, "VersionTLS12"); err == nil {
+		t.Error("expected an error if only a private key is given")
+	}
+
+	tlsConfig, err := createTLSConfig(ctx, certFile, keyFile, "VersionTLS13")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGoCollector())
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go serve(ctx, l, buildTelemetryServerMux(reg, noAuth, false), tlsConfig, time.Second)
+	defer l.Close()
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	resp, err := client.Get("https://" + l.Addr().String() + metricsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "go_goroutines") {
+		t.Errorf("expected successful HTTPS scrape, got status %d and body:\n%s", resp.StatusCode, body)
+	}
+
+	oldClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MaxVersion: tls.VersionTLS12}}}
+	if _, err := oldClient.Get("https://" + l.Addr().String() + metricsPath); err == nil {
+		t.Error("expected connections below the minimum TLS version to fail")
+	}
+}
+
+func TestShardMetrics(t *testing.T) {
+	t.Parallel()
+
+	kubeClient := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l, err := allowdenylist.New(map[string]struct{}{}, map[string]struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newShard := func(shard int32, totalShards int, resources []string) (*metricshandler.MetricsHandler, *prometheus.Registry) {
+		reg := prometheus.NewRegistry()
+		storeBuilder := builder.NewBuilder()
+		storeBuilder.WithMetrics(reg)
+		storeBuilder.WithEnabledResources(resources)
+		storeBuilder.WithKubeClient(kubeClient)
+		storeBuilder.WithNamespaces(options.DefaultNamespaces)
+		storeBuilder.WithAllowDenyList(l)
+		storeBuilder.WithGenerateStoreFunc(storeBuilder.DefaultGenerateStoreFunc())
+
+		handler := metricshandler.New(&options.Options{}, kubeClient, storeBuilder, false)
+		handler.WithMetrics(reg)
+		handler.ConfigureSharding(ctx, shard, totalShards)
+		return handler, reg
+	}
+
+	// gauges returns the values of the gauges of the given registry by
+	// metric name and labels.
+	gauges := func(reg *prometheus.Registry) map[string]float64 {
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := map[string]float64{}
+		for _, f := range families {
+			for _, m := range f.GetMetric() {
+				name := f.GetName()
+				for _, l := range m.GetLabel() {
+					name += "/" + l.GetName() + "=" + l.GetValue()
+				}
+				values[name] = m.GetGauge().GetValue()
+			}
+		}
+		return values
+	}
+
+	_, reg0 := newShard(0, 2, []string{"pods"})
+	handler1, reg1 := newShard(1, 2, []string{"pods"})
+	_, regOther := newShard(1, 2, []string{"pods", "nodes"})
+
+	shard0, shard1, other := gauges(reg0), gauges(reg1), gauges(regOther)
+	for _, want := range []struct {
+		values map[string]float64
+		name   string
+		value  float64
+	}{
+		{shard0, "kube_state_metrics_shard_ordinal/shard_ordinal=0", 0},
+		{shard0, "kube_state_metrics_total_shards", 2},
+		{shard1, "kube_state_metrics_shard_ordinal/shard_ordinal=1", 1},
+		{shard1, "kube_state_metrics_total_shards", 2},
+	} {
+		if got, ok := want.values[want.name]; !ok || got != want.value {
+			t.Errorf("expected %s to be %v, got %v", want.name, want.value, got)
+		}
+	}
+
+	hash := "kube_state_metrics_shard_config_hash"
+	if shard0[hash] == 0 || shard0[hash] != shard1[hash] {
+		t.Errorf("expected equal config hashes of consistently configured shards, got %v and %v", shard0[hash], shard1[hash])
+	}
+	if shard1[hash] == other[hash] {
+		t.Errorf("expected config hashes of differently configured shards to differ, got %v", other[hash])
+	}
+
+	// discoveries returns the number of discoveries of the served resources.
+	discoveries := func() int {
+		n := 0
+		for _, a := range kubeClient.Actions() {
+			if a.GetVerb() == "get" && a.GetResource().Resource == "resource" {
+				n++
+			}
+		}
+		return n
+	}
+
+	// Re-sharding replaces the ordinal, discovering the served resources only
+	// once to build the stores and hash their configuration.
+	before := discoveries()
+	handler1.ConfigureSharding(ctx, 2, 3)
+	if n := discoveries() - before; n != 1 {
+		t.Errorf("expected the served resources to be discovered once on re-sharding, got %d discoveries", n)
+	}
+	shard1 = gauges(reg1)
+	if _, ok := shard1["kube_state_metrics_shard_ordinal/shard_ordinal=1"]; ok {
+		t.Error("expected previous shard ordinal to be removed after re-sharding")
+	}
+	if shard1["kube_state_metrics_shard_ordinal/shard_ordinal=2"] != 2 || shard1["kube_state_metrics_total_shards"] != 3 {
+		t.Errorf("expected shard 2 of 3 after re-sharding, got %v", shard1)
+	}
+	if shard1[hash] == shard0[hash] {
+		t.Error("expected config hash to change with the number of shards")
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+// Attributes are the attributes of the SubjectAccessReviews authorizing
+// requests. If Resource is empty, the path of the request is reviewed as a
+// non-resource URL.
+type Attributes struct {
+	Verb     string
+	Group    string
+	Resource string
+}
+
+// ParseAttributes returns the Attributes for the given verb and resource in
+// the form <resource>[.<group>], e.g. services or metrics.example.com.
+func ParseAttributes(verb, resource string) Attributes {
+	a := Attributes{Verb: verb}
+	parts := strings.SplitN(resource, ".", 2)
+	a.Resource = parts[0]
+	if len(parts) == 2 {
+		a.Group = parts[1]
+	}
+	return a
+}
+
+// Filter authenticates HTTP requests by their bearer token with TokenReviews
+// and authorizes them with SubjectAccessReviews. Allowed decisions are cached
+// for a limited time.
+type Filter struct {
+	kubeClient clientset.Interface
+	attributes Attributes
+	ttl        time.Duration
+
+	// mtx protects allowed
+	mtx sync.Mutex
+	// allowed maps the hashes of tokens and request paths to the time their
+	// allowed decision expires.
+	allowed map[[sha256.Size]byte]time.Time
+
+	now func() time.Time
+}
+
+// New returns a new Filter reviewing requests with the given client and
+// caching allowed decisions for the given duration.
+func New(kubeClient clientset.Interface, attributes Attributes, ttl time.Duration) *Filter {
+	return &Filter{
+		kubeClient: kubeClient,
+		attributes: attributes,
+		ttl:        ttl,
+		allowed:    map[[sha256.Size]byte]time.Time{},
+		now:        time.Now,
+	}
+}
+
+// WithAuth returns a handler passing authenticated and authorized requests on
+// to the given handler. Other requests are answered with 401 Unauthorized and
+// 403 Forbidden respectively.
+func (f *Filter) WithAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := f.review(r)
+		if status != http.StatusOK {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// review returns http.StatusOK if the given request is authenticated and
+// authorized, http.StatusUnauthorized or http.StatusForbidden otherwise.
+func (f *Filter) review(r *http.Request) int {
+	token := bearerToken(r)
+	if token == "" {
+		return http.StatusUnauthorized
+	}
+
+	key := sha256.Sum256([]byte(token + "\x00" + r.URL.Path))
+	now := f.now()
+
+	f.mtx.Lock()
+	expiry, ok := f.allowed[key]
+	f.mtx.Unlock()
+	if ok && now.Before(expiry) {
+		return http.StatusOK
+	}
+
+	tr, err := f.kubeClient.AuthenticationV1().TokenReviews().Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	})
+	if err != nil {
+		klog.Errorf("Failed to review token: %v", err)
+		return http.StatusUnauthorized
+	}
+	if !tr.Status.Authenticated {
+		return http.StatusUnauthorized
+	}
+
+	sar, err := f.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(f.subjectAccessReview(tr.Status.User, r.URL.Path))
+	if err != nil {
+		klog.Errorf("Failed to review access of user %s: %v", tr.Status.User.Username, err)
+		return http.StatusForbidden
+	}
+	if !sar.Status.Allowed {
+		return http.StatusForbidden
+	}
+
+	f.mtx.Lock()
+	for k, e := range f.allowed {
+		if !now.Before(e) {
+			delete(f.allowed, k)
+		}
+	}
+	f.allowed[key] = now.Add(f.ttl)
+	f.mtx.Unlock()
+
+	return http.StatusOK
+}
+
+// subjectAccessReview returns the SubjectAccessReview for the given user
+// requesting the given path.
+func (f *Filter) subjectAccessReview(user authenticationv1.UserInfo, path string) *authorizationv1.SubjectAccessReview {
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+		},
+	}
+
+	if len(user.Extra) > 0 {
+		sar.Spec.Extra = make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+		for k, v := range user.Extra {
+			sar.Spec.Extra[k] = authorizationv1.ExtraValue(v)
+		}
+	}
+
+	if f.attributes.Resource == "" {
+		sar.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{
+			Path: path,
+			Verb: f.attributes.Verb,
+		}
+	} else {
+		sar.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+			Verb:     f.attributes.Verb,
+			Group:    f.attributes.Group,
+			Resource: f.attributes.Resource,
+		}
+	}
+
+	return sar
+}
+
+// bearerToken returns the bearer token of the Authorization header of the
+// given request.
+func bearerToken(r *http.Request) string {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newFakeClient returns a fake client authenticating the token "alice-token"
+// as the user alice and "bob-token" as bob, and authorizing alice only.
+func newFakeClient() *fake.Clientset {
+	kubeClient := fake.NewSimpleClientset()
+
+	kubeClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		switch tr.Spec.Token {
+		case "alice-token":
+			tr.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "alice"}}
+		case "bob-token":
+			tr.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "bob"}}
+		}
+		return true, tr, nil
+	})
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		sar := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		sar.Status.Allowed = sar.Spec.User == "alice"
+		return true, sar, nil
+	})
+
+	return kubeClient
+}
+
+func TestWithAuth(t *testing.T) {
+	tests := []struct {
+		Desc          string
+		Authorization string
+		Wanted        int
+	}{
+		{
+			Desc:   "no token",
+			Wanted: http.StatusUnauthorized,
+		},
+		{
+			Desc:          "basic auth",
+			Authorization: "Basic YWxpY2U6c2VjcmV0",
+			Wanted:        http.StatusUnauthorized,
+		},
+		{
+			Desc:          "invalid token",
+			Authorization: "Bearer eve-token",
+			Wanted:        http.StatusUnauthorized,
+		},
+		{
+			Desc:          "unauthorized user",
+			Authorization: "Bearer bob-token",
+			Wanted:        http.StatusForbidden,
+		},
+		{
+			Desc:          "authorized user",
+			Authorization: "Bearer alice-token",
+			Wanted:        http.StatusOK,
+		},
+	}
+
+	f := New(newFakeClient(), ParseAttributes("get", ""), time.Minute)
+	h := f.WithAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "http://localhost:8080/metrics", nil)
+		if test.Authorization != "" {
+			req.Header.Set("Authorization", test.Authorization)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != test.Wanted {
+			t.Errorf("Test error for Desc: %s. Want status %d, got %d", test.Desc, test.Wanted, w.Code)
+		}
+	}
+}
+
+func TestWithAuthCache(t *testing.T) {
+	kubeClient := newFakeClient()
+	f := New(kubeClient, ParseAttributes("get", "services"), time.Minute)
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	h := f.WithAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	scrape := func(token string) int {
+		req := httptest.NewRequest("GET", "http://localhost:8080/metrics", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := scrape("alice-token"); code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", code)
+		}
+	}
+	if n := len(kubeClient.Actions()); n != 2 {
+		t.Errorf("expected one token and one access review for cached decisions, got %d actions", n)
+	}
+
+	sar := kubeClient.Actions()[1].(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+	if sar.Spec.ResourceAttributes == nil || sar.Spec.ResourceAttributes.Resource != "services" || sar.Spec.ResourceAttributes.Verb != "get" {
+		t.Errorf("expected access review for get services, got %+v", sar.Spec)
+	}
+
+	// Denied decisions are not cached.
+	scrape("bob-token")
+	scrape("bob-token")
+	if n := len(kubeClient.Actions()); n != 6 {
+		t.Errorf("expected denied decisions to be reviewed each time, got %d actions", n)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if code := scrape("alice-token"); code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if n := len(kubeClient.Actions()); n != 8 {
+		t.Errorf("expected expired decision to be reviewed again, got %d actions", n)
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	a := ParseAttributes("get", "metrics.example.com")
+	if a.Verb != "get" || a.Resource != "metrics" || a.Group != "example.com" {
+		t.Errorf("unexpected attributes %+v", a)
+	}
+}
@@ -14,10 +14,17 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// Package builder is the public API to build the metric stores of
+// kube-state-metrics, e.g. to embed kube-state-metrics in another binary.
+// Next to the built-in resources, custom stores generating metrics for any
+// object that can be listed and watched can be registered with
+// WithCustomStores. The built stores implement metricsstore.MetricsWriter,
+// which the metrics handler serves.
 package builder
 
 import (
 	"context"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	vpaclientset "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/client/clientset/versioned"
@@ -26,6 +33,7 @@ import (
 
 	internalstore "k8s.io/kube-state-metrics/internal/store"
 	ksmtypes "k8s.io/kube-state-metrics/pkg/builder/types"
+	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
 	"k8s.io/kube-state-metrics/pkg/options"
 )
 
@@ -63,6 +71,19 @@ func (b *Builder) WithSharding(shard int32, totalShards int) {
 	b.internal.WithSharding(shard, totalShards)
 }
 
+// WithUseAPIServerCache configures whether lists are served from the watch
+// cache of the apiserver instead of etcd.
+func (b *Builder) WithUseAPIServerCache(useAPIServerCache bool) {
+	b.internal.WithUseAPIServerCache(useAPIServerCache)
+}
+
+// WithMetadataOnlyResources configures the resources the objects of which are
+// listed and watched metadata only, so that their data is neither transferred
+// nor cached. Only secrets and configmaps are supported.
+func (b *Builder) WithMetadataOnlyResources(r []string) error {
+	return b.internal.WithMetadataOnlyResources(r)
+}
+
 // WithContext sets the ctx property of a Builder.
 func (b *Builder) WithContext(ctx context.Context) {
 	b.internal.WithContext(ctx)
@@ -84,6 +105,95 @@ func (b *Builder) WithAllowDenyList(l ksmtypes.AllowDenyLister) {
 	b.internal.WithAllowDenyList(l)
 }
 
+// WithLabelSelectors configures the label selectors used when listing and
+// watching the objects of each resource.
+func (b *Builder) WithLabelSelectors(labelSelector string, resourceLabelSelectors map[string]string) error {
+	return b.internal.WithLabelSelectors(labelSelector, resourceLabelSelectors)
+}
+
+// WithFieldSelectors configures the field selectors used when listing and
+// watching the objects of individual resources.
+func (b *Builder) WithFieldSelectors(resourceFieldSelectors map[string]string) error {
+	return b.internal.WithFieldSelectors(resourceFieldSelectors)
+}
+
+// WithResyncPeriods configures the resync periods of the reflectors per
+// resource, with the "default" entry applying to all others.
+func (b *Builder) WithResyncPeriods(resyncPeriods map[string]time.Duration) error {
+	return b.internal.WithResyncPeriods(resyncPeriods)
+}
+
+// WithMetricPrefix configures the prefix replacing the leading "kube_" of all
+// metric family names.
+func (b *Builder) WithMetricPrefix(prefix string) error {
+	return b.internal.WithMetricPrefix(prefix)
+}
+
+// WithEmitDeprecatedMetricNames configures whether the metric families
+// renamed to end with their unit are exposed under their deprecated names as
+// well.
+func (b *Builder) WithEmitDeprecatedMetricNames(emitDeprecatedNames bool) {
+	b.internal.WithEmitDeprecatedMetricNames(emitDeprecatedNames)
+}
+
+// WithUIDLabel configures whether the uid of each object is added as a label
+// to the info metric family of its resource.
+func (b *Builder) WithUIDLabel(addUIDLabel bool) {
+	b.internal.WithUIDLabel(addUIDLabel)
+}
+
+// WithSkipAnnotation configures the key of the annotation objects opt out of
+// metrics with by setting it to "true". An empty key disables skipping
+// objects.
+func (b *Builder) WithSkipAnnotation(key string) error {
+	return b.internal.WithSkipAnnotation(key)
+}
+
+// WithMaxLabelValueLength configures the maximum length in bytes of the
+// values of labels converted from Kubernetes labels and annotations, longer
+// values are truncated. 0 disables truncation.
+func (b *Builder) WithMaxLabelValueLength(max int) error {
+	return b.internal.WithMaxLabelValueLength(max)
+}
+
+// WithFamilyGenerationDurationSampling configures observing the duration of
+// every nth generation of each metric family for an object. 0 disables the
+// observation.
+func (b *Builder) WithFamilyGenerationDurationSampling(n int) error {
+	return b.internal.WithFamilyGenerationDurationSampling(n)
+}
+
+// WithCustomLabels configures static labels appended to every metric.
+func (b *Builder) WithCustomLabels(customLabels map[string]string) error {
+	return b.internal.WithCustomLabels(customLabels)
+}
+
+// WithCustomStores registers the stores of resources not built into
+// kube-state-metrics, e.g. custom resources, each defined by a
+// cache.ListerWatcher and the metric families generated for its objects. They
+// are built in addition to the enabled resources, subject to the same
+// allow- and denylist, metric prefix and custom labels.
+func (b *Builder) WithCustomStores(stores []ksmtypes.CustomStore) error {
+	return b.internal.WithCustomStores(stores)
+}
+
+// WithExtraFamilyGenerators appends metric families to the built-in ones of
+// the given resource, e.g. for metrics derived from company specific
+// annotations. They are subject to the same allow- and denylist, and their
+// metrics carry the default labels of the resource, e.g. namespace and pod.
+// Names colliding with the metric families of the resource are rejected.
+func (b *Builder) WithExtraFamilyGenerators(resource string, gens []generator.FamilyGenerator) error {
+	return b.internal.WithExtraFamilyGenerators(resource, gens)
+}
+
+// WithDefaultLabelRemaps renames the default labels identifying the objects of
+// each resource, e.g. namespace to exported_namespace for pods. remaps maps
+// resources to the names of their default labels and their replacements.
+// Replacements colliding with the other labels of a resource are rejected.
+func (b *Builder) WithDefaultLabelRemaps(remaps map[string]map[string]string) error {
+	return b.internal.WithDefaultLabelRemaps(remaps)
+}
+
 // WithGenerateStoreFunc configures a constom generate store function
 func (b *Builder) WithGenerateStoreFunc(f ksmtypes.BuildStoreFunc) {
 	b.internal.WithGenerateStoreFunc(f)
@@ -94,7 +204,47 @@ func (b *Builder) DefaultGenerateStoreFunc() ksmtypes.BuildStoreFunc {
 	return b.internal.DefaultGenerateStoreFunc()
 }
 
-// Build initializes and registers all enabled stores.
-func (b *Builder) Build() []cache.Store {
+// Snapshot returns a function restoring the current configuration of the
+// Builder, e.g. to roll back a reconfiguration the stores of which could not
+// be built.
+func (b *Builder) Snapshot() func() {
+	return b.internal.Snapshot()
+}
+
+// Build initializes and registers all enabled stores. It returns an error if
+// the field selector of any resource is rejected by the apiserver.
+func (b *Builder) Build() ([]cache.Store, error) {
 	return b.internal.Build()
 }
+
+// BuildStores initializes and registers all enabled stores. It returns the
+// names of the resources alongside their stores. No store is built if the
+// field selector of any resource is rejected by the apiserver.
+func (b *Builder) BuildStores() ([]string, []cache.Store, error) {
+	return b.internal.BuildStores()
+}
+
+// ServedResources returns the enabled resources served by the apiserver, plus
+// the resources of all custom stores.
+func (b *Builder) ServedResources() []string {
+	return b.internal.ServedResources()
+}
+
+// BuildStore initializes and registers the store of the given resource, which
+// has to be one of the ServedResources. It returns an error if the field
+// selector of the resource is rejected by the apiserver.
+func (b *Builder) BuildStore(resource string) (cache.Store, error) {
+	return b.internal.BuildStore(resource)
+}
+
+// StoreFingerprint returns a string identifying the configuration the store of
+// the given resource is built with.
+func (b *Builder) StoreFingerprint(resource string) string {
+	return b.internal.StoreFingerprint(resource)
+}
+
+// ShardConfigFingerprint returns a string identifying the configuration the
+// stores of the given resources are built with, except for the shard.
+func (b *Builder) ShardConfigFingerprint(resources []string) string {
+	return b.internal.ShardConfigFingerprint(resources)
+}
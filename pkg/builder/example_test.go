@@ -0,0 +1,123 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder_test
+
+import (
+	"context"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/kube-state-metrics/pkg/allowdenylist"
+	"k8s.io/kube-state-metrics/pkg/builder"
+	ksmtypes "k8s.io/kube-state-metrics/pkg/builder/types"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+	"k8s.io/kube-state-metrics/pkg/options"
+)
+
+// This example embeds kube-state-metrics to expose a custom metric for each
+// ConfigMap next to the built-in metrics.
+func Example() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeClient := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"},
+		Data:       map[string]string{"color": "blue", "size": "large"},
+	})
+
+	configMapKeys := generator.FamilyGenerator{
+		Name: "acme_configmap_keys",
+		Type: metric.Gauge,
+		Help: "Number of keys in the ConfigMap.",
+		GenerateFunc: func(obj interface{}) *metric.Family {
+			cm := obj.(*v1.ConfigMap)
+			return &metric.Family{
+				Metrics: []*metric.Metric{{
+					LabelKeys:   []string{"namespace", "configmap"},
+					LabelValues: []string{cm.Namespace, cm.Name},
+					Value:       float64(len(cm.Data)),
+				}},
+			}
+		},
+	}
+
+	allowDenyList, err := allowdenylist.New(map[string]struct{}{}, map[string]struct{}{})
+	if err != nil {
+		panic(err)
+	}
+	if err := allowDenyList.Parse(); err != nil {
+		panic(err)
+	}
+
+	b := builder.NewBuilder()
+	b.WithMetrics(prometheus.NewRegistry())
+	b.WithContext(ctx)
+	b.WithKubeClient(kubeClient)
+	b.WithNamespaces(options.DefaultNamespaces)
+	b.WithSharding(0, 1)
+	b.WithAllowDenyList(allowDenyList)
+	b.WithGenerateStoreFunc(b.DefaultGenerateStoreFunc())
+	if err := b.WithEnabledResources([]string{}); err != nil {
+		panic(err)
+	}
+	err = b.WithCustomStores([]ksmtypes.CustomStore{{
+		Resource:     "acme_configmaps",
+		ExpectedType: &v1.ConfigMap{},
+		ListWatchFunc: func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher {
+			return &cache.ListWatch{
+				ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+					tweakListOptions(&opts)
+					return kubeClient.CoreV1().ConfigMaps(ns).List(opts)
+				},
+				WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+					tweakListOptions(&opts)
+					return kubeClient.CoreV1().ConfigMaps(ns).Watch(opts)
+				},
+			}
+		},
+		FamilyGenerators: []generator.FamilyGenerator{configMapKeys},
+	}})
+	if err != nil {
+		panic(err)
+	}
+
+	_, stores, err := b.BuildStores()
+	if err != nil {
+		panic(err)
+	}
+	for _, s := range stores {
+		if !cache.WaitForCacheSync(ctx.Done(), s.(*metricsstore.MetricsStore).HasSynced) {
+			panic("store did not sync")
+		}
+		s.(metricsstore.MetricsWriter).WriteAll(os.Stdout)
+	}
+
+	// Output:
+	// # HELP acme_configmap_keys Number of keys in the ConfigMap.
+	// # TYPE acme_configmap_keys gauge
+	// acme_configmap_keys{namespace="default",configmap="settings"} 2
+}
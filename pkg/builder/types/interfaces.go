@@ -18,8 +18,10 @@ package types
 
 import (
 	"context"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	vpaclientset "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/client/clientset/versioned"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -34,21 +36,60 @@ type BuilderInterface interface {
 	WithEnabledResources(c []string) error
 	WithNamespaces(n options.NamespaceList)
 	WithSharding(shard int32, totalShards int)
+	WithUseAPIServerCache(useAPIServerCache bool)
+	WithMetadataOnlyResources(r []string) error
 	WithContext(ctx context.Context)
 	WithKubeClient(c clientset.Interface)
 	WithVPAClient(c vpaclientset.Interface)
 	WithAllowDenyList(l AllowDenyLister)
+	WithLabelSelectors(labelSelector string, resourceLabelSelectors map[string]string) error
+	WithFieldSelectors(resourceFieldSelectors map[string]string) error
+	WithResyncPeriods(resyncPeriods map[string]time.Duration) error
+	WithMetricPrefix(prefix string) error
+	WithEmitDeprecatedMetricNames(emitDeprecatedNames bool)
+	WithUIDLabel(addUIDLabel bool)
+	WithSkipAnnotation(key string) error
+	WithMaxLabelValueLength(max int) error
+	WithFamilyGenerationDurationSampling(n int) error
+	WithCustomLabels(customLabels map[string]string) error
+	WithCustomStores(stores []CustomStore) error
+	WithExtraFamilyGenerators(resource string, gens []generator.FamilyGenerator) error
+	WithDefaultLabelRemaps(remaps map[string]map[string]string) error
 	WithGenerateStoreFunc(f BuildStoreFunc)
 	DefaultGenerateStoreFunc() BuildStoreFunc
-	Build() []cache.Store
+	Snapshot() func()
+	Build() ([]cache.Store, error)
+	BuildStores() ([]string, []cache.Store, error)
+	ServedResources() []string
+	BuildStore(resource string) (cache.Store, error)
+	StoreFingerprint(resource string) string
+	ShardConfigFingerprint(resources []string) string
 }
 
 // BuildStoreFunc function signature that is use to returns a cache.Store
-type BuildStoreFunc func(metricFamilies []generator.FamilyGenerator,
+type BuildStoreFunc func(resource string,
+	metricFamilies []generator.FamilyGenerator,
 	expectedType interface{},
-	listWatchFunc func(kubeClient clientset.Interface, ns string) cache.ListerWatcher,
+	listWatchFunc func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher,
 ) cache.Store
 
+// CustomStore describes the store of a resource not built into
+// kube-state-metrics, e.g. a custom resource.
+type CustomStore struct {
+	// Resource is the name of the resource, e.g. foos.example.com. It must not
+	// collide with a built-in resource.
+	Resource string
+	// ExpectedType is an object of the listed type, e.g. &v1alpha1.Foo{}.
+	ExpectedType interface{}
+	// ListWatchFunc returns the cache.ListerWatcher of the objects in the given
+	// namespace, applying tweakListOptions to both lists and watches.
+	ListWatchFunc func(kubeClient clientset.Interface, ns string, tweakListOptions func(*metav1.ListOptions)) cache.ListerWatcher
+	// FamilyGenerators generate the metrics of each object.
+	FamilyGenerators []generator.FamilyGenerator
+	// ClusterScoped is set if the objects are not bound to a namespace.
+	ClusterScoped bool
+}
+
 // AllowDenyLister interface for AllowDeny lister that can allow or exclude metrics by there names
 type AllowDenyLister interface {
 	IsIncluded(string) bool
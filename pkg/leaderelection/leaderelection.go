@@ -0,0 +1,301 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package leaderelection implements leader election among kube-state-metrics
+// replicas based on a coordination.k8s.io Lease, following the algorithm of
+// k8s.io/client-go/tools/leaderelection.
+package leaderelection
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+// Config configures an Elector.
+type Config struct {
+	// LeaseName and LeaseNamespace identify the Lease the replicas compete
+	// for.
+	LeaseName      string
+	LeaseNamespace string
+	// Identity identifies the replica, e.g. its pod name.
+	Identity string
+
+	// LeaseDuration is the duration non-leaders wait before taking over a
+	// Lease that was not renewed.
+	LeaseDuration time.Duration
+	// RenewDeadline is the duration the leader keeps retrying to renew the
+	// Lease before giving up leadership.
+	RenewDeadline time.Duration
+	// RetryPeriod is the interval in which acquiring or renewing the Lease
+	// is tried.
+	RetryPeriod time.Duration
+}
+
+// Validate returns an error if the Config is incomplete or its durations are
+// inconsistent.
+func (c Config) Validate() error {
+	if c.LeaseName == "" || c.LeaseNamespace == "" {
+		return errors.New("lease name and namespace are required")
+	}
+	if c.Identity == "" {
+		return errors.New("identity is required")
+	}
+	if c.LeaseDuration <= c.RenewDeadline {
+		return errors.New("lease duration has to be greater than the renew deadline")
+	}
+	if c.RenewDeadline <= c.RetryPeriod {
+		return errors.New("renew deadline has to be greater than the retry period")
+	}
+	if c.RetryPeriod <= 0 {
+		return errors.New("retry period has to be positive")
+	}
+	return nil
+}
+
+// Elector takes part in leader election for a Lease.
+type Elector struct {
+	kubeClient clientset.Interface
+	config     Config
+	leader     prometheus.Gauge
+
+	// mtx protects isLeader
+	mtx      sync.RWMutex
+	isLeader bool
+
+	// observedRecord and observedTime track when the Lease was last seen
+	// changing, to determine whether it expired without relying on synchronized
+	// clocks.
+	observedRecord coordinationv1.LeaseSpec
+	observedTime   time.Time
+
+	now func() time.Time
+}
+
+// New returns a new Elector. The leadership state is exposed via the
+// kube_state_metrics_leader metric registered with the given registry.
+func New(kubeClient clientset.Interface, config Config, r prometheus.Registerer) (*Elector, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	e := &Elector{
+		kubeClient: kubeClient,
+		config:     config,
+		leader: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "kube_state_metrics_leader",
+			Help: "Whether this kube-state-metrics instance is the leader and serves metrics.",
+		}),
+		now: time.Now,
+	}
+	if r != nil {
+		r.MustRegister(e.leader)
+	}
+	return e, nil
+}
+
+// IsLeader returns whether the Elector currently holds the Lease.
+func (e *Elector) IsLeader() bool {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+
+	return e.isLeader
+}
+
+func (e *Elector) setLeader(isLeader bool) {
+	e.mtx.Lock()
+	changed := e.isLeader != isLeader
+	e.isLeader = isLeader
+	e.mtx.Unlock()
+
+	if isLeader {
+		e.leader.Set(1)
+	} else {
+		e.leader.Set(0)
+	}
+
+	if changed && isLeader {
+		klog.Infof("Became leader of lease %s/%s", e.config.LeaseNamespace, e.config.LeaseName)
+	} else if changed {
+		klog.Infof("Lost leadership of lease %s/%s", e.config.LeaseNamespace, e.config.LeaseName)
+	}
+}
+
+// Run takes part in leader election until the given context is done. The
+// Lease is released on return if held, so that another replica can take
+// over right away.
+func (e *Elector) Run(ctx context.Context) {
+	e.setLeader(false)
+
+	ticker := time.NewTicker(e.config.RetryPeriod)
+	defer ticker.Stop()
+
+	var lastRenew time.Time
+	for {
+		if e.tryAcquireOrRenew() {
+			lastRenew = e.now()
+			e.setLeader(true)
+		} else if e.IsLeader() && e.now().Sub(lastRenew) > e.config.RenewDeadline {
+			e.setLeader(false)
+		}
+
+		select {
+		case <-ctx.Done():
+			if e.IsLeader() {
+				e.release()
+				e.setLeader(false)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tryAcquireOrRenew tries to acquire the Lease, or renew it if already held,
+// and returns whether it succeeded.
+func (e *Elector) tryAcquireOrRenew() bool {
+	leases := e.kubeClient.CoordinationV1().Leases(e.config.LeaseNamespace)
+	now := metav1.NewMicroTime(e.now())
+	leaseDurationSeconds := int32(e.config.LeaseDuration / time.Second)
+
+	lease, err := leases.Get(e.config.LeaseName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		transitions := int32(0)
+		created, err := leases.Create(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      e.config.LeaseName,
+				Namespace: e.config.LeaseNamespace,
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &e.config.Identity,
+				LeaseDurationSeconds: &leaseDurationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+				LeaseTransitions:     &transitions,
+			},
+		})
+		if err != nil {
+			klog.Errorf("Failed to create lease %s/%s: %v", e.config.LeaseNamespace, e.config.LeaseName, err)
+			return false
+		}
+		e.observe(created.Spec)
+		return true
+	}
+	if err != nil {
+		klog.Errorf("Failed to get lease %s/%s: %v", e.config.LeaseNamespace, e.config.LeaseName, err)
+		return false
+	}
+
+	holder := stringValue(lease.Spec.HolderIdentity)
+	if !specEqual(lease.Spec, e.observedRecord) {
+		e.observe(lease.Spec)
+	}
+	if holder != "" && holder != e.config.Identity && e.observedTime.Add(e.config.LeaseDuration).After(e.now()) {
+		return false
+	}
+
+	spec := lease.Spec.DeepCopy()
+	if holder != e.config.Identity {
+		transitions := int32Value(spec.LeaseTransitions) + 1
+		spec.LeaseTransitions = &transitions
+		spec.AcquireTime = &now
+	}
+	spec.HolderIdentity = &e.config.Identity
+	spec.LeaseDurationSeconds = &leaseDurationSeconds
+	spec.RenewTime = &now
+
+	lease.Spec = *spec
+	updated, err := leases.Update(lease)
+	if err != nil {
+		klog.Errorf("Failed to update lease %s/%s: %v", e.config.LeaseNamespace, e.config.LeaseName, err)
+		return false
+	}
+	e.observe(updated.Spec)
+	return true
+}
+
+// release gives up the Lease by clearing its holder.
+func (e *Elector) release() {
+	leases := e.kubeClient.CoordinationV1().Leases(e.config.LeaseNamespace)
+
+	lease, err := leases.Get(e.config.LeaseName, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Failed to get lease %s/%s for release: %v", e.config.LeaseNamespace, e.config.LeaseName, err)
+		return
+	}
+	if stringValue(lease.Spec.HolderIdentity) != e.config.Identity {
+		return
+	}
+
+	lease.Spec.HolderIdentity = nil
+	if _, err := leases.Update(lease); err != nil {
+		klog.Errorf("Failed to release lease %s/%s: %v", e.config.LeaseNamespace, e.config.LeaseName, err)
+	}
+}
+
+func (e *Elector) observe(spec coordinationv1.LeaseSpec) {
+	e.observedRecord = *spec.DeepCopy()
+	e.observedTime = e.now()
+}
+
+// WithLeadership returns a handler passing requests on to the given handler
+// while the Elector is the leader. Otherwise requests are answered with 503
+// Service Unavailable.
+func (e *Elector) WithLeadership(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !e.IsLeader() {
+			http.Error(w, "not the leader", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func specEqual(a, b coordinationv1.LeaseSpec) bool {
+	return stringValue(a.HolderIdentity) == stringValue(b.HolderIdentity) &&
+		microTimeEqual(a.RenewTime, b.RenewTime) &&
+		int32Value(a.LeaseTransitions) == int32Value(b.LeaseTransitions)
+}
+
+func microTimeEqual(a, b *metav1.MicroTime) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(b)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func int32Value(i *int32) int32 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
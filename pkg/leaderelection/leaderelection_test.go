@@ -0,0 +1,239 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderelection
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testConfig(identity string) Config {
+	return Config{
+		LeaseName:      "kube-state-metrics",
+		LeaseNamespace: "kube-system",
+		Identity:       identity,
+		LeaseDuration:  15 * time.Second,
+		RenewDeadline:  10 * time.Second,
+		RetryPeriod:    2 * time.Second,
+	}
+}
+
+func newTestElector(t *testing.T, kubeClient clientset.Interface, identity string, now *time.Time) *Elector {
+	e, err := New(kubeClient, testConfig(identity), prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.now = func() time.Time { return *now }
+	return e
+}
+
+func holder(t *testing.T, kubeClient clientset.Interface) string {
+	lease, err := kubeClient.CoordinationV1().Leases("kube-system").Get("kube-state-metrics", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stringValue(lease.Spec.HolderIdentity)
+}
+
+func TestTryAcquireOrRenew(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	now := time.Unix(1000, 0)
+	a := newTestElector(t, kubeClient, "a", &now)
+	b := newTestElector(t, kubeClient, "b", &now)
+
+	steps := []struct {
+		Desc         string
+		Advance      time.Duration
+		Elector      *Elector
+		Wanted       bool
+		WantedHolder string
+	}{
+		{
+			Desc:         "first elector creates the lease",
+			Elector:      a,
+			Wanted:       true,
+			WantedHolder: "a",
+		},
+		{
+			Desc:         "second elector cannot take a held lease",
+			Elector:      b,
+			Wanted:       false,
+			WantedHolder: "a",
+		},
+		{
+			Desc:         "leader renews the lease",
+			Advance:      5 * time.Second,
+			Elector:      a,
+			Wanted:       true,
+			WantedHolder: "a",
+		},
+		{
+			Desc:         "second elector observes the renewed lease",
+			Elector:      b,
+			Wanted:       false,
+			WantedHolder: "a",
+		},
+		{
+			Desc:         "second elector cannot take the lease before it expires",
+			Advance:      10 * time.Second,
+			Elector:      b,
+			Wanted:       false,
+			WantedHolder: "a",
+		},
+		{
+			Desc:         "second elector takes over the expired lease",
+			Advance:      6 * time.Second,
+			Elector:      b,
+			Wanted:       true,
+			WantedHolder: "b",
+		},
+		{
+			Desc:         "former leader cannot renew the lease taken over",
+			Elector:      a,
+			Wanted:       false,
+			WantedHolder: "b",
+		},
+	}
+
+	for _, s := range steps {
+		now = now.Add(s.Advance)
+		got := s.Elector.tryAcquireOrRenew()
+		gotHolder := holder(t, kubeClient)
+		if got != s.Wanted || gotHolder != s.WantedHolder {
+			t.Errorf("Test error for Desc: %s. Want: %v, holder %q. Got: %v, holder %q.", s.Desc, s.Wanted, s.WantedHolder, got, gotHolder)
+		}
+	}
+}
+
+func TestRelease(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	now := time.Unix(1000, 0)
+	a := newTestElector(t, kubeClient, "a", &now)
+	b := newTestElector(t, kubeClient, "b", &now)
+
+	if !a.tryAcquireOrRenew() {
+		t.Fatal("expected to acquire the lease")
+	}
+	if b.tryAcquireOrRenew() {
+		t.Fatal("expected not to acquire a held lease")
+	}
+
+	// Releasing lets another elector take over without waiting for the
+	// lease to expire.
+	a.release()
+	if !b.tryAcquireOrRenew() {
+		t.Fatal("expected to acquire a released lease")
+	}
+	if got := holder(t, kubeClient); got != "b" {
+		t.Fatalf("expected holder b, got %q", got)
+	}
+
+	// Releasing a lease held by another elector has no effect.
+	a.release()
+	if got := holder(t, kubeClient); got != "b" {
+		t.Fatalf("expected holder b, got %q", got)
+	}
+}
+
+func TestWithLeadership(t *testing.T) {
+	now := time.Unix(1000, 0)
+	e := newTestElector(t, fake.NewSimpleClientset(), "a", &now)
+	h := e.WithLeadership(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		Desc         string
+		IsLeader     bool
+		WantedStatus int
+		WantedMetric float64
+	}{
+		{
+			Desc:         "not the leader",
+			IsLeader:     false,
+			WantedStatus: http.StatusServiceUnavailable,
+			WantedMetric: 0,
+		},
+		{
+			Desc:         "leader",
+			IsLeader:     true,
+			WantedStatus: http.StatusOK,
+			WantedMetric: 1,
+		},
+	}
+
+	for _, test := range tests {
+		e.setLeader(test.IsLeader)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		m := &dto.Metric{}
+		if err := e.leader.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		gotMetric := m.GetGauge().GetValue()
+		if w.Code != test.WantedStatus || gotMetric != test.WantedMetric {
+			t.Errorf("Test error for Desc: %s. Want: status %d, metric %v. Got: status %d, metric %v.", test.Desc, test.WantedStatus, test.WantedMetric, w.Code, gotMetric)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Modify      func(*Config)
+		WantedError bool
+	}{
+		{
+			Desc:   "valid config",
+			Modify: func(*Config) {},
+		},
+		{
+			Desc:        "missing namespace",
+			Modify:      func(c *Config) { c.LeaseNamespace = "" },
+			WantedError: true,
+		},
+		{
+			Desc:        "missing identity",
+			Modify:      func(c *Config) { c.Identity = "" },
+			WantedError: true,
+		},
+		{
+			Desc:        "renew deadline not shorter than lease duration",
+			Modify:      func(c *Config) { c.RenewDeadline = c.LeaseDuration },
+			WantedError: true,
+		},
+		{
+			Desc:        "retry period not shorter than renew deadline",
+			Modify:      func(c *Config) { c.RetryPeriod = c.RenewDeadline },
+			WantedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		c := testConfig("a")
+		test.Modify(&c)
+		if err := c.Validate(); (err != nil) != test.WantedError {
+			t.Errorf("Test error for Desc: %s. Wanted Error: %v, Got Error: %v", test.Desc, test.WantedError, err)
+		}
+	}
+}
@@ -41,6 +41,7 @@ func (f Family) Inspect(inspect func(Family)) {
 // ByteSlice returns the given Family in its string representation.
 func (f Family) ByteSlice() []byte {
 	b := strings.Builder{}
+	b.Grow(f.size())
 	for _, m := range f.Metrics {
 		b.WriteString(f.Name)
 		m.Write(&b)
@@ -48,3 +49,22 @@ func (f Family) ByteSlice() []byte {
 
 	return []byte(b.String())
 }
+
+// size estimates the length of the string representation of the Family, so
+// that it is rendered without growing the buffer, unless label values need
+// escaping or values are long.
+func (f Family) size() int {
+	size := 0
+	for _, m := range f.Metrics {
+		// Name, braces, space, value and new line.
+		size += len(f.Name) + 2 + 1 + 12 + 1
+		for _, k := range m.LabelKeys {
+			// Equal sign, quotes and comma.
+			size += len(k) + 4
+		}
+		for _, v := range m.LabelValues {
+			size += len(v)
+		}
+	}
+	return size
+}
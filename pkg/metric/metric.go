@@ -47,6 +47,44 @@ var Gauge Type = "gauge"
 // Counter defines a Prometheus counter.
 var Counter Type = "counter"
 
+// Info defines an info metric, exposing information about an object in its
+// labels. Its metrics always have the value 1, which is set when they are
+// generated. It is exposed as a gauge in the Prometheus text format.
+var Info Type = "info"
+
+// StateSet defines a set of metrics, one for each possible state of an object,
+// with the value 1 for the current state and 0 for the others, see
+// StateSetMetrics. It is exposed as a gauge in both the Prometheus and the
+// OpenMetrics text format, see OpenMetricsHeader.
+var StateSet Type = "stateset"
+
+// PrometheusType returns the type in the Prometheus text format, which knows
+// neither info metrics nor state sets.
+func (t Type) PrometheusType() Type {
+	if t == Info || t == StateSet {
+		return Gauge
+	}
+	return t
+}
+
+// Header returns the metadata of a metric family in the Prometheus text
+// format, without trailing new line. Backslashes and new lines in the help
+// text are escaped, as the text format requires.
+func Header(name, help string, t Type) string {
+	header := strings.Builder{}
+	header.WriteString("# HELP ")
+	header.WriteString(name)
+	header.WriteByte(' ')
+	escapeHelp.WriteString(&header, help)
+	header.WriteByte('\n')
+	header.WriteString("# TYPE ")
+	header.WriteString(name)
+	header.WriteByte(' ')
+	header.WriteString(string(t.PrometheusType()))
+
+	return header.String()
+}
+
 // Metric represents a single time series.
 type Metric struct {
 	// The name of a metric is injected by its family to reduce duplication.
@@ -55,6 +93,54 @@ type Metric struct {
 	Value       float64
 }
 
+// StateSetMetrics returns a metric for each of the given states, labelled with
+// the given key and the state, with the value 1 for the current state and 0
+// for the others. All values are 0 if the current state is none of the states.
+func StateSetMetrics(labelKey string, states []string, current string) []*Metric {
+	ms := make([]*Metric, len(states))
+	// The metrics and their labels are allocated at once, the labels of each
+	// metric capped so that appending to them reallocates.
+	metrics := make([]Metric, len(states))
+	labels := make([]string, 2*len(states))
+
+	for i, state := range states {
+		var value float64
+		if state == current {
+			value = 1
+		}
+		labels[2*i], labels[2*i+1] = labelKey, state
+		metrics[i] = Metric{
+			LabelKeys:   labels[2*i : 2*i+1 : 2*i+1],
+			LabelValues: labels[2*i+1 : 2*i+2 : 2*i+2],
+			Value:       value,
+		}
+		ms[i] = &metrics[i]
+	}
+
+	return ms
+}
+
+// AppendLabels appends the given labels to the labels of the Metric, except
+// for those the Metric already has a label of the same name for.
+func (m *Metric) AppendLabels(keys, values []string) {
+	for i, key := range keys {
+		if containsKey(m.LabelKeys, key) {
+			continue
+		}
+		m.LabelKeys = append(m.LabelKeys, key)
+		m.LabelValues = append(m.LabelValues, values[i])
+	}
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *Metric) Write(s *strings.Builder) {
 	if len(m.LabelKeys) != len(m.LabelValues) {
 		panic(fmt.Sprintf(
@@ -88,6 +174,7 @@ func labelsToString(m *strings.Builder, keys, values []string) {
 
 var (
 	escapeWithDoubleQuote = strings.NewReplacer("\\", `\\`, "\n", `\n`, "\"", `\"`)
+	escapeHelp            = strings.NewReplacer("\\", `\\`, "\n", `\n`)
 )
 
 // escapeString replaces '\' by '\\', new line character by '\n', and '"' by
@@ -41,6 +41,78 @@ func TestFamilyString(t *testing.T) {
 	}
 }
 
+func TestHeader(t *testing.T) {
+	tests := []struct {
+		help     string
+		t        Type
+		expected string
+	}{
+		{
+			help:     "Information about pod.",
+			t:        Info,
+			expected: "# HELP kube_pod_info Information about pod.\n# TYPE kube_pod_info gauge",
+		},
+		{
+			help:     "Information \"about\" pod,\nwith a \\ backslash.",
+			t:        Gauge,
+			expected: "# HELP kube_pod_info Information \"about\" pod,\\nwith a \\\\ backslash.\n# TYPE kube_pod_info gauge",
+		},
+	}
+
+	for _, test := range tests {
+		if got := Header("kube_pod_info", test.help, test.t); got != test.expected {
+			t.Errorf("expected header %q, got %q", test.expected, got)
+		}
+	}
+}
+
+func TestStateSetMetrics(t *testing.T) {
+	tests := []struct {
+		Desc    string
+		Current string
+		Want    []float64
+	}{
+		{
+			Desc:    "current state",
+			Current: "Running",
+			Want:    []float64{0, 1, 0},
+		},
+		{
+			Desc:    "unknown state",
+			Current: "Evicted",
+			Want:    []float64{0, 0, 0},
+		},
+	}
+
+	states := []string{"Pending", "Running", "Succeeded"}
+	for _, test := range tests {
+		ms := StateSetMetrics("phase", states, test.Current)
+		if len(ms) != len(states) {
+			t.Fatalf("Test error for Desc: %s. Expected %d metrics, got %d", test.Desc, len(states), len(ms))
+		}
+		for i, m := range ms {
+			if len(m.LabelKeys) != 1 || m.LabelKeys[0] != "phase" || m.LabelValues[0] != states[i] || m.Value != test.Want[i] {
+				t.Errorf("Test error for Desc: %s. Got metric %+v for state %s, want value %v", test.Desc, *m, states[i], test.Want[i])
+			}
+		}
+
+		// Appending labels to a metric must not affect the other metrics,
+		// the labels of which share an allocation.
+		ms[0].AppendLabels([]string{"cluster"}, []string{"prod"})
+		if ms[1].LabelKeys[0] != "phase" || ms[1].LabelValues[0] != states[1] || len(ms[0].LabelValues) != 2 {
+			t.Errorf("Test error for Desc: %s. Appending labels to %+v changed %+v", test.Desc, *ms[0], *ms[1])
+		}
+	}
+}
+
+func TestPrometheusType(t *testing.T) {
+	for typ, want := range map[Type]Type{Gauge: Gauge, Counter: Counter, Info: Gauge, StateSet: Gauge} {
+		if got := typ.PrometheusType(); got != want {
+			t.Errorf("expected %s to be exposed as %s, got %s", typ, want, got)
+		}
+	}
+}
+
 func BenchmarkMetricWrite(b *testing.B) {
 	tests := []struct {
 		testName       string
@@ -0,0 +1,80 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metric
+
+import (
+	"strings"
+)
+
+// OpenMetricsEOF terminates every exposition in the OpenMetrics text format.
+const OpenMetricsEOF = "# EOF\n"
+
+// openMetricsUnits are the units metric family names can end with, which are
+// exposed as UNIT metadata in the OpenMetrics text format.
+var openMetricsUnits = []string{"seconds", "bytes"}
+
+var escapeOpenMetricsHelp = strings.NewReplacer("\\", `\\`, "\n", `\n`, "\"", `\"`)
+
+// OpenMetricsHeader returns the metadata of a metric family in the OpenMetrics
+// text format, without trailing new line. The samples of a metric family are
+// the same as in the Prometheus text format, only the metadata differs:
+//
+//   - The metadata of counters refers to the name without the _total suffix.
+//   - The metadata of info metrics refers to the name without the _info
+//     suffix. Info metrics not named accordingly are exposed as gauges.
+//   - State sets are exposed as gauges, as OpenMetrics requires their state
+//     label to be named after the metric family, which the shared samples
+//     are not.
+//   - Names ending with a unit, e.g. _seconds, carry UNIT metadata.
+func OpenMetricsHeader(name, help string, t Type) string {
+	typ := string(t)
+	switch {
+	case t == Counter && strings.HasSuffix(name, "_total"):
+		name = strings.TrimSuffix(name, "_total")
+	case t == Counter:
+		typ = "unknown"
+	case t == Info && strings.HasSuffix(name, "_info"):
+		name = strings.TrimSuffix(name, "_info")
+	case t == Info, t == StateSet:
+		typ = string(Gauge)
+	}
+
+	header := strings.Builder{}
+	header.WriteString("# HELP ")
+	header.WriteString(name)
+	header.WriteByte(' ')
+	escapeOpenMetricsHelp.WriteString(&header, help)
+	header.WriteByte('\n')
+	header.WriteString("# TYPE ")
+	header.WriteString(name)
+	header.WriteByte(' ')
+	header.WriteString(typ)
+
+	if typ != string(Info) {
+		for _, unit := range openMetricsUnits {
+			if strings.HasSuffix(name, "_"+unit) {
+				header.WriteString("\n# UNIT ")
+				header.WriteString(name)
+				header.WriteByte(' ')
+				header.WriteString(unit)
+				break
+			}
+		}
+	}
+
+	return header.String()
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metric
+
+import (
+	"testing"
+)
+
+func TestOpenMetricsHeader(t *testing.T) {
+	tests := []struct {
+		Desc string
+		Name string
+		Help string
+		Type Type
+		Want string
+	}{
+		{
+			Desc: "gauge",
+			Name: "kube_deployment_spec_replicas",
+			Help: "Number of desired pods for a deployment.",
+			Type: Gauge,
+			Want: "# HELP kube_deployment_spec_replicas Number of desired pods for a deployment.\n# TYPE kube_deployment_spec_replicas gauge",
+		},
+		{
+			Desc: "counter",
+			Name: "kube_pod_container_status_restarts_total",
+			Help: "The number of container restarts per container.",
+			Type: Counter,
+			Want: "# HELP kube_pod_container_status_restarts The number of container restarts per container.\n# TYPE kube_pod_container_status_restarts counter",
+		},
+		{
+			Desc: "counter without _total suffix",
+			Name: "kube_foo_restarts",
+			Help: "Restarts.",
+			Type: Counter,
+			Want: "# HELP kube_foo_restarts Restarts.\n# TYPE kube_foo_restarts unknown",
+		},
+		{
+			Desc: "info",
+			Name: "kube_pod_info",
+			Help: "Information about pod.",
+			Type: Info,
+			Want: "# HELP kube_pod Information about pod.\n# TYPE kube_pod info",
+		},
+		{
+			Desc: "info without _info suffix",
+			Name: "kube_pod_owner",
+			Help: "Information about the Pod's owner.",
+			Type: Info,
+			Want: "# HELP kube_pod_owner Information about the Pod's owner.\n# TYPE kube_pod_owner gauge",
+		},
+		{
+			Desc: "gauge with _info suffix",
+			Name: "kube_foo_info",
+			Help: "Foo.",
+			Type: Gauge,
+			Want: "# HELP kube_foo_info Foo.\n# TYPE kube_foo_info gauge",
+		},
+		{
+			Desc: "state set",
+			Name: "kube_pod_status_phase",
+			Help: "The pods current phase.",
+			Type: StateSet,
+			Want: "# HELP kube_pod_status_phase The pods current phase.\n# TYPE kube_pod_status_phase gauge",
+		},
+		{
+			Desc: "unit",
+			Name: "kube_pod_start_time_seconds",
+			Help: "Start time in unix timestamp for a pod.",
+			Type: Gauge,
+			Want: "# HELP kube_pod_start_time_seconds Start time in unix timestamp for a pod.\n# TYPE kube_pod_start_time_seconds gauge\n# UNIT kube_pod_start_time_seconds seconds",
+		},
+		{
+			Desc: "escaped help",
+			Name: "kube_foo",
+			Help: "Foo \"bar\"\\baz.",
+			Type: Gauge,
+			Want: "# HELP kube_foo Foo \\\"bar\\\"\\\\baz.\n# TYPE kube_foo gauge",
+		},
+	}
+
+	for _, test := range tests {
+		if got := OpenMetricsHeader(test.Name, test.Help, test.Type); got != test.Want {
+			t.Errorf("Test error for Desc: %s. Want:\n%s\nGot:\n%s", test.Desc, test.Want, got)
+		}
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package generator
 
 import (
+	"fmt"
 	"strings"
 
 	"k8s.io/kube-state-metrics/pkg/metric"
@@ -35,26 +36,32 @@ type FamilyGenerator struct {
 // name. The reasoning behind injecting the name at such a late point in time is
 // deduplication in the code, preventing typos made by developers as
 // well as saving memory.
+// The metrics of info families get the value 1, setting a value is a
+// programming error, see checkInfoValues().
 func (g *FamilyGenerator) Generate(obj interface{}) *metric.Family {
 	family := g.GenerateFunc(obj)
 	family.Name = g.Name
 	family.Type = g.Type
+	if g.Type == metric.Info {
+		checkInfoValues(g.Name, family)
+		for _, m := range family.Metrics {
+			m.Value = 1
+		}
+	}
 	return family
 }
 
+// checkInfoValues panics if any metric of the given info family has a value.
+func checkInfoValues(name string, family *metric.Family) {
+	for _, m := range family.Metrics {
+		if m.Value != 0 {
+			panic(fmt.Sprintf("expected no value for metric of info family %s, got %v", name, m.Value))
+		}
+	}
+}
+
 func (g *FamilyGenerator) generateHeader() string {
-	header := strings.Builder{}
-	header.WriteString("# HELP ")
-	header.WriteString(g.Name)
-	header.WriteByte(' ')
-	header.WriteString(g.Help)
-	header.WriteByte('\n')
-	header.WriteString("# TYPE ")
-	header.WriteString(g.Name)
-	header.WriteByte(' ')
-	header.WriteString(string(g.Type))
-
-	return header.String()
+	return metric.Header(g.Name, g.Help, g.Type)
 }
 
 // ExtractMetricFamilyHeaders takes in a slice of FamilyGenerator metrics and
@@ -69,6 +76,18 @@ func ExtractMetricFamilyHeaders(families []FamilyGenerator) []string {
 	return headers
 }
 
+// ExtractMetricFamilyOpenMetricsHeaders takes in a slice of FamilyGenerator
+// metrics and returns their headers in the OpenMetrics text format.
+func ExtractMetricFamilyOpenMetricsHeaders(families []FamilyGenerator) []string {
+	headers := make([]string, len(families))
+
+	for i, f := range families {
+		headers[i] = metric.OpenMetricsHeader(f.Name, f.Help, f.Type)
+	}
+
+	return headers
+}
+
 // ComposeMetricGenFuncs takes a slice of metric families and returns a function
 // that composes their metric generation functions into a single one.
 func ComposeMetricGenFuncs(familyGens []FamilyGenerator) func(obj interface{}) []metric.FamilyInterface {
@@ -83,6 +102,59 @@ func ComposeMetricGenFuncs(familyGens []FamilyGenerator) func(obj interface{}) [
 	}
 }
 
+// RecoverMetricFamilies takes a slice of metric families and returns a slice of
+// the metric families, the generation functions of which recover from panics.
+// A family panicking for an object generates no metrics for it and onPanic is
+// called with the name of the family, the object and the recovered value, so
+// that a single malformed object cannot take down all metrics. Info families
+// setting a value are treated as panicking as well.
+func RecoverMetricFamilies(families []FamilyGenerator, onPanic func(family string, obj interface{}, r interface{})) []FamilyGenerator {
+	recovered := make([]FamilyGenerator, len(families))
+
+	for i, f := range families {
+		name, typ, generateFunc := f.Name, f.Type, f.GenerateFunc
+		f.GenerateFunc = func(obj interface{}) (family *metric.Family) {
+			defer func() {
+				if r := recover(); r != nil {
+					onPanic(name, obj, r)
+					family = &metric.Family{}
+				}
+			}()
+			family = generateFunc(obj)
+			if typ == metric.Info {
+				checkInfoValues(name, family)
+			}
+			return family
+		}
+		recovered[i] = f
+	}
+
+	return recovered
+}
+
+// DefaultMetricPrefix is the prefix all metric family names start with.
+const DefaultMetricPrefix = "kube_"
+
+// PrefixMetricFamilies takes a prefix and a slice of metric families and
+// returns a slice of the metric families, the DefaultMetricPrefix of the names
+// of which is replaced by the given prefix.
+func PrefixMetricFamilies(prefix string, families []FamilyGenerator) []FamilyGenerator {
+	if prefix == DefaultMetricPrefix {
+		return families
+	}
+
+	prefixed := make([]FamilyGenerator, len(families))
+
+	for i, f := range families {
+		if strings.HasPrefix(f.Name, DefaultMetricPrefix) {
+			f.Name = prefix + strings.TrimPrefix(f.Name, DefaultMetricPrefix)
+		}
+		prefixed[i] = f
+	}
+
+	return prefixed
+}
+
 type allowDenyLister interface {
 	IsIncluded(string) bool
 	IsExcluded(string) bool
@@ -17,15 +17,68 @@ limitations under the License.
 package metricsstore
 
 import (
+	"bytes"
 	"io"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	"k8s.io/kube-state-metrics/pkg/metric"
 )
 
+// MetricsWriter is implemented by stores able to write the metrics of all their
+// objects, which the metrics handler serves.
+type MetricsWriter interface {
+	WriteAll(w io.Writer)
+}
+
+// OpenMetricsWriter is implemented by stores able to write the metrics of all
+// their objects in the OpenMetrics text format.
+type OpenMetricsWriter interface {
+	WriteAllOpenMetrics(w io.Writer)
+}
+
+// FilterWriter is implemented by stores able to write a subset of their
+// metrics.
+type FilterWriter interface {
+	FamilyNames() []string
+	WriteFiltered(w io.Writer, openMetrics bool, filter Filter)
+}
+
+// GenerationGetter is implemented by stores counting the changes of their
+// objects, so that unchanged metrics need not be written again.
+type GenerationGetter interface {
+	Generation() uint64
+}
+
+// Filter selects the metrics written by FilterWriter.WriteFiltered().
+type Filter struct {
+	// Family returns whether the metric family of the given name is written.
+	// A nil Family selects all metric families.
+	Family func(familyName string) bool
+	// Namespaces are the namespaces the objects of which are written, the
+	// empty namespace standing for cluster-scoped objects. Nil Namespaces
+	// select the objects of all namespaces.
+	Namespaces []string
+}
+
+// Gauge is implemented by gauges the number of objects of a MetricsStore is
+// exposed with, e.g. prometheus.Gauge.
+type Gauge interface {
+	Set(float64)
+}
+
+// Counter is implemented by counters the objects skipped by a MetricsStore
+// are counted with, e.g. prometheus.Counter.
+type Counter interface {
+	Inc()
+}
+
 // MetricsStore implements the k8s.io/client-go/tools/cache.Store
 // interface. Instead of storing entire Kubernetes objects, it stores metrics
 // generated based on those objects.
@@ -37,10 +90,54 @@ type MetricsStore struct {
 	// grouped by metric families in order to zip families with their help text in
 	// MetricsStore.WriteAll().
 	metrics map[types.UID][][]byte
+	// namespaces indexes the ids of the objects in metrics by their
+	// namespace. It allows replacing the objects of a single namespace without
+	// touching the others, see NamespacedStore.
+	namespaces map[string]map[types.UID]struct{}
+	// keys contains the key of each object in metrics, by which the objects
+	// are ordered when written.
+	keys map[types.UID]objectKey
+	// generation is incremented on every change of the objects, under the
+	// same write lock as the change itself, invalidating order. It is
+	// protected by mutex, see MetricsStore.Generation().
+	generation uint64
+	// orderMutex protects order and orderGeneration. They are rebuilt by writers
+	// only holding mutex for reading.
+	orderMutex sync.Mutex
+	// order contains the keys of all objects, sorted. It is rebuilt lazily
+	// from keys when written after the objects changed, so that sorting does
+	// not slow down adding objects and unchanged objects are not re-sorted on
+	// every scrape.
+	order           []objectKey
+	orderGeneration uint64
+	// synced tracks for each reflector feeding the store, identified by the
+	// namespace it watches, whether its initial list was processed.
+	synced map[string]bool
 	// headers contains the header (TYPE and HELP) of each metric family. It is
 	// later on zipped with with their corresponding metric families in
 	// MetricStore.WriteAll().
 	headers []string
+	// familyNames contains the name of each metric family, in the order of
+	// headers. It is extracted from the HELP line of the headers.
+	familyNames []string
+	// familyOrder contains the indices of the metric families in headers,
+	// sorted by the names of the families.
+	familyOrder []int
+	// openMetricsHeaders contains the header of each metric family in the
+	// OpenMetrics text format, see MetricsStore.WithOpenMetricsHeaders().
+	openMetricsHeaders []string
+	// customLabelKeys and customLabelValues are appended to the labels of
+	// every metric, see MetricsStore.WithCustomLabels().
+	customLabelKeys   []string
+	customLabelValues []string
+	// objectsGauge is set to the number of objects on every change, see
+	// MetricsStore.WithObjectsGauge().
+	objectsGauge Gauge
+	// skip returns whether no metrics are generated for an object, which is
+	// then treated as deleted and counted by skippedCounter, see
+	// MetricsStore.WithSkip().
+	skip           func(metav1.Object) bool
+	skippedCounter Counter
 
 	// generateMetricsFunc generates metrics based on a given Kubernetes object
 	// and returns them grouped by metric family.
@@ -49,15 +146,131 @@ type MetricsStore struct {
 
 // NewMetricsStore returns a new MetricsStore
 func NewMetricsStore(headers []string, generateFunc func(interface{}) []metric.FamilyInterface) *MetricsStore {
+	names := familyNames(headers)
 	return &MetricsStore{
 		generateMetricsFunc: generateFunc,
 		headers:             headers,
+		familyNames:         names,
+		familyOrder:         familyOrder(names),
 		metrics:             map[types.UID][][]byte{},
+		namespaces:          map[string]map[types.UID]struct{}{},
+		keys:                map[types.UID]objectKey{},
+		synced:              map[string]bool{},
+	}
+}
+
+// objectKey identifies an object of the MetricsStore. Objects are written
+// ordered by namespace, name and finally uid.
+type objectKey struct {
+	namespace string
+	name      string
+	uid       types.UID
+}
+
+func (k objectKey) less(o objectKey) bool {
+	if k.namespace != o.namespace {
+		return k.namespace < o.namespace
+	}
+	if k.name != o.name {
+		return k.name < o.name
+	}
+	return k.uid < o.uid
+}
+
+// familyOrder returns the indices of the given family names, sorted by name.
+func familyOrder(names []string) []int {
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return names[order[i]] < names[order[j]]
+	})
+	return order
+}
+
+// familyNames returns the name of the metric family of each of the given
+// headers, or an empty string for headers without a HELP line.
+func familyNames(headers []string) []string {
+	names := make([]string, len(headers))
+	for i, h := range headers {
+		if !strings.HasPrefix(h, "# HELP ") {
+			continue
+		}
+		h = h[len("# HELP "):]
+		if j := strings.IndexAny(h, " \n"); j >= 0 {
+			h = h[:j]
+		}
+		names[i] = h
+	}
+	return names
+}
+
+// WithCustomLabels configures static labels appended to the labels of every
+// metric of the MetricsStore, unless a metric already has a label of the same
+// name. It has to be called before any object is added.
+func (s *MetricsStore) WithCustomLabels(keys, values []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.customLabelKeys = keys
+	s.customLabelValues = values
+}
+
+// WithOpenMetricsHeaders configures the headers of the metric families in the
+// OpenMetrics text format, enabling MetricsStore.WriteAllOpenMetrics(). They
+// have to be in the same order as the headers given to NewMetricsStore.
+func (s *MetricsStore) WithOpenMetricsHeaders(headers []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.openMetricsHeaders = headers
+}
+
+// WithObjectsGauge configures a gauge set to the number of objects of the
+// MetricsStore whenever they change. It is set under the same lock as the
+// change, so that concurrent reflectors of a NamespacedStore never leave it at
+// a stale value.
+func (s *MetricsStore) WithObjectsGauge(g Gauge) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.objectsGauge = g
+	s.setObjectsGauge()
+}
+
+// setObjectsGauge sets the objects gauge, if any, to the number of objects.
+// The caller has to hold the mutex for writing.
+func (s *MetricsStore) setObjectsGauge() {
+	if s.objectsGauge != nil {
+		s.objectsGauge.Set(float64(len(s.metrics)))
 	}
 }
 
 // Implementing k8s.io/client-go/tools/cache.Store interface
 
+// WithSkip sets the function returning whether no metrics are generated for
+// an object. Skipped objects are not held by the store, adding or updating an
+// object that became skipped deletes its metrics, updating it once no longer
+// skipped adds them again. Each skipped object is counted by the given
+// counter, if not nil, on every add, update and list.
+func (s *MetricsStore) WithSkip(skip func(metav1.Object) bool, skipped Counter) {
+	s.skip = skip
+	s.skippedCounter = skipped
+}
+
+// skipped returns whether no metrics are generated for the given object,
+// counting it if so.
+func (s *MetricsStore) skipped(o metav1.Object) bool {
+	if s.skip == nil || !s.skip(o) {
+		return false
+	}
+	if s.skippedCounter != nil {
+		s.skippedCounter.Inc()
+	}
+	return true
+}
+
 // Add inserts adds to the MetricsStore by calling the metrics generator functions and
 // adding the generated metrics to the metrics map that underlies the MetricStore.
 func (s *MetricsStore) Add(obj interface{}) error {
@@ -66,19 +279,74 @@ func (s *MetricsStore) Add(obj interface{}) error {
 		return err
 	}
 
+	if s.skipped(o) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		s.delete(o)
+		s.setObjectsGauge()
+		return nil
+	}
+
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.add(o, s.generate(obj))
+	s.setObjectsGauge()
+
+	return nil
+}
+
+// generate returns the rendered metric families of the given object. All
+// families of an object are rendered in one pass from the same version of the
+// object. The caller has to hold the mutex, at least for reading.
+func (s *MetricsStore) generate(obj interface{}) [][]byte {
 	families := s.generateMetricsFunc(obj)
 	familyStrings := make([][]byte, len(families))
 
 	for i, f := range families {
-		familyStrings[i] = f.ByteSlice()
+		if len(s.customLabelKeys) > 0 {
+			f.Inspect(func(family metric.Family) {
+				for _, m := range family.Metrics {
+					m.AppendLabels(s.customLabelKeys, s.customLabelValues)
+				}
+			})
+		}
+		familyStrings[i] = sortLines(f.ByteSlice())
+	}
+
+	return familyStrings
+}
+
+// sortLines sorts the metrics of a rendered metric family, one per line, so
+// that the metrics of an object are ordered by their labels. Generators
+// ranging over maps do not generate metrics in a deterministic order.
+func sortLines(family []byte) []byte {
+	lines := bytes.SplitAfter(family, []byte{'\n'})
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
 	}
+	less := func(i, j int) bool { return bytes.Compare(lines[i], lines[j]) < 0 }
+	if sort.SliceIsSorted(lines, less) {
+		return family
+	}
+	sort.Slice(lines, less)
+	return bytes.Join(lines, nil)
+}
 
+// add stores the given rendered metric families of the given object. The
+// caller has to hold the mutex for writing.
+func (s *MetricsStore) add(o metav1.Object, familyStrings [][]byte) {
 	s.metrics[o.GetUID()] = familyStrings
+	s.keys[o.GetUID()] = objectKey{namespace: o.GetNamespace(), name: o.GetName(), uid: o.GetUID()}
+	s.generation++
 
-	return nil
+	uids, ok := s.namespaces[o.GetNamespace()]
+	if !ok {
+		uids = map[types.UID]struct{}{}
+		s.namespaces[o.GetNamespace()] = uids
+	}
+	uids[o.GetUID()] = struct{}{}
 }
 
 // Update updates the existing entry in the MetricsStore.
@@ -98,11 +366,27 @@ func (s *MetricsStore) Delete(obj interface{}) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	delete(s.metrics, o.GetUID())
+	s.delete(o)
+	s.setObjectsGauge()
 
 	return nil
 }
 
+// delete removes the metrics of the given object. The caller has to hold the
+// mutex for writing.
+func (s *MetricsStore) delete(o metav1.Object) {
+	delete(s.metrics, o.GetUID())
+	delete(s.keys, o.GetUID())
+	s.generation++
+
+	if uids, ok := s.namespaces[o.GetNamespace()]; ok {
+		delete(uids, o.GetUID())
+		if len(uids) == 0 {
+			delete(s.namespaces, o.GetNamespace())
+		}
+	}
+}
+
 // List implements the List method of the store interface.
 func (s *MetricsStore) List() []interface{} {
 	return nil
@@ -126,36 +410,328 @@ func (s *MetricsStore) GetByKey(key string) (item interface{}, exists bool, err
 // Replace will delete the contents of the store, using instead the
 // given list.
 func (s *MetricsStore) Replace(list []interface{}, _ string) error {
-	s.mutex.Lock()
-	s.metrics = map[types.UID][][]byte{}
-	s.mutex.Unlock()
+	return s.replace(metav1.NamespaceAll, list)
+}
+
+// replace deletes the objects of the given namespace, or all objects in case
+// of metav1.NamespaceAll, and adds the given list instead. The metrics of the
+// list are generated before the objects are swapped under a single write
+// lock, so that scrapes see either the previous or the new objects, never a
+// partially replaced namespace.
+func (s *MetricsStore) replace(namespace string, list []interface{}) error {
+	objects := make([]metav1.Object, 0, len(list))
+	familyStrings := make([][][]byte, 0, len(list))
 
-	for _, o := range list {
-		err := s.Add(o)
+	s.mutex.RLock()
+	for _, obj := range list {
+		o, err := meta.Accessor(obj)
 		if err != nil {
+			s.mutex.RUnlock()
 			return err
 		}
+		if s.skipped(o) {
+			continue
+		}
+		objects = append(objects, o)
+		familyStrings = append(familyStrings, s.generate(obj))
 	}
+	s.mutex.RUnlock()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if namespace == metav1.NamespaceAll {
+		s.metrics = map[types.UID][][]byte{}
+		s.namespaces = map[string]map[types.UID]struct{}{}
+		s.keys = map[types.UID]objectKey{}
+	} else {
+		for uid := range s.namespaces[namespace] {
+			delete(s.metrics, uid)
+			delete(s.keys, uid)
+		}
+		delete(s.namespaces, namespace)
+	}
+	s.generation++
+	for i, o := range objects {
+		s.add(o, familyStrings[i])
+	}
+	s.synced[namespace] = true
+	s.setObjectsGauge()
 
 	return nil
 }
 
+// HasSynced returns whether the initial list of every reflector feeding the
+// MetricsStore was processed.
+func (s *MetricsStore) HasSynced() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if len(s.synced) == 0 {
+		return false
+	}
+	for _, synced := range s.synced {
+		if !synced {
+			return false
+		}
+	}
+	return true
+}
+
+// Generation returns the generation of the objects of the store, which is
+// incremented on every Add, Update, Delete and Replace. Metrics written while
+// the store is at a generation are identical to those written at any later
+// time the store is still at the same generation.
+func (s *MetricsStore) Generation() uint64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.generation
+}
+
 // Resync implements the Resync method of the store interface.
 func (s *MetricsStore) Resync() error {
 	return nil
 }
 
 // WriteAll writes all metrics of the store into the given writer, zipped with the
-// help text of each metric family.
+// help text of each metric family. The store is read-locked for the whole write,
+// so that all metric families are written from the same snapshot of objects:
+// an object is either present in all of its metric families or in none.
+// Metric families are written ordered by name, their metrics ordered by the
+// namespace and name of their object and then by their labels, so that the
+// output of two scrapes of the same objects is identical. Writing stops at the
+// first error returned by the writer.
 func (s *MetricsStore) WriteAll(w io.Writer) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	for i, help := range s.headers {
-		w.Write([]byte(help))
-		w.Write([]byte{'\n'})
-		for _, metricFamilies := range s.metrics {
-			w.Write(metricFamilies[i])
+	s.writeAll(w, s.headers)
+}
+
+// WriteAllOpenMetrics writes all metrics of the store into the given writer in
+// the OpenMetrics text format, without the terminating EOF. It falls back to
+// the Prometheus text format if no OpenMetrics headers were configured.
+func (s *MetricsStore) WriteAllOpenMetrics(w io.Writer) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if len(s.openMetricsHeaders) != len(s.headers) {
+		s.writeAll(w, s.headers)
+		return
+	}
+	s.writeAll(w, s.openMetricsHeaders)
+}
+
+// FamilyNames returns the names of the metric families of the store.
+func (s *MetricsStore) FamilyNames() []string {
+	return s.familyNames
+}
+
+// WriteFiltered writes the metrics selected by the given filter into the given
+// writer, in the OpenMetrics text format if openMetrics is set and OpenMetrics
+// headers were configured. Objects of other namespaces are looked up in the
+// namespace index and skipped without being written, same as other metric
+// families.
+func (s *MetricsStore) WriteFiltered(w io.Writer, openMetrics bool, filter Filter) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	headers := s.headers
+	if openMetrics && len(s.openMetricsHeaders) == len(s.headers) {
+		headers = s.openMetricsHeaders
+	}
+	s.writeFiltered(w, headers, filter)
+}
+
+// writeAll writes the metrics zipped with the given headers. The samples are
+// the same in the Prometheus and the OpenMetrics text format.
+func (s *MetricsStore) writeAll(w io.Writer, headers []string) {
+	s.writeFiltered(w, headers, Filter{})
+}
+
+// writeFiltered writes the metrics selected by the given filter zipped with
+// the given headers. Writing stops at the first error returned by the
+// writer, e.g. once the scrape was aborted. The caller has to hold the mutex,
+// at least for reading.
+func (s *MetricsStore) writeFiltered(w io.Writer, headers []string, filter Filter) {
+	order := s.sortedKeys()
+	if filter.Namespaces != nil {
+		order = filterNamespaces(order, filter.Namespaces)
+	}
+
+	for _, i := range s.familyOrder {
+		if filter.Family != nil && !filter.Family(s.familyNames[i]) {
+			continue
+		}
+		if _, err := io.WriteString(w, headers[i]+"\n"); err != nil {
+			return
 		}
+		for _, k := range order {
+			if _, err := w.Write(s.metrics[k.uid][i]); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sortedKeys returns the sorted keys of all objects, rebuilding them if the
+// objects changed since they were last sorted. The caller has to hold the
+// mutex, at least for reading, and must not modify the returned keys.
+func (s *MetricsStore) sortedKeys() []objectKey {
+	s.orderMutex.Lock()
+	defer s.orderMutex.Unlock()
+
+	if s.orderGeneration == s.generation && len(s.order) == len(s.keys) {
+		return s.order
+	}
+
+	order := make([]objectKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		order = append(order, k)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i].less(order[j]) })
+
+	s.order = order
+	s.orderGeneration = s.generation
+	return order
+}
+
+// filterNamespaces returns the given sorted keys of the objects of the given
+// namespaces. The keys of a namespace are adjacent, so that they are looked
+// up without walking the objects of other namespaces.
+func filterNamespaces(order []objectKey, namespaces []string) []objectKey {
+	sorted := append([]string(nil), namespaces...)
+	sort.Strings(sorted)
+
+	var filtered []objectKey
+	for i, ns := range sorted {
+		if i > 0 && ns == sorted[i-1] {
+			continue
+		}
+		start := sort.Search(len(order), func(j int) bool { return order[j].namespace >= ns })
+		end := start + sort.Search(len(order)-start, func(j int) bool { return order[start+j].namespace > ns })
+		filtered = append(filtered, order[start:end]...)
+	}
+	return filtered
+}
+
+// ForEach calls fn with the labels and value of every metric of the metric
+// family with the given name. The metrics are read from the cached exposition
+// of each object under the read lock, so that neither adding objects nor the
+// memory usage of the store are affected. It is safe to call concurrently with
+// updates and scrapes, but fn must not call back into the MetricsStore.
+func (s *MetricsStore) ForEach(familyName string, fn func(labels map[string]string, value float64)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	name := []byte(familyName)
+	for _, metricFamilies := range s.metrics {
+		for _, family := range metricFamilies {
+			if !hasMetricName(family, name) {
+				continue
+			}
+			for len(family) > 0 {
+				var line []byte
+				if i := bytes.IndexByte(family, '\n'); i >= 0 {
+					line, family = family[:i], family[i+1:]
+				} else {
+					line, family = family, nil
+				}
+				if labels, value, ok := parseMetric(line[len(name):]); ok {
+					fn(labels, value)
+				}
+			}
+		}
+	}
+}
+
+// hasMetricName returns whether the given metric family exposition starts
+// with a metric of the given name.
+func hasMetricName(family, name []byte) bool {
+	if !bytes.HasPrefix(family, name) || len(family) == len(name) {
+		return false
+	}
+	c := family[len(name)]
+	return c == '{' || c == ' '
+}
+
+// parseMetric parses the labels and value of a single metric line of the text
+// exposition format, with the metric name already stripped off.
+func parseMetric(line []byte) (map[string]string, float64, bool) {
+	labels := map[string]string{}
+	if len(line) > 0 && line[0] == '{' {
+		line = line[1:]
+		for len(line) > 0 && line[0] != '}' {
+			i := bytes.Index(line, []byte(`="`))
+			if i < 0 {
+				return nil, 0, false
+			}
+			key := string(line[:i])
+			line = line[i+2:]
+
+			value := make([]byte, 0, len(line))
+			for len(line) > 0 && line[0] != '"' {
+				if line[0] == '\\' && len(line) > 1 {
+					switch line[1] {
+					case 'n':
+						value = append(value, '\n')
+					default:
+						value = append(value, line[1])
+					}
+					line = line[2:]
+					continue
+				}
+				value = append(value, line[0])
+				line = line[1:]
+			}
+			if len(line) == 0 {
+				return nil, 0, false
+			}
+			labels[key] = string(value)
+
+			line = line[1:]
+			if len(line) > 0 && line[0] == ',' {
+				line = line[1:]
+			}
+		}
+		if len(line) == 0 {
+			return nil, 0, false
+		}
+		line = line[1:]
+	}
+
+	value, err := strconv.ParseFloat(string(bytes.TrimSpace(line)), 64)
+	if err != nil {
+		return nil, 0, false
 	}
+	return labels, value, true
+}
+
+// NamespacedStore wraps a MetricsStore that is shared by multiple reflectors,
+// each of them watching a single namespace. Replace only deletes the objects
+// of its own namespace, so that a relist in one namespace does not drop the
+// metrics of all others.
+type NamespacedStore struct {
+	*MetricsStore
+	namespace string
+}
+
+// NewNamespacedStore returns a new NamespacedStore for the given namespace,
+// backed by the given MetricsStore.
+func NewNamespacedStore(s *MetricsStore, namespace string) *NamespacedStore {
+	s.mutex.Lock()
+	s.synced[namespace] = false
+	s.mutex.Unlock()
+
+	return &NamespacedStore{
+		MetricsStore: s,
+		namespace:    namespace,
+	}
+}
+
+// Replace will delete the contents of the store belonging to the namespace
+// of the NamespacedStore, using instead the given list.
+func (s *NamespacedStore) Replace(list []interface{}, _ string) error {
+	return s.replace(s.namespace, list)
 }
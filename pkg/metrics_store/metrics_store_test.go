@@ -18,8 +18,15 @@ package metricsstore
 
 import (
 	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -90,3 +97,865 @@ func TestObjectsSameNameDifferentNamespaces(t *testing.T) {
 		}
 	}
 }
+
+func TestNamespacedStoreReplace(t *testing.T) {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		metricFamily := metric.Family{
+			Name: "kube_service_info",
+			Metrics: []*metric.Metric{
+				{
+					LabelKeys:   []string{"namespace", "uid"},
+					LabelValues: []string{o.GetNamespace(), string(o.GetUID())},
+					Value:       float64(1),
+				},
+			},
+		}
+
+		return []metric.FamilyInterface{&metricFamily}
+	}
+
+	service := func(namespace, uid string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "service",
+				Namespace: namespace,
+				UID:       types.UID(uid),
+			},
+		}
+	}
+
+	ms := NewMetricsStore([]string{"Information about service."}, genFunc)
+	a := NewNamespacedStore(ms, "a")
+	b := NewNamespacedStore(ms, "b")
+
+	if err := a.Replace([]interface{}{service("a", "a1"), service("a", "a2")}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if ms.HasSynced() {
+		t.Error("expected store not to be synced before all namespaces were listed")
+	}
+	if err := b.Replace([]interface{}{service("b", "b1")}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !ms.HasSynced() {
+		t.Error("expected store to be synced after all namespaces were listed")
+	}
+	// A relist in namespace a must not touch the objects of namespace b.
+	if err := a.Replace([]interface{}{service("a", "a3")}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	w := strings.Builder{}
+	ms.WriteAll(&w)
+	m := w.String()
+
+	for _, uid := range []string{"a3", "b1"} {
+		if !strings.Contains(m, fmt.Sprintf("uid=\"%v\"", uid)) {
+			t.Errorf("expected to find metric with uid %v, got:\n%v", uid, m)
+		}
+	}
+	for _, uid := range []string{"a1", "a2"} {
+		if strings.Contains(m, fmt.Sprintf("uid=\"%v\"", uid)) {
+			t.Errorf("expected metric with uid %v to be replaced, got:\n%v", uid, m)
+		}
+	}
+}
+
+// gauge records the value it was last set to.
+type gauge float64
+
+func (g *gauge) Set(v float64) {
+	*g = gauge(v)
+}
+
+// TestUpdateReplacesSeries ensures that an update of an object changing only
+// a label value, like the container ID of a restarted container, replaces the
+// series of the object instead of adding a second one.
+func TestUpdateReplacesSeries(t *testing.T) {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		p := obj.(*v1.Pod)
+		ms := make([]*metric.Metric, len(p.Status.ContainerStatuses))
+		for i, cs := range p.Status.ContainerStatuses {
+			ms[i] = &metric.Metric{
+				LabelKeys:   []string{"container", "container_id"},
+				LabelValues: []string{cs.Name, cs.ContainerID},
+				Value:       1,
+			}
+		}
+		return []metric.FamilyInterface{&metric.Family{Name: "kube_pod_container_info", Metrics: ms}}
+	}
+	pod := func(containerID string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pod",
+				Namespace: "ns",
+				UID:       types.UID("uid"),
+			},
+			Status: v1.PodStatus{
+				ContainerStatuses: []v1.ContainerStatus{
+					{Name: "container", ContainerID: containerID},
+				},
+			},
+		}
+	}
+
+	ms := NewMetricsStore([]string{"# HELP kube_pod_container_info Information about a container in a pod."}, genFunc)
+	if err := ms.Add(pod("docker://ab123")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ms.Update(pod("docker://cd456")); err != nil {
+		t.Fatal(err)
+	}
+
+	w := strings.Builder{}
+	ms.WriteAll(&w)
+	want := `# HELP kube_pod_container_info Information about a container in a pod.
+kube_pod_container_info{container="container",container_id="docker://cd456"} 1
+`
+	if got := w.String(); got != want {
+		t.Errorf("expected metrics\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestObjectsGauge(t *testing.T) {
+	service := func(namespace, uid string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uid,
+				Namespace: namespace,
+				UID:       types.UID(uid),
+			},
+		}
+	}
+
+	ms := NewMetricsStore(nil, func(interface{}) []metric.FamilyInterface { return nil })
+	var objects gauge = -1
+	ms.WithObjectsGauge(&objects)
+	a := NewNamespacedStore(ms, "a")
+	b := NewNamespacedStore(ms, "b")
+
+	steps := []struct {
+		desc string
+		do   func() error
+		want gauge
+	}{
+		{desc: "empty", do: func() error { return nil }, want: 0},
+		{desc: "list a", do: func() error { return a.Replace([]interface{}{service("a", "a1"), service("a", "a2")}, "") }, want: 2},
+		{desc: "list b", do: func() error { return b.Replace([]interface{}{service("b", "b1")}, "") }, want: 3},
+		{desc: "add", do: func() error { return b.Add(service("b", "b2")) }, want: 4},
+		{desc: "update", do: func() error { return b.Update(service("b", "b2")) }, want: 4},
+		{desc: "delete", do: func() error { return a.Delete(service("a", "a1")) }, want: 3},
+		{desc: "shrinking relist", do: func() error { return b.Replace(nil, "") }, want: 1},
+		{desc: "relist all", do: func() error { return ms.Replace([]interface{}{service("a", "a3")}, "") }, want: 1},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatal(err)
+		}
+		if objects != step.want {
+			t.Errorf("%s: expected %v objects, got %v", step.desc, step.want, objects)
+		}
+	}
+}
+
+type counter float64
+
+func (c *counter) Inc() {
+	*c++
+}
+
+func TestSkip(t *testing.T) {
+	service := func(uid string, skip bool) *v1.Service {
+		s := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uid,
+				Namespace: "ns",
+				UID:       types.UID(uid),
+			},
+		}
+		if skip {
+			s.Annotations = map[string]string{"skip": "true"}
+		}
+		return s
+	}
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []metric.FamilyInterface{&metric.Family{
+			Name:    "kube_service_info",
+			Metrics: []*metric.Metric{{LabelKeys: []string{"service"}, LabelValues: []string{o.GetName()}, Value: 1}},
+		}}
+	}
+
+	ms := NewMetricsStore([]string{"# HELP kube_service_info Information about service."}, genFunc)
+	var objects gauge
+	var skipped counter
+	ms.WithObjectsGauge(&objects)
+	ms.WithSkip(func(o metav1.Object) bool { return o.GetAnnotations()["skip"] == "true" }, &skipped)
+
+	steps := []struct {
+		desc        string
+		do          func() error
+		wantObjects gauge
+		wantSkipped counter
+		want        []string
+	}{
+		{desc: "add", do: func() error { return ms.Add(service("a", false)) }, wantObjects: 1, want: []string{"a"}},
+		{desc: "add skipped", do: func() error { return ms.Add(service("b", true)) }, wantObjects: 1, wantSkipped: 1, want: []string{"a"}},
+		{desc: "annotate", do: func() error { return ms.Update(service("a", true)) }, wantObjects: 0, wantSkipped: 2},
+		{desc: "remove annotation", do: func() error { return ms.Update(service("a", false)) }, wantObjects: 1, wantSkipped: 2, want: []string{"a"}},
+		{
+			desc: "relist",
+			do: func() error {
+				return ms.Replace([]interface{}{service("a", true), service("b", true), service("c", false)}, "")
+			},
+			wantObjects: 1,
+			wantSkipped: 4,
+			want:        []string{"c"},
+		},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatal(err)
+		}
+		if objects != step.wantObjects || skipped != step.wantSkipped {
+			t.Errorf("%s: expected %v objects and %v skipped, got %v and %v", step.desc, step.wantObjects, step.wantSkipped, objects, skipped)
+		}
+
+		w := strings.Builder{}
+		ms.WriteAll(&w)
+		want := "# HELP kube_service_info Information about service.\n"
+		for _, name := range step.want {
+			want += fmt.Sprintf("kube_service_info{service=%q} 1\n", name)
+		}
+		if got := w.String(); got != want {
+			t.Errorf("%s: expected metrics\n%s\ngot\n%s", step.desc, want, got)
+		}
+	}
+}
+
+func TestCustomLabels(t *testing.T) {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		metricFamily := metric.Family{
+			Name: "kube_service_info",
+			Metrics: []*metric.Metric{
+				{
+					LabelKeys:   []string{"namespace", "uid"},
+					LabelValues: []string{o.GetNamespace(), string(o.GetUID())},
+					Value:       float64(1),
+				},
+				{
+					LabelKeys:   []string{"namespace", "region"},
+					LabelValues: []string{o.GetNamespace(), "us-east-1"},
+					Value:       float64(1),
+				},
+			},
+		}
+
+		return []metric.FamilyInterface{&metricFamily}
+	}
+
+	ms := NewMetricsStore([]string{"Information about service."}, genFunc)
+	ms.WithCustomLabels([]string{"cluster", "env", "region"}, []string{"prod", "staging", "eu-west-1"})
+
+	svc := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "service",
+			Namespace: "a",
+			UID:       types.UID("a1"),
+		},
+	}
+	if err := ms.Add(&svc); err != nil {
+		t.Fatal(err)
+	}
+
+	w := strings.Builder{}
+	ms.WriteAll(&w)
+	m := w.String()
+
+	for _, want := range []string{
+		`kube_service_info{namespace="a",uid="a1",cluster="prod",env="staging",region="eu-west-1"} 1`,
+		`kube_service_info{namespace="a",region="us-east-1",cluster="prod",env="staging"} 1`,
+	} {
+		if !strings.Contains(m, want) {
+			t.Errorf("expected to find %s, got:\n%v", want, m)
+		}
+	}
+}
+
+func TestForEach(t *testing.T) {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return []metric.FamilyInterface{
+			&metric.Family{
+				Name: "kube_service_info",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "uid"},
+						LabelValues: []string{o.GetNamespace(), string(o.GetUID())},
+						Value:       float64(1),
+					},
+				},
+			},
+			&metric.Family{
+				Name: "kube_service_info_annotation",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "annotation"},
+						LabelValues: []string{o.GetNamespace(), "a\"b\\c\nd"},
+						Value:       float64(2.5),
+					},
+				},
+			},
+		}
+	}
+
+	ms := NewMetricsStore([]string{"Information about service.", "Annotations of service."}, genFunc)
+	ms.WithCustomLabels([]string{"cluster"}, []string{"prod"})
+
+	for _, uid := range []string{"a1", "a2"} {
+		svc := v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "service-" + uid,
+				Namespace: "a",
+				UID:       types.UID(uid),
+			},
+		}
+		if err := ms.Add(&svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := map[string]float64{}
+	ms.ForEach("kube_service_info", func(labels map[string]string, value float64) {
+		if labels["namespace"] != "a" || labels["cluster"] != "prod" {
+			t.Errorf("unexpected labels %v", labels)
+		}
+		got[labels["uid"]] = value
+	})
+	if !reflect.DeepEqual(got, map[string]float64{"a1": 1, "a2": 1}) {
+		t.Errorf("expected a metric for each service, got %v", got)
+	}
+
+	count := 0
+	ms.ForEach("kube_service_info_annotation", func(labels map[string]string, value float64) {
+		count++
+		if labels["annotation"] != "a\"b\\c\nd" || value != 2.5 {
+			t.Errorf("expected unescaped label value and value 2.5, got %q and %v", labels["annotation"], value)
+		}
+	})
+	if count != 2 {
+		t.Errorf("expected 2 metrics, got %d", count)
+	}
+
+	ms.ForEach("kube_service", func(labels map[string]string, value float64) {
+		t.Errorf("expected no metrics for a prefix of a metric family name, got %v", labels)
+	})
+}
+
+func BenchmarkMetricsStoreAdd(b *testing.B) {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o := obj.(*v1.Service)
+		return []metric.FamilyInterface{
+			&metric.Family{
+				Name: "kube_service_info",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "service", "uid"},
+						LabelValues: []string{o.Namespace, o.Name, string(o.UID)},
+						Value:       float64(1),
+					},
+				},
+			},
+		}
+	}
+
+	services := make([]*v1.Service, 1000)
+	for i := range services {
+		services[i] = &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("service-%d", i),
+				Namespace: "default",
+				UID:       types.UID(fmt.Sprintf("uid-%d", i)),
+			},
+		}
+	}
+
+	bench := func(b *testing.B, ms *MetricsStore) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := ms.Add(services[i%len(services)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("without readers", func(b *testing.B) {
+		bench(b, NewMetricsStore([]string{"Information about service."}, genFunc))
+	})
+
+	b.Run("with concurrent ForEach", func(b *testing.B) {
+		ms := NewMetricsStore([]string{"Information about service."}, genFunc)
+		for _, s := range services {
+			if err := ms.Add(s); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					ms.ForEach("kube_service_info", func(map[string]string, float64) {})
+				}
+			}
+		}()
+
+		bench(b, ms)
+
+		close(done)
+		wg.Wait()
+	})
+}
+
+func BenchmarkMetricsStoreForEach(b *testing.B) {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o := obj.(*v1.Service)
+		return []metric.FamilyInterface{
+			&metric.Family{
+				Name: "kube_service_info",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "service", "uid"},
+						LabelValues: []string{o.Namespace, o.Name, string(o.UID)},
+						Value:       float64(1),
+					},
+				},
+			},
+		}
+	}
+
+	ms := NewMetricsStore([]string{"Information about service."}, genFunc)
+	for i := 0; i < 1000; i++ {
+		err := ms.Add(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("service-%d", i),
+				Namespace: "default",
+				UID:       types.UID(fmt.Sprintf("uid-%d", i)),
+			},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ms.ForEach("kube_service_info", func(map[string]string, float64) {})
+	}
+}
+
+func TestMetricsAreGeneratedOnEventsOnly(t *testing.T) {
+	generated := 0
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		generated++
+		o := obj.(*v1.Service)
+		return []metric.FamilyInterface{
+			&metric.Family{
+				Name: "kube_service_labels",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"service", "label_app"},
+						LabelValues: []string{o.Name, o.Labels["app"]},
+						Value:       float64(1),
+					},
+				},
+			},
+		}
+	}
+
+	ms := NewMetricsStore([]string{"Labels of service."}, genFunc)
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "service",
+			UID:    types.UID("a1"),
+			Labels: map[string]string{"app": "foo"},
+		},
+	}
+	if err := ms.Add(svc); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		ms.WriteAll(&strings.Builder{})
+	}
+	if generated != 1 {
+		t.Errorf("expected scrapes to write the cached metrics without generating them, got %d generations", generated)
+	}
+
+	// A label-only update fully replaces the cached metrics of the object.
+	updated := svc.DeepCopy()
+	updated.Labels["app"] = "bar"
+	if err := ms.Update(updated); err != nil {
+		t.Fatal(err)
+	}
+	w := strings.Builder{}
+	ms.WriteAll(&w)
+	if m := w.String(); !strings.Contains(m, `label_app="bar"`) || strings.Contains(m, `label_app="foo"`) {
+		t.Errorf("expected the update to replace the cached metrics, got:\n%v", m)
+	}
+
+	if err := ms.Delete(updated); err != nil {
+		t.Fatal(err)
+	}
+	w = strings.Builder{}
+	ms.WriteAll(&w)
+	if m := w.String(); strings.Contains(m, "kube_service_labels{") {
+		t.Errorf("expected the delete to remove the cached metrics, got:\n%v", m)
+	}
+}
+
+// TestObjectsAreNotRetained ensures that the store only retains the metrics of
+// objects, not the objects themselves, so that fields unused by the metric
+// families like managedFields are never cached.
+func TestObjectsAreNotRetained(t *testing.T) {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o := obj.(*v1.Service)
+		return []metric.FamilyInterface{
+			&metric.Family{
+				Name: "kube_service_info",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"service"},
+						LabelValues: []string{o.Name},
+						Value:       float64(1),
+					},
+				},
+			},
+		}
+	}
+
+	newService := func(managedFields []metav1.ManagedFieldsEntry) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:          "service",
+				UID:           types.UID("a1"),
+				ManagedFields: managedFields,
+			},
+		}
+	}
+	managedFields := []metav1.ManagedFieldsEntry{
+		{
+			Manager:    "kubectl",
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(strings.Repeat(`{"f:metadata":{}}`, 1000))},
+		},
+	}
+
+	plain := NewMetricsStore([]string{"Information about service."}, genFunc)
+	if err := plain.Add(newService(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	ms := NewMetricsStore([]string{"Information about service."}, genFunc)
+	finalized := make(chan struct{}, 2)
+	for i, f := range []func(obj interface{}) error{ms.Add, ms.Update} {
+		svc := newService(managedFields)
+		svc.ResourceVersion = fmt.Sprint(i)
+		runtime.SetFinalizer(svc, func(*v1.Service) { finalized <- struct{}{} })
+		if err := f(svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var want, got strings.Builder
+	plain.WriteAll(&want)
+	ms.WriteAll(&got)
+	if want.String() != got.String() {
+		t.Errorf("expected metrics not to depend on managedFields, want:\n%s\ngot:\n%s", want.String(), got.String())
+	}
+
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < 2; {
+		runtime.GC()
+		select {
+		case <-finalized:
+			i++
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("expected objects added to and updated in the store not to be retained")
+		}
+	}
+	runtime.KeepAlive(ms)
+}
+
+// TestScrapeConsistency ensures that each scrape writes all metric families
+// from the same snapshot of objects, while objects are concurrently added,
+// deleted and relisted.
+func TestScrapeConsistency(t *testing.T) {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o := obj.(*v1.Service)
+		return []metric.FamilyInterface{
+			&metric.Family{
+				Name: "kube_service_info",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "service"},
+						LabelValues: []string{o.Namespace, o.Name},
+						Value:       float64(1),
+					},
+				},
+			},
+			&metric.Family{
+				Name: "kube_service_labels",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "service"},
+						LabelValues: []string{o.Namespace, o.Name},
+						Value:       float64(1),
+					},
+				},
+			},
+		}
+	}
+	newService := func(namespace string, i int) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("service-%d", i),
+				Namespace: namespace,
+				UID:       types.UID(fmt.Sprintf("%s-%d", namespace, i)),
+			},
+		}
+	}
+
+	const relisted = 100
+	ms := NewMetricsStore([]string{"kube_service_info", "kube_service_labels"}, genFunc)
+	stable := NewNamespacedStore(ms, "stable")
+	churn := NewNamespacedStore(ms, "churn")
+	list := make([]interface{}, relisted)
+	for i := range list {
+		list[i] = newService("stable", i)
+	}
+	if err := stable.Replace(list, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if err := stable.Replace(list, ""); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if err := churn.Add(newService("churn", i)); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := churn.Delete(newService("churn", i-10)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for scrape := 0; scrape < 200; scrape++ {
+		w := strings.Builder{}
+		ms.WriteAll(&w)
+
+		services := map[string]map[string]struct{}{}
+		stableServices := 0
+		for _, line := range strings.Split(w.String(), "\n") {
+			i := strings.IndexByte(line, '{')
+			if i < 0 {
+				continue
+			}
+			family, labels := line[:i], line[i:]
+			if services[family] == nil {
+				services[family] = map[string]struct{}{}
+			}
+			services[family][labels] = struct{}{}
+			if family == "kube_service_info" && strings.Contains(labels, `namespace="stable"`) {
+				stableServices++
+			}
+		}
+
+		if !reflect.DeepEqual(services["kube_service_info"], services["kube_service_labels"]) {
+			t.Fatalf("expected the same services in all metric families of a scrape, got:\n%s", w.String())
+		}
+		if stableServices != relisted {
+			t.Fatalf("expected all %d relisted services in every scrape, got %d", relisted, stableServices)
+		}
+	}
+
+	close(done)
+	wg.Wait()
+}
+
+func BenchmarkMetricsStoreWriteAll(b *testing.B) {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o := obj.(*v1.Service)
+		return []metric.FamilyInterface{
+			&metric.Family{
+				Name: "kube_service_info",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "service", "uid"},
+						LabelValues: []string{o.Namespace, o.Name, string(o.UID)},
+						Value:       float64(1),
+					},
+				},
+			},
+		}
+	}
+
+	ms := NewMetricsStore([]string{"Information about service."}, genFunc)
+	for i := 0; i < 1000; i++ {
+		err := ms.Add(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("service-%d", i),
+				Namespace: "default",
+				UID:       types.UID(fmt.Sprintf("uid-%d", i)),
+			},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	// Scrapes only concatenate the metrics cached on Add, so their latency
+	// depends on the number of objects, not on how often they are scraped.
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ms.WriteAll(ioutil.Discard)
+	}
+}
+
+func TestDeterministicOrder(t *testing.T) {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o := obj.(*v1.Service)
+		labels := &metric.Family{Name: "kube_service_labels"}
+		// Ranging over the labels generates the metrics in random order.
+		for k, v := range o.Labels {
+			labels.Metrics = append(labels.Metrics, &metric.Metric{
+				LabelKeys:   []string{"namespace", "service", "key"},
+				LabelValues: []string{o.Namespace, o.Name, k},
+				Value:       float64(len(v)),
+			})
+		}
+		return []metric.FamilyInterface{
+			&metric.Family{
+				Name: "kube_service_info",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "service"},
+						LabelValues: []string{o.Namespace, o.Name},
+						Value:       float64(1),
+					},
+				},
+			},
+			labels,
+		}
+	}
+
+	var services []*v1.Service
+	for _, ns := range []string{"", "ns1", "ns2"} {
+		for i := 0; i < 20; i++ {
+			services = append(services, &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("service-%d", i),
+					Namespace: ns,
+					UID:       types.UID(fmt.Sprintf("%s-%d", ns, i)),
+					Labels:    map[string]string{"a": "1", "b": "22", "c": "333", "d": "4444"},
+				},
+			})
+		}
+	}
+	headers := []string{
+		"# HELP kube_service_info Information about service.",
+		"# HELP kube_service_labels Kubernetes labels converted to Prometheus labels.",
+	}
+
+	var want, wantFiltered string
+	for run := 0; run < 10; run++ {
+		ms := NewMetricsStore(headers, genFunc)
+		for _, i := range rand.Perm(len(services)) {
+			if err := ms.Add(services[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		w := strings.Builder{}
+		ms.WriteAll(&w)
+		filtered := strings.Builder{}
+		ms.WriteFiltered(&filtered, false, Filter{Namespaces: []string{"ns2", "ns1"}})
+
+		if run == 0 {
+			want, wantFiltered = w.String(), filtered.String()
+			var lines []string
+			for _, line := range strings.Split(want, "\n") {
+				if line != "" && !strings.HasPrefix(line, "#") {
+					lines = append(lines, line)
+				}
+			}
+			if len(lines) != 300 || !sort.StringsAreSorted(lines) {
+				t.Errorf("expected families and metrics to be sorted, got:\n%s", want)
+			}
+			continue
+		}
+		if got := w.String(); got != want {
+			t.Fatalf("run %d: expected identical output, want:\n%s\ngot:\n%s", run, want, got)
+		}
+		if got := filtered.String(); got != wantFiltered {
+			t.Fatalf("run %d: expected identical output of namespaces, want:\n%s\ngot:\n%s", run, wantFiltered, got)
+		}
+	}
+
+	if !strings.Contains(wantFiltered, `{namespace="ns1",service="service-0"}`) || strings.Contains(wantFiltered, `{namespace="",`) ||
+		strings.Index(wantFiltered, `namespace="ns1"`) > strings.Index(wantFiltered, `namespace="ns2"`) {
+		t.Errorf("expected the metrics of ns1 and ns2 in order, got:\n%s", wantFiltered)
+	}
+}
@@ -17,15 +17,21 @@ limitations under the License.
 package metricshandler
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
+	"fmt"
+	"hash/fnv"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -33,7 +39,8 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
 
-	"k8s.io/kube-state-metrics/internal/store"
+	"k8s.io/kube-state-metrics/pkg/builder"
+	"k8s.io/kube-state-metrics/pkg/metric"
 	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
 	"k8s.io/kube-state-metrics/pkg/options"
 )
@@ -43,47 +50,338 @@ import (
 type MetricsHandler struct {
 	opts               *options.Options
 	kubeClient         kubernetes.Interface
-	storeBuilder       *store.Builder
+	storeBuilder       *builder.Builder
 	enableGZIPEncoding bool
-
-	cancel func()
-
-	// mtx protects stores, curShard, and curTotalShards
-	mtx            *sync.RWMutex
-	stores         []cache.Store
+	// scrapeConcurrency is the number of stores written concurrently during a
+	// scrape, see ServeHTTP().
+	scrapeConcurrency int
+	// syncTimeout bounds waiting for rebuilt stores to sync, see
+	// buildStores().
+	syncTimeout time.Duration
+	// includeClusterScoped is set if scrapes filtered by namespace include
+	// cluster-scoped objects, see scrapeFilter().
+	includeClusterScoped bool
+	// scrapesAborted counts the scrapes aborted before all metrics were
+	// written, see WithMetrics().
+	scrapesAborted *prometheus.CounterVec
+	// shardOrdinal, totalShards and shardConfigHash expose the active
+	// sharding configuration, see WithMetrics().
+	shardOrdinal    *prometheus.GaugeVec
+	totalShards     prometheus.Gauge
+	shardConfigHash prometheus.Gauge
+
+	// buildMtx serializes the configuration of storeBuilder and the building
+	// of stores.
+	buildMtx sync.Mutex
+
+	// epoch identifies the process, so that entity tags of a previous process
+	// never match, see etag().
+	epoch string
+
+	// mtx protects stores, storesGeneration, pendingStores, curShard, and
+	// curTotalShards
+	mtx    *sync.RWMutex
+	stores []builtStore
+	// storesGeneration is incremented whenever stores are swapped.
+	storesGeneration uint64
+	// pendingStores are being built to replace stores, see buildStores().
+	pendingStores  []builtStore
 	curShard       int32
 	curTotalShards int
 }
 
+// builtStore is the store of a resource alongside the fingerprint of the
+// configuration it was built with and the function stopping its reflectors.
+type builtStore struct {
+	resource    string
+	fingerprint string
+	store       cache.Store
+	cancel      func()
+}
+
 // New creates and returns a new MetricsHandler with the given options.
-func New(opts *options.Options, kubeClient kubernetes.Interface, storeBuilder *store.Builder, enableGZIPEncoding bool) *MetricsHandler {
+func New(opts *options.Options, kubeClient kubernetes.Interface, storeBuilder *builder.Builder, enableGZIPEncoding bool) *MetricsHandler {
 	return &MetricsHandler{
-		opts:               opts,
-		kubeClient:         kubeClient,
-		storeBuilder:       storeBuilder,
-		enableGZIPEncoding: enableGZIPEncoding,
-		mtx:                &sync.RWMutex{},
+		opts:                 opts,
+		kubeClient:           kubeClient,
+		storeBuilder:         storeBuilder,
+		enableGZIPEncoding:   enableGZIPEncoding,
+		scrapeConcurrency:    opts.ScrapeConcurrency,
+		syncTimeout:          opts.ReloadSyncTimeout,
+		includeClusterScoped: opts.NamespaceFilterIncludeClusterScoped,
+		epoch:                strconv.FormatInt(time.Now().UnixNano(), 36),
+		mtx:                  &sync.RWMutex{},
+	}
+}
+
+// WithMetrics registers the metrics of the MetricsHandler itself with the
+// given registry.
+func (m *MetricsHandler) WithMetrics(r *prometheus.Registry) {
+	m.scrapesAborted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_state_metrics_scrapes_aborted_total",
+			Help: "Number of scrapes aborted before all metrics were written, as the client disconnected, the scrape timed out or writing failed.",
+		},
+		[]string{"reason"},
+	)
+	m.shardOrdinal = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_state_metrics_shard_ordinal",
+			Help: "Ordinal of the shard served by this instance, zero-indexed, given as value and label.",
+		},
+		[]string{"shard_ordinal"},
+	)
+	m.totalShards = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kube_state_metrics_total_shards",
+		Help: "Number of shards the objects are distributed across.",
+	})
+	m.shardConfigHash = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kube_state_metrics_shard_config_hash",
+		Help: "Hash of the configuration of the stores except for the shard, which is equal for all consistently configured shards.",
+	})
+	if r != nil {
+		r.MustRegister(m.scrapesAborted, m.shardOrdinal, m.totalShards, m.shardConfigHash)
 	}
 }
 
 // ConfigureSharding (re-)configures sharding. Re-configuration can be done
-// concurrently.
-func (m *MetricsHandler) ConfigureSharding(ctx context.Context, shard int32, totalShards int) {
-	m.mtx.Lock()
-	defer m.mtx.Unlock()
+// concurrently. It returns an error if the stores could not be built or did
+// not sync in time, in which case the previous sharding configuration is
+// restored and the current stores keep being served.
+func (m *MetricsHandler) ConfigureSharding(ctx context.Context, shard int32, totalShards int) error {
+	m.buildMtx.Lock()
+	defer m.buildMtx.Unlock()
 
-	if m.cancel != nil {
-		m.cancel()
-	}
 	if totalShards != 1 {
 		klog.Infof("configuring sharding of this instance to be shard index %d (zero-indexed) out of %d total shards", shard, totalShards)
 	}
-	ctx, m.cancel = context.WithCancel(ctx)
+	restore := m.storeBuilder.Snapshot()
 	m.storeBuilder.WithSharding(shard, totalShards)
-	m.storeBuilder.WithContext(ctx)
-	m.stores = m.storeBuilder.Build()
+	if err := m.buildStores(ctx); err != nil {
+		restore()
+		return err
+	}
+
+	m.mtx.Lock()
 	m.curShard = shard
 	m.curTotalShards = totalShards
+	m.mtx.Unlock()
+
+	if m.shardOrdinal != nil {
+		m.shardOrdinal.Reset()
+		m.shardOrdinal.WithLabelValues(strconv.Itoa(int(shard))).Set(float64(shard))
+		m.totalShards.Set(float64(totalShards))
+	}
+	m.setShardConfigHash()
+	return nil
+}
+
+// Reconfigure applies the given configuration function to the store builder
+// and rebuilds the stores. Until the new stores have synced, the previous ones
+// keep being served. If the configuration function fails, or the new stores
+// could not be built or did not sync in time, the previous configuration is
+// restored and the error is returned. Re-configuration can be done
+// concurrently.
+func (m *MetricsHandler) Reconfigure(ctx context.Context, configure func(*builder.Builder) error) error {
+	m.buildMtx.Lock()
+	defer m.buildMtx.Unlock()
+
+	restore := m.storeBuilder.Snapshot()
+	if err := configure(m.storeBuilder); err != nil {
+		restore()
+		return err
+	}
+	if err := m.buildStores(ctx); err != nil {
+		restore()
+		return err
+	}
+	m.setShardConfigHash()
+	return nil
+}
+
+// setShardConfigHash exposes the hash of the configuration of the current
+// stores except for the shard, so that the configurations of all shards can be
+// compared. The resources are the ones of the stores built by buildStores(),
+// so that the hash describes the served stores without discovering the
+// resources again. Callers must hold buildMtx.
+func (m *MetricsHandler) setShardConfigHash() {
+	if m.shardConfigHash == nil {
+		return
+	}
+	m.mtx.RLock()
+	resources := make([]string, 0, len(m.stores))
+	for _, s := range m.stores {
+		resources = append(resources, s.resource)
+	}
+	m.mtx.RUnlock()
+
+	h := fnv.New64a()
+	io.WriteString(h, m.storeBuilder.ShardConfigFingerprint(resources))
+	// Only the upper 48 bits are kept, as a float64 has a 53 bit mantissa.
+	m.shardConfigHash.Set(float64(h.Sum64() >> 16))
+}
+
+// buildStores builds the stores of all resources the configuration of which
+// changed and swaps them in for the current ones, which are stopped afterwards.
+// Stores of unaffected resources are kept running. Unless no stores were built
+// before, the swap is delayed until the new stores have synced, so that scrapes
+// never see partially filled stores. If any store could not be built or the
+// new stores did not sync within syncTimeout, the stores built so far are
+// stopped and the current ones are kept. Callers must hold buildMtx.
+func (m *MetricsHandler) buildStores(ctx context.Context) error {
+	resources := m.storeBuilder.ServedResources()
+
+	m.mtx.RLock()
+	initial := m.stores == nil
+	current := make(map[string]builtStore, len(m.stores))
+	for _, s := range m.stores {
+		current[s.resource] = s
+	}
+	m.mtx.RUnlock()
+
+	stores := make([]builtStore, 0, len(resources))
+	rebuilt := []builtStore{}
+	rebuiltResources := []string{}
+	for _, r := range resources {
+		fingerprint := m.storeBuilder.StoreFingerprint(r)
+		if s, ok := current[r]; ok && s.fingerprint == fingerprint {
+			stores = append(stores, s)
+			continue
+		}
+
+		storeCtx, cancel := context.WithCancel(ctx)
+		m.storeBuilder.WithContext(storeCtx)
+		store, err := m.storeBuilder.BuildStore(r)
+		if err != nil {
+			cancel()
+			for _, s := range rebuilt {
+				s.cancel()
+			}
+			return err
+		}
+		s := builtStore{
+			resource:    r,
+			fingerprint: fingerprint,
+			store:       store,
+			cancel:      cancel,
+		}
+		stores = append(stores, s)
+		rebuilt = append(rebuilt, s)
+		rebuiltResources = append(rebuiltResources, r)
+	}
+	klog.Infof("Active resources: %s", strings.Join(resources, ","))
+	if !initial && len(rebuilt) == 0 {
+		klog.Info("Configuration of all active resources unchanged, keeping their stores")
+	} else if !initial {
+		klog.Infof("Rebuilding stores of resources: %s", strings.Join(rebuiltResources, ","))
+	}
+
+	if !initial && len(rebuilt) > 0 {
+		m.mtx.Lock()
+		m.pendingStores = rebuilt
+		m.mtx.Unlock()
+
+		synced := make([]cache.InformerSynced, 0, len(rebuilt))
+		for _, s := range rebuilt {
+			synced = append(synced, hasSynced(s.store))
+		}
+		syncCtx := ctx
+		if m.syncTimeout > 0 {
+			var cancel func()
+			syncCtx, cancel = context.WithTimeout(ctx, m.syncTimeout)
+			defer cancel()
+		}
+		ok := cache.WaitForCacheSync(syncCtx.Done(), synced...)
+
+		m.mtx.Lock()
+		m.pendingStores = nil
+		m.mtx.Unlock()
+
+		if !ok {
+			for _, s := range rebuilt {
+				s.cancel()
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return errors.Errorf("stores of resources %s did not sync within %s", strings.Join(rebuiltResources, ","), m.syncTimeout)
+		}
+	}
+
+	m.mtx.Lock()
+	previous := m.stores
+	m.stores = stores
+	m.storesGeneration++
+	m.mtx.Unlock()
+
+	// Stop the stores which were replaced or whose resource is no longer
+	// enabled.
+	kept := make(map[cache.Store]struct{}, len(stores))
+	for _, s := range stores {
+		kept[s.store] = struct{}{}
+	}
+	for _, s := range previous {
+		if _, ok := kept[s.store]; !ok {
+			s.cancel()
+		}
+	}
+	return nil
+}
+
+// NotSyncedResources returns the resources the stores of which have not yet
+// completed their initial sync, including those being rebuilt. Before any
+// store was built, it returns nil and false.
+func (m *MetricsHandler) NotSyncedResources() ([]string, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if m.stores == nil {
+		return nil, false
+	}
+
+	notSynced := []string{}
+	for _, s := range m.stores {
+		if !hasSynced(s.store)() {
+			notSynced = append(notSynced, s.resource)
+		}
+	}
+	for _, s := range m.pendingStores {
+		if !hasSynced(s.store)() {
+			notSynced = append(notSynced, s.resource)
+		}
+	}
+	return notSynced, true
+}
+
+// hasSynced returns the HasSynced function of the given store. Stores not
+// tracking their sync state are considered synced.
+func hasSynced(s cache.Store) cache.InformerSynced {
+	if syncer, ok := s.(interface{ HasSynced() bool }); ok {
+		return syncer.HasSynced
+	}
+	return func() bool { return true }
+}
+
+// WriteMetrics writes the metrics of all stores in the Prometheus text format,
+// as pushed via remote-write. It writes nothing and returns false before the
+// stores were built or while any of them has not completed its initial sync,
+// so that incomplete metrics are never pushed.
+func (m *MetricsHandler) WriteMetrics(w io.Writer) bool {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if len(m.stores) == 0 {
+		return false
+	}
+	for _, s := range m.stores {
+		if !hasSynced(s.store)() {
+			return false
+		}
+	}
+	for _, s := range m.stores {
+		writeStore(w, s, false, scrapeFilter{})
+	}
+	return true
 }
 
 // Run configures the MetricsHandler's sharding and if autosharding is enabled
@@ -94,7 +392,9 @@ func (m *MetricsHandler) Run(ctx context.Context) error {
 
 	if !autoSharding {
 		klog.Info("Autosharding disabled")
-		m.ConfigureSharding(ctx, m.opts.Shard, m.opts.TotalShards)
+		if err := m.ConfigureSharding(ctx, m.opts.Shard, m.opts.TotalShards); err != nil {
+			return errors.Wrap(err, "configure sharding")
+		}
 		<-ctx.Done()
 		return ctx.Err()
 	}
@@ -111,6 +411,20 @@ func (m *MetricsHandler) Run(ctx context.Context) error {
 		o.LabelSelector = fields.SelectorFromSet(ss.Labels).String()
 	}
 
+	// Failing to build the stores of the first sharding settings is fatal, as
+	// there are no stores to keep serving.
+	configureSharding := func(shard int32, totalShards int) {
+		if err := m.ConfigureSharding(ctx, shard, totalShards); err != nil {
+			m.mtx.RLock()
+			initial := m.stores == nil
+			m.mtx.RUnlock()
+			if initial {
+				klog.Fatalf("Failed to configure sharding: %v", err)
+			}
+			klog.Errorf("Failed to configure sharding: %v", err)
+		}
+	}
+
 	i := cache.NewSharedIndexInformer(
 		cache.NewFilteredListWatchFromClient(m.kubeClient.AppsV1().RESTClient(), "statefulsets", m.opts.Namespace, labelSelectorOptions),
 		&appsv1.StatefulSet{}, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
@@ -136,7 +450,7 @@ func (m *MetricsHandler) Run(ctx context.Context) error {
 				return
 			}
 
-			m.ConfigureSharding(ctx, shard, totalShards)
+			configureSharding(shard, totalShards)
 		},
 		UpdateFunc: func(oldo, curo interface{}) {
 			old := oldo.(*appsv1.StatefulSet)
@@ -163,7 +477,7 @@ func (m *MetricsHandler) Run(ctx context.Context) error {
 				return
 			}
 
-			m.ConfigureSharding(ctx, shard, totalShards)
+			configureSharding(shard, totalShards)
 		},
 	})
 	go i.Run(ctx.Done())
@@ -175,38 +489,368 @@ func (m *MetricsHandler) Run(ctx context.Context) error {
 }
 
 // ServeHTTP implements the http.Handler interface. It writes the metrics in
-// its stores to the response body.
+// its stores to the response body. Writing is aborted once the client
+// disconnected or the scrape timed out, see scrapeContext(), failing the
+// response, see abortScrape(). Responses carry an entity tag derived from the
+// generations of the stores, and a request with a matching If-None-Match
+// header is answered with 304 Not Modified without writing any metrics.
 func (m *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	m.mtx.RLock()
 	defer m.mtx.RUnlock()
+
+	ctx, cancel := scrapeContext(r)
+	defer cancel()
+
+	filter, err := m.scrapeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	resHeader := w.Header()
 	var writer io.Writer = w
 
-	resHeader.Set("Content-Type", `text/plain; version=`+"0.0.4")
+	// The OpenMetrics text format is served only if requested, as the
+	// Prometheus text format stays the default.
+	openMetrics := expfmt.NegotiateIncludingOpenMetrics(r.Header) == expfmt.FmtOpenMetrics
+	if openMetrics {
+		resHeader.Set("Content-Type", string(expfmt.FmtOpenMetrics))
+	} else {
+		resHeader.Set("Content-Type", `text/plain; version=`+"0.0.4")
+	}
 
+	gzipped := m.enableGZIPEncoding && acceptsGzip(r)
 	if m.enableGZIPEncoding {
-		// Gzip response if requested. Taken from
-		// github.com/prometheus/client_golang/prometheus/promhttp.decorateWriter.
-		reqHeader := r.Header.Get("Accept-Encoding")
-		parts := strings.Split(reqHeader, ",")
-		for _, part := range parts {
-			part = strings.TrimSpace(part)
-			if part == "gzip" || strings.HasPrefix(part, "gzip;") {
-				writer = gzip.NewWriter(writer)
-				resHeader.Set("Content-Encoding", "gzip")
+		resHeader.Add("Vary", "Accept-Encoding")
+	}
+	if etag, ok := m.etag(r, openMetrics, gzipped); ok {
+		resHeader.Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	aborted := false
+	if gzipped {
+		gz := gzipWriterPool.Get().(*gzip.Writer)
+		defer gzipWriterPool.Put(gz)
+		gz.Reset(w)
+		// The writer has to be closed, flushing the remaining compressed
+		// data, before it is put back into the pool. Aborted responses are
+		// not completed, see abortScrape().
+		defer func() {
+			if !aborted {
+				gz.Close()
+			}
+		}()
+
+		writer = gz
+		resHeader.Set("Content-Encoding", "gzip")
+	}
+
+	cw := &contextWriter{ctx: ctx, w: writer}
+	if m.scrapeConcurrency > 1 && len(m.stores) > 1 {
+		m.writeStoresConcurrently(ctx, cw, openMetrics, filter)
+	} else {
+		for _, s := range m.stores {
+			if ctx.Err() != nil || cw.err != nil {
+				break
 			}
+			writeStore(cw, s, openMetrics, filter)
 		}
 	}
 
+	if err := ctx.Err(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	if cw.err != nil {
+		aborted = true
+		m.abortScrape(r, cw.err)
+	}
+
+	if openMetrics {
+		io.WriteString(writer, metric.OpenMetricsEOF)
+	}
+}
+
+// etag returns the strong entity tag of the response to the given request,
+// which changes whenever the metrics written in response to it may change:
+// if any store changed or the stores were swapped, but also depending on the
+// query, the format and the encoding of the response. Generations are read
+// before the metrics are written, so that the metrics written are at least
+// as recent as the entity tag. It returns false if any store does not count
+// its generations. The caller has to hold mtx, at least for reading.
+func (m *MetricsHandler) etag(r *http.Request, openMetrics, gzipped bool) (string, bool) {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%d\x00%t\x00%t\x00%s", m.epoch, m.storesGeneration, openMetrics, gzipped, r.URL.RawQuery)
 	for _, s := range m.stores {
-		ms := s.(*metricsstore.MetricsStore)
-		ms.WriteAll(w)
+		g, ok := s.store.(metricsstore.GenerationGetter)
+		if !ok {
+			return "", false
+		}
+		fmt.Fprintf(h, "\x00%d", g.Generation())
+	}
+	return fmt.Sprintf(`"%016x"`, h.Sum64()), true
+}
+
+// etagMatches returns whether the given If-None-Match header matches the given
+// entity tag, using the weak comparison RFC 7232 requires for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// scrapeTimeoutOffset is subtracted from the scrape timeout announced by
+// Prometheus, so that a scrape is aborted before Prometheus gives up on it.
+const scrapeTimeoutOffset = 500 * time.Millisecond
+
+// scrapeContext returns the context of a scrape, which is done once the
+// client disconnected or, if Prometheus announced its scrape timeout in the
+// X-Prometheus-Scrape-Timeout-Seconds header, shortly before the timeout.
+func scrapeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx := r.Context()
+
+	seconds, err := strconv.ParseFloat(r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"), 64)
+	if err != nil || seconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > scrapeTimeoutOffset {
+		timeout -= scrapeTimeoutOffset
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// abortScrape logs and counts a scrape aborted with the given error, then
+// aborts the response by panicking with http.ErrAbortHandler. The server
+// closes the connection without completing the response, so that clients
+// never take the metrics written so far for a complete response, e.g. cached
+// under its entity tag.
+func (m *MetricsHandler) abortScrape(r *http.Request, err error) {
+	reason := "write_error"
+	switch err {
+	case context.Canceled:
+		reason = "client_disconnected"
+	case context.DeadlineExceeded:
+		reason = "timeout"
+	}
+	klog.V(2).Infof("Aborted scrape from %s: %v", r.RemoteAddr, err)
+	if m.scrapesAborted != nil {
+		m.scrapesAborted.WithLabelValues(reason).Inc()
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// contextCheckInterval is the number of writes after which contextWriter
+// checks its context, as stores write the metrics of each object separately.
+const contextCheckInterval = 64
+
+// contextWriter writes to w until ctx is done or writing to w failed. After
+// that, writes fail with the error, so that the stores stop writing.
+type contextWriter struct {
+	ctx    context.Context
+	w      io.Writer
+	writes int
+	err    error
+}
+
+// Write implements the io.Writer interface.
+func (c *contextWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	if c.writes%contextCheckInterval == 0 {
+		if err := c.ctx.Err(); err != nil {
+			c.err = err
+			return 0, err
+		}
 	}
+	c.writes++
+
+	n, err := c.w.Write(p)
+	if err != nil {
+		c.err = err
+	}
+	return n, err
+}
+
+// bufferPool pools the buffers the stores are written into concurrently, so
+// that they are reused across scrapes.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &bytes.Buffer{}
+	},
+}
 
-	// In case we gzipped the response, we have to close the writer.
-	if closer, ok := writer.(io.Closer); ok {
-		closer.Close()
+// writeStoresConcurrently writes up to scrapeConcurrency stores concurrently,
+// each into its own buffer, and then writes the buffers in the order of the
+// stores. A store panicking is logged and left out, without affecting the
+// output of the others. No more stores are written once ctx is done.
+func (m *MetricsHandler) writeStoresConcurrently(ctx context.Context, w io.Writer, openMetrics bool, filter scrapeFilter) {
+	buffers := make([]*bytes.Buffer, 0, len(m.stores))
+	sem := make(chan struct{}, m.scrapeConcurrency)
+	var wg sync.WaitGroup
+
+	for _, s := range m.stores {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		buf := bufferPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		buffers = append(buffers, buf)
+
+		wg.Add(1)
+		go func(s builtStore, buf *bytes.Buffer) {
+			defer func() {
+				if r := recover(); r != nil {
+					klog.Errorf("Failed to write metrics of resource %s: %v", s.resource, r)
+					buf.Reset()
+				}
+				<-sem
+				wg.Done()
+			}()
+
+			writeStore(&contextWriter{ctx: ctx, w: buf}, s, openMetrics, filter)
+		}(s, buf)
+	}
+	wg.Wait()
+
+	for _, buf := range buffers {
+		if ctx.Err() == nil {
+			w.Write(buf.Bytes())
+		}
+		bufferPool.Put(buf)
+	}
+}
+
+// writeStore writes the metrics of the given store selected by the given
+// filter, in the OpenMetrics text format if requested and supported by the
+// store, otherwise in the Prometheus text format.
+func writeStore(w io.Writer, s builtStore, openMetrics bool, filter scrapeFilter) {
+	storeFilter := metricsstore.Filter{Namespaces: filter.namespaces}
+	if _, ok := filter.collect[s.resource]; filter.collect != nil && !ok {
+		storeFilter.Family = func(familyName string) bool {
+			_, ok := filter.collect[familyName]
+			return ok
+		}
+	}
+
+	if storeFilter.Family != nil || storeFilter.Namespaces != nil {
+		if fw, ok := s.store.(metricsstore.FilterWriter); ok {
+			fw.WriteFiltered(w, openMetrics, storeFilter)
+		}
+		return
+	}
+
+	if omw, ok := s.store.(metricsstore.OpenMetricsWriter); ok && openMetrics {
+		omw.WriteAllOpenMetrics(w)
+	} else if mw, ok := s.store.(metricsstore.MetricsWriter); ok {
+		mw.WriteAll(w)
+	}
+}
+
+// scrapeFilter selects the metrics written by a scrape.
+type scrapeFilter struct {
+	// collect contains the metric family and resource names given by the
+	// collect[] query parameters. Nil collect selects all metrics.
+	collect map[string]struct{}
+	// namespaces contains the namespaces given by the namespace query
+	// parameters, see metricsstore.Filter. Nil namespaces select all
+	// namespaces.
+	namespaces []string
+}
+
+// scrapeFilter returns the filter given by the query parameters of the request.
+// Names given by collect[] neither of a served resource nor of one of its
+// metric families are rejected. Cluster-scoped objects are only selected
+// alongside namespaces if includeClusterScoped is set.
+func (m *MetricsHandler) scrapeFilter(r *http.Request) (scrapeFilter, error) {
+	query := r.URL.Query()
+
+	collect, err := m.collectFilter(query["collect[]"])
+	if err != nil {
+		return scrapeFilter{}, err
+	}
+
+	var namespaces []string
+	if values := query["namespace"]; len(values) > 0 {
+		seen := map[string]struct{}{}
+		for _, ns := range values {
+			if ns == "" {
+				return scrapeFilter{}, errors.New("empty namespace")
+			}
+			if _, ok := seen[ns]; !ok {
+				seen[ns] = struct{}{}
+				namespaces = append(namespaces, ns)
+			}
+		}
+		if m.includeClusterScoped {
+			namespaces = append(namespaces, metav1.NamespaceNone)
+		}
+	}
+
+	return scrapeFilter{collect: collect, namespaces: namespaces}, nil
+}
+
+// collectFilter returns the given metric family and resource names as a set,
+// or nil if there are none. Names neither of a served resource nor of one of
+// its metric families are rejected.
+func (m *MetricsHandler) collectFilter(names []string) (map[string]struct{}, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := map[string]struct{}{}
+	for _, s := range m.stores {
+		known[s.resource] = struct{}{}
+		if fw, ok := s.store.(metricsstore.FilterWriter); ok {
+			for _, name := range fw.FamilyNames() {
+				if name != "" {
+					known[name] = struct{}{}
+				}
+			}
+		}
+	}
+
+	collect := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if _, ok := known[name]; !ok {
+			return nil, errors.Errorf("unknown metric family or resource %q in collect[]", name)
+		}
+		collect[name] = struct{}{}
+	}
+	return collect, nil
+}
+
+// gzipWriterPool pools the writers compressing responses, which are expensive
+// to allocate for every scrape. The payload compresses well even at the lowest
+// compression level, so BestSpeed is used.
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		gz, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+		return gz
+	},
+}
+
+// acceptsGzip returns whether the client accepts gzip encoded responses. Taken
+// from github.com/prometheus/client_golang/prometheus/promhttp.decorateWriter.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "gzip" || strings.HasPrefix(part, "gzip;") {
+			return true
+		}
 	}
+	return false
 }
 
 func shardingSettingsFromStatefulSet(ss *appsv1.StatefulSet, podName string) (nominal int32, totalReplicas int, err error) {
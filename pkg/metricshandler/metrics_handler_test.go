@@ -0,0 +1,894 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricshandler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/textparse"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/kube-state-metrics/pkg/metric"
+	generator "k8s.io/kube-state-metrics/pkg/metric_generator"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+)
+
+// newTestHandler returns a MetricsHandler serving a single store with a metric
+// for each of the given number of services.
+func newTestHandler(t testing.TB, services int, enableGZIPEncoding bool) *MetricsHandler {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o := obj.(*v1.Service)
+		return []metric.FamilyInterface{
+			&metric.Family{
+				Name: "kube_service_info",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "service"},
+						LabelValues: []string{o.Namespace, o.Name},
+						Value:       float64(1),
+					},
+				},
+			},
+		}
+	}
+
+	store := metricsstore.NewMetricsStore([]string{"# HELP kube_service_info Information about service.\n# TYPE kube_service_info gauge"}, genFunc)
+	store.WithOpenMetricsHeaders([]string{metric.OpenMetricsHeader("kube_service_info", "Information about service.", metric.Info)})
+	for i := 0; i < services; i++ {
+		err := store.Add(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("service-%d", i),
+				Namespace: "default",
+				UID:       types.UID(fmt.Sprintf("uid-%d", i)),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &MetricsHandler{
+		enableGZIPEncoding: enableGZIPEncoding,
+		mtx:                &sync.RWMutex{},
+		stores:             []builtStore{{resource: "services", store: store}},
+	}
+}
+
+func TestServeHTTPGzip(t *testing.T) {
+	m := newTestHandler(t, 100, true)
+
+	plain := httptest.NewRecorder()
+	m.ServeHTTP(plain, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if enc := plain.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("expected no content encoding without Accept-Encoding header, got %q", enc)
+	}
+
+	// Serve twice to exercise a gzip writer reused from the pool.
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept-Encoding", "deflate, gzip;q=1.0")
+		compressed := httptest.NewRecorder()
+		m.ServeHTTP(compressed, req)
+
+		if enc := compressed.Header().Get("Content-Encoding"); enc != "gzip" {
+			t.Fatalf("expected gzip content encoding, got %q", enc)
+		}
+		gz, err := gzip.NewReader(compressed.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(gz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The order of the metrics of a family is not deterministic.
+		if got, want := sortedLines(string(body)), sortedLines(plain.Body.String()); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected decompressed response to equal the plain response, got:\n%s\nwant:\n%s", body, plain.Body.String())
+		}
+	}
+
+	m.enableGZIPEncoding = false
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	disabled := httptest.NewRecorder()
+	m.ServeHTTP(disabled, req)
+	if enc := disabled.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("expected no content encoding with gzip encoding disabled, got %q", enc)
+	}
+}
+
+func TestServeHTTPOpenMetrics(t *testing.T) {
+	m := newTestHandler(t, 1, false)
+
+	plain := httptest.NewRecorder()
+	m.ServeHTTP(plain, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := plain.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4" {
+		t.Errorf("expected the Prometheus text format by default, got %q", ct)
+	}
+	if strings.Contains(plain.Body.String(), "# EOF") {
+		t.Errorf("expected no EOF in the Prometheus text format, got:\n%s", plain.Body.String())
+	}
+
+	// Accept header sent by Prometheus.
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1,text/plain;version=0.0.4;q=0.5,*/*;q=0.1")
+	om := httptest.NewRecorder()
+	m.ServeHTTP(om, req)
+
+	if ct := om.Header().Get("Content-Type"); ct != "application/openmetrics-text; version=0.0.1; charset=utf-8" {
+		t.Errorf("expected the OpenMetrics text format, got %q", ct)
+	}
+	want := `# HELP kube_service Information about service.
+# TYPE kube_service info
+kube_service_info{namespace="default",service="service-0"} 1
+# EOF
+`
+	if got := om.Body.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+// TestServeHTTPOpenMetricsConformance ensures the OpenMetrics text format
+// served for all types of metric families is accepted by the OpenMetrics parser
+// of Prometheus and follows the naming rules of the format.
+func TestServeHTTPOpenMetricsConformance(t *testing.T) {
+	families := []generator.FamilyGenerator{
+		{Name: "kube_service_info", Help: "Information about service.", Type: metric.Info},
+		{Name: "kube_service_status_phase", Help: "The phase of the service.", Type: metric.StateSet},
+		{Name: "kube_service_restarts_total", Help: "Number of restarts of the service.", Type: metric.Counter},
+		{Name: "kube_service_created_seconds", Help: "Unix creation timestamp.", Type: metric.Gauge},
+	}
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o := obj.(*v1.Service)
+		serviceLabels := func(keys ...string) ([]string, []string) {
+			return append([]string{"namespace", "service"}, keys...), []string{o.Namespace, o.Name}
+		}
+		phases := metric.StateSetMetrics("phase", []string{"Pending", "Active"}, "Active")
+		for _, m := range phases {
+			keys, values := serviceLabels()
+			m.LabelKeys = append(keys, m.LabelKeys...)
+			m.LabelValues = append(values, m.LabelValues...)
+		}
+		keys, values := serviceLabels()
+		return []metric.FamilyInterface{
+			&metric.Family{Name: "kube_service_info", Metrics: []*metric.Metric{{LabelKeys: keys, LabelValues: values, Value: 1}}},
+			&metric.Family{Name: "kube_service_status_phase", Metrics: phases},
+			&metric.Family{Name: "kube_service_restarts_total", Metrics: []*metric.Metric{{LabelKeys: keys, LabelValues: values, Value: 3}}},
+			&metric.Family{Name: "kube_service_created_seconds", Metrics: []*metric.Metric{{LabelKeys: keys, LabelValues: values, Value: 1501569018}}},
+		}
+	}
+	store := metricsstore.NewMetricsStore(generator.ExtractMetricFamilyHeaders(families), genFunc)
+	store.WithOpenMetricsHeaders(generator.ExtractMetricFamilyOpenMetricsHeaders(families))
+	err := store.Add(&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "service-0", Namespace: "default", UID: "uid-0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &MetricsHandler{
+		mtx:    &sync.RWMutex{},
+		stores: []builtStore{{resource: "services", store: store}},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	body := w.Body.Bytes()
+
+	p := textparse.NewOpenMetricsParser(body)
+	var family string
+	var typ textparse.MetricType
+	series := 0
+	for {
+		entry, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("expected a valid OpenMetrics exposition, got %v:\n%s", err, body)
+		}
+		switch entry {
+		case textparse.EntryType:
+			var name []byte
+			name, typ = p.Type()
+			family = string(name)
+		case textparse.EntryUnit:
+			name, unit := p.Unit()
+			if !strings.HasSuffix(string(name), "_"+string(unit)) {
+				t.Errorf("expected the name of family %s to end with its unit %s", name, unit)
+			}
+		case textparse.EntrySeries:
+			series++
+			var lset labels.Labels
+			p.Metric(&lset)
+			name := lset.Get(labels.MetricName)
+			_, _, value := p.Series()
+			switch typ {
+			case textparse.MetricTypeCounter:
+				if name != family+"_total" && name != family+"_created" {
+					t.Errorf("expected counter %s to be named %s_total, got %s", family, family, name)
+				}
+			case textparse.MetricTypeInfo:
+				if name != family+"_info" || value != 1 {
+					t.Errorf("expected info %s to be named %s_info with value 1, got %s %v", family, family, name, value)
+				}
+			case textparse.MetricTypeStateset:
+				if lset.Get(family) == "" || (value != 0 && value != 1) {
+					t.Errorf("expected state set %s to be labelled %s with value 0 or 1, got %s %v", family, family, lset, value)
+				}
+			default:
+				if name != family {
+					t.Errorf("expected %s %s to be named %s, got %s", typ, family, family, name)
+				}
+			}
+		}
+	}
+	if series != 5 {
+		t.Errorf("expected 5 series, got %d:\n%s", series, body)
+	}
+}
+
+func TestServeHTTPCollect(t *testing.T) {
+	m := newTestHandler(t, 1, false)
+
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o := obj.(*v1.Pod)
+		return []metric.FamilyInterface{
+			&metric.Family{
+				Name: "kube_pod_info",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "pod"},
+						LabelValues: []string{o.Namespace, o.Name},
+						Value:       float64(1),
+					},
+				},
+			},
+			&metric.Family{
+				Name: "kube_pod_created",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "pod"},
+						LabelValues: []string{o.Namespace, o.Name},
+						Value:       float64(1501569018),
+					},
+				},
+			},
+		}
+	}
+	pods := metricsstore.NewMetricsStore([]string{
+		"# HELP kube_pod_info Information about pod.\n# TYPE kube_pod_info gauge",
+		"# HELP kube_pod_created Unix creation timestamp\n# TYPE kube_pod_created gauge",
+	}, genFunc)
+	err := pods.Add(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-0",
+			Namespace: "default",
+			UID:       types.UID("pod-uid-0"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.stores = append(m.stores, builtStore{resource: "pods", store: pods})
+
+	services := `# HELP kube_service_info Information about service.
+# TYPE kube_service_info gauge
+kube_service_info{namespace="default",service="service-0"} 1
+`
+	podInfo := `# HELP kube_pod_info Information about pod.
+# TYPE kube_pod_info gauge
+kube_pod_info{namespace="default",pod="pod-0"} 1
+`
+	podCreated := `# HELP kube_pod_created Unix creation timestamp
+# TYPE kube_pod_created gauge
+kube_pod_created{namespace="default",pod="pod-0"} 1.501569018e+09
+`
+
+	tests := []struct {
+		query string
+		code  int
+		want  string
+	}{
+		{"", http.StatusOK, services + podCreated + podInfo},
+		{"?collect[]=kube_pod_created", http.StatusOK, podCreated},
+		{"?collect[]=kube_service_info&collect[]=kube_pod_info", http.StatusOK, services + podInfo},
+		{"?collect[]=pods", http.StatusOK, podCreated + podInfo},
+		{"?collect[]=pods&collect[]=kube_service_info", http.StatusOK, services + podCreated + podInfo},
+		{"?collect[]=kube_pod_info&collect[]=kube_node_info", http.StatusBadRequest, "unknown metric family or resource \"kube_node_info\" in collect[]\n"},
+		{"?collect[]=", http.StatusBadRequest, "unknown metric family or resource \"\" in collect[]\n"},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics"+test.query, nil))
+		if rec.Code != test.code {
+			t.Errorf("query %q: expected status code %d, got %d", test.query, test.code, rec.Code)
+		}
+		if got := rec.Body.String(); got != test.want {
+			t.Errorf("query %q: want:\n%s\ngot:\n%s", test.query, test.want, got)
+		}
+	}
+}
+
+func TestServeHTTPNamespace(t *testing.T) {
+	genFunc := func(obj interface{}) []metric.FamilyInterface {
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []metric.FamilyInterface{
+			&metric.Family{
+				Name: "kube_object_info",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"namespace", "name"},
+						LabelValues: []string{o.GetNamespace(), o.GetName()},
+						Value:       float64(1),
+					},
+				},
+			},
+		}
+	}
+
+	services := metricsstore.NewMetricsStore([]string{"# HELP kube_object_info Information about object.\n# TYPE kube_object_info gauge"}, genFunc)
+	for _, ns := range []string{"team-a", "team-b"} {
+		err := services.Add(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "service",
+				Namespace: ns,
+				UID:       types.UID(ns),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	nodes := metricsstore.NewMetricsStore([]string{"# HELP kube_object_info Information about object.\n# TYPE kube_object_info gauge"}, genFunc)
+	err := nodes.Add(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node",
+			UID:  types.UID("node"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &MetricsHandler{
+		mtx: &sync.RWMutex{},
+		stores: []builtStore{
+			{resource: "services", store: services},
+			{resource: "nodes", store: nodes},
+		},
+	}
+
+	header := "# HELP kube_object_info Information about object.\n# TYPE kube_object_info gauge\n"
+	teamA := `kube_object_info{namespace="team-a",name="service"} 1` + "\n"
+	teamB := `kube_object_info{namespace="team-b",name="service"} 1` + "\n"
+	node := `kube_object_info{namespace="",name="node"} 1` + "\n"
+
+	tests := []struct {
+		query                string
+		includeClusterScoped bool
+		code                 int
+		want                 []string
+	}{
+		{"?namespace=team-a", false, http.StatusOK, []string{header + teamA, header}},
+		{"?namespace=team-a&namespace=team-b&namespace=team-a", false, http.StatusOK, []string{header + teamA + teamB, header}},
+		{"?namespace=team-c", false, http.StatusOK, []string{header, header}},
+		{"?namespace=team-a", true, http.StatusOK, []string{header + teamA, header + node}},
+		{"?namespace=team-a&collect[]=nodes", true, http.StatusOK, []string{header + node}},
+		{"?namespace=", false, http.StatusBadRequest, []string{"empty namespace\n"}},
+	}
+
+	for _, test := range tests {
+		m.includeClusterScoped = test.includeClusterScoped
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics"+test.query, nil))
+		if rec.Code != test.code {
+			t.Errorf("query %q: expected status code %d, got %d", test.query, test.code, rec.Code)
+		}
+		// The order of the metrics of a family is not deterministic.
+		if got, want := sortedLines(rec.Body.String()), sortedLines(strings.Join(test.want, "")); !reflect.DeepEqual(got, want) {
+			t.Errorf("query %q: want:\n%s\ngot:\n%s", test.query, strings.Join(test.want, ""), rec.Body.String())
+		}
+	}
+}
+
+// panickingStore is a store panicking when written.
+type panickingStore struct {
+	*metricsstore.MetricsStore
+}
+
+func (s panickingStore) WriteAll(w io.Writer) {
+	io.WriteString(w, "partial output\n")
+	panic("write failed")
+}
+
+func TestServeHTTPConcurrency(t *testing.T) {
+	// A single object per store, as the order of the metrics of a family is
+	// not deterministic.
+	m := newTestHandler(t, 1, false)
+	sequential := httptest.NewRecorder()
+	m.ServeHTTP(sequential, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	store := m.stores[0].store.(*metricsstore.MetricsStore)
+	m.stores = []builtStore{
+		{resource: "services", store: store},
+		{resource: "broken", store: panickingStore{store}},
+		{resource: "services", store: store},
+	}
+	m.scrapeConcurrency = 2
+
+	// Serve twice to exercise buffers reused from the pool.
+	for i := 0; i < 2; i++ {
+		concurrent := httptest.NewRecorder()
+		m.ServeHTTP(concurrent, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		// The output of the stores is written in order, leaving out the
+		// output of the panicking store.
+		if got, want := concurrent.Body.String(), sequential.Body.String()+sequential.Body.String(); got != want {
+			t.Errorf("want:\n%s\ngot:\n%s", want, got)
+		}
+	}
+}
+
+func TestServeHTTPGeneratorPanic(t *testing.T) {
+	families := []generator.FamilyGenerator{
+		{
+			Name: "kube_service_info",
+			Help: "Information about service.",
+			Type: metric.Info,
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				o := obj.(*v1.Service)
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   []string{"namespace", "service"},
+							LabelValues: []string{o.Namespace, o.Name},
+						},
+					},
+				}
+			},
+		},
+		{
+			Name: "kube_service_spec_type",
+			Help: "Type about service.",
+			Type: metric.Gauge,
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				var spec *v1.ServiceSpec
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   []string{"type"},
+							LabelValues: []string{string(spec.Type)},
+							Value:       1,
+						},
+					},
+				}
+			},
+		},
+	}
+
+	var panicked []string
+	families = generator.RecoverMetricFamilies(families, func(family string, obj interface{}, r interface{}) {
+		panicked = append(panicked, family)
+	})
+	store := metricsstore.NewMetricsStore(generator.ExtractMetricFamilyHeaders(families), generator.ComposeMetricGenFuncs(families))
+	err := store.Add(&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "service", Namespace: "default", UID: "uid"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &MetricsHandler{
+		mtx:    &sync.RWMutex{},
+		stores: []builtStore{{resource: "services", store: store}},
+	}
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `kube_service_info{namespace="default",service="service"} 1`) {
+		t.Errorf("expected metrics of the other families, got:\n%s", w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "kube_service_spec_type{") {
+		t.Errorf("expected no metrics of the panicking family, got:\n%s", w.Body.String())
+	}
+	if !reflect.DeepEqual(panicked, []string{"kube_service_spec_type"}) {
+		t.Errorf("expected panic of kube_service_spec_type to be recovered, got %v", panicked)
+	}
+}
+
+// failingWriter is a http.ResponseWriter failing after the given number of
+// writes, as when the client disconnected.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.writes == 0 {
+		return 0, errors.New("broken pipe")
+	}
+	w.writes--
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestServeHTTPAborted(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	tests := []struct {
+		desc        string
+		ctx         context.Context
+		concurrency int
+		writes      int
+		reason      string
+	}{
+		{desc: "client disconnected", ctx: canceled, reason: "client_disconnected"},
+		{desc: "client disconnected, concurrent", ctx: canceled, concurrency: 2, reason: "client_disconnected"},
+		{desc: "timed out", ctx: expired, reason: "timeout"},
+		{desc: "write failed", ctx: context.Background(), writes: 10, reason: "write_error"},
+	}
+
+	for _, test := range tests {
+		m := newTestHandler(t, 1000, false)
+		m.stores = append(m.stores, m.stores[0])
+		m.scrapeConcurrency = test.concurrency
+		m.WithMetrics(prometheus.NewRegistry())
+
+		w := &failingWriter{ResponseRecorder: httptest.NewRecorder(), writes: test.writes}
+		if test.writes == 0 {
+			w.writes = -1
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != http.ErrAbortHandler {
+					t.Errorf("%s: expected the response to be aborted, got %v", test.desc, r)
+				}
+			}()
+			m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil).WithContext(test.ctx))
+		}()
+
+		// The header of the family is written at once, spanning two lines.
+		if lines := strings.Count(w.Body.String(), "\n"); lines > test.writes+1 {
+			t.Errorf("%s: expected writing to be aborted, got %d lines", test.desc, lines)
+		}
+		metric := &dto.Metric{}
+		if err := m.scrapesAborted.WithLabelValues(test.reason).Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		if got := metric.GetCounter().GetValue(); got != 1 {
+			t.Errorf("%s: expected scrape aborted with reason %s to be counted, got %v", test.desc, test.reason, got)
+		}
+	}
+
+	// Served over HTTP, a scrape timing out fails instead of completing with
+	// the metrics written so far.
+	for _, gzipped := range []bool{false, true} {
+		server := httptest.NewServer(newSlowTestHandler(t, gzipped))
+		if _, err := scrape(server.URL, "0.01", nil); err == nil {
+			t.Errorf("gzip %v: expected the timed out scrape to fail", gzipped)
+		}
+		server.Close()
+	}
+}
+
+// slowStore is a store taking a while to write its metrics, so that scrapes
+// with a shorter timeout time out.
+type slowStore struct {
+	cache.Store
+	delay time.Duration
+}
+
+func (s *slowStore) WriteAll(w io.Writer) {
+	time.Sleep(s.delay)
+	io.WriteString(w, "kube_slow_metric 1\n")
+}
+
+func (s *slowStore) Generation() uint64 {
+	return 0
+}
+
+// newSlowTestHandler returns the MetricsHandler of newTestHandler with an
+// additional store taking 100ms to write its metrics.
+func newSlowTestHandler(t testing.TB, enableGZIPEncoding bool) *MetricsHandler {
+	m := newTestHandler(t, 1000, enableGZIPEncoding)
+	m.stores = append(m.stores, builtStore{
+		resource: "slow",
+		store:    &slowStore{Store: cache.NewStore(cache.MetaNamespaceKeyFunc), delay: 100 * time.Millisecond},
+	})
+	return m
+}
+
+// scrape requests the metrics from the given URL with the given scrape timeout
+// and headers, and returns the response, the body of which was read.
+func scrape(url, timeout string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url+"/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if timeout != "" {
+		req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", timeout)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if _, err := ioutil.ReadAll(resp.Body); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func TestScrapeContext(t *testing.T) {
+	tests := []struct {
+		header  string
+		timeout time.Duration
+	}{
+		{header: "", timeout: 0},
+		{header: "invalid", timeout: 0},
+		{header: "-1", timeout: 0},
+		{header: "10", timeout: 10*time.Second - scrapeTimeoutOffset},
+		{header: "0.2", timeout: 200 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if test.header != "" {
+			r.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", test.header)
+		}
+		start := time.Now()
+		ctx, cancel := scrapeContext(r)
+		deadline, ok := ctx.Deadline()
+		cancel()
+
+		if test.timeout == 0 {
+			if ok {
+				t.Errorf("header %q: expected no deadline, got %v", test.header, deadline)
+			}
+			continue
+		}
+		if !ok || deadline.Before(start.Add(test.timeout)) || deadline.After(time.Now().Add(test.timeout)) {
+			t.Errorf("header %q: expected deadline in %v, got %v", test.header, test.timeout, deadline.Sub(start))
+		}
+	}
+}
+
+func TestServeHTTPETag(t *testing.T) {
+	m := newTestHandler(t, 2, true)
+
+	serve := func(etag string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		return w
+	}
+
+	first := serve("", nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || strings.HasPrefix(etag, "W/") {
+		t.Fatalf("expected 200 with strong ETag, got %d with ETag %q", first.Code, etag)
+	}
+
+	for _, ifNoneMatch := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+		w := serve(ifNoneMatch, nil)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: expected 304 without body, got %d with %d bytes", ifNoneMatch, w.Code, w.Body.Len())
+		}
+		if got := w.Header().Get("ETag"); got != etag {
+			t.Errorf("If-None-Match %s: expected ETag %s, got %s", ifNoneMatch, etag, got)
+		}
+	}
+
+	// The representation differs by format, encoding and query.
+	for _, header := range []http.Header{
+		{"Accept": {`application/openmetrics-text; version=0.0.1`}},
+		{"Accept-Encoding": {"gzip"}},
+	} {
+		if w := serve(etag, header); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+			t.Errorf("%v: expected 200 with different ETag, got %d with ETag %s", header, w.Code, w.Header().Get("ETag"))
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/metrics?namespace=default", nil)
+	req.Header.Set("If-None-Match", etag)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for different query, got %d", w.Code)
+	}
+
+	err := m.stores[0].store.Delete(&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "service-0", Namespace: "default", UID: "uid-0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := serve(etag, nil)
+	if changed.Code != http.StatusOK || changed.Header().Get("ETag") == etag {
+		t.Fatalf("expected 200 with new ETag after change, got %d with ETag %s", changed.Code, changed.Header().Get("ETag"))
+	}
+	if changed.Body.String() == first.Body.String() {
+		t.Errorf("expected changed metrics, got:\n%s", changed.Body.String())
+	}
+
+	m.storesGeneration++
+	if w := serve(changed.Header().Get("ETag"), nil); w.Code != http.StatusOK {
+		t.Errorf("expected 200 after stores were swapped, got %d", w.Code)
+	}
+
+	m.stores = append(m.stores, builtStore{resource: "other", store: cache.NewStore(cache.MetaNamespaceKeyFunc)})
+	if w := serve("*", nil); w.Code != http.StatusOK || w.Header().Get("ETag") != "" {
+		t.Errorf("expected 200 without ETag for store without generations, got %d with ETag %s", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+// TestServeHTTPETagAborted ensures aborted scrapes never complete with an
+// entity tag, so that conditional requests only ever match complete responses.
+func TestServeHTTPETagAborted(t *testing.T) {
+	m := newSlowTestHandler(t, false)
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	complete, err := scrape(server.URL, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := complete.Header.Get("ETag")
+
+	err = m.stores[0].store.Delete(&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "service-0", Namespace: "default", UID: "uid-0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := scrape(server.URL, "0.01", nil); err == nil {
+		t.Fatalf("expected the timed out scrape to fail, got %d with ETag %s", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	resp, err := scrape(server.URL, "", http.Header{"If-None-Match": {etag}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == etag {
+		t.Errorf("expected 200 with new ETag after the aborted scrape of changed metrics, got %d with ETag %s", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	resp, err = scrape(server.URL, "", http.Header{"If-None-Match": {resp.Header.Get("ETag")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304 for the ETag of the complete response, got %d", resp.StatusCode)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	m := &MetricsHandler{mtx: &sync.RWMutex{}}
+	buf := &bytes.Buffer{}
+	if m.WriteMetrics(buf) || buf.Len() != 0 {
+		t.Errorf("expected no metrics before stores were built, got %q", buf.String())
+	}
+
+	m = newTestHandler(t, 2, false)
+	if m.WriteMetrics(buf) || buf.Len() != 0 {
+		t.Errorf("expected no metrics before stores synced, got %q", buf.String())
+	}
+
+	services := []interface{}{
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "service-0", Namespace: "default", UID: "uid-0"}},
+	}
+	if err := m.stores[0].store.Replace(services, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !m.WriteMetrics(buf) {
+		t.Fatal("expected metrics once stores synced")
+	}
+	want := `# HELP kube_service_info Information about service.
+# TYPE kube_service_info gauge
+kube_service_info{namespace="default",service="service-0"} 1
+`
+	if buf.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func sortedLines(s string) []string {
+	lines := strings.Split(s, "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	m := newTestHandler(b, 1000, true)
+
+	for _, encoding := range []string{"", "gzip"} {
+		name := "plain"
+		if encoding != "" {
+			name = encoding
+		}
+		b.Run(name, func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.Header.Set("Accept-Encoding", encoding)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		})
+	}
+}
+
+func BenchmarkServeHTTPConcurrency(b *testing.B) {
+	const (
+		storeCount = 25
+		objects    = 50000
+	)
+
+	stores := make([]builtStore, 0, storeCount)
+	for i := 0; i < storeCount; i++ {
+		stores = append(stores, newTestHandler(b, objects/storeCount, false).stores...)
+	}
+
+	for _, concurrency := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			m := &MetricsHandler{
+				mtx:               &sync.RWMutex{},
+				stores:            stores,
+				scrapeConcurrency: concurrency,
+			}
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		})
+	}
+}
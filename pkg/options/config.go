@@ -0,0 +1,125 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigHash returns the hex encoded SHA256 hash of the configuration file
+// the Options were last loaded from, or an empty string if there is none.
+func (o *Options) ConfigHash() string {
+	return o.configHash
+}
+
+// Reload returns new Options, parsed from the command line arguments and the
+// current content of the configuration file. Options that can only be set via
+// klog flags are not part of the returned Options.
+func (o *Options) Reload() (*Options, error) {
+	n := NewOptions()
+	n.flags = pflag.NewFlagSet("", pflag.ContinueOnError)
+	n.flags.ParseErrorsWhitelist.UnknownFlags = true
+	n.flags.SetOutput(ioutil.Discard)
+	n.addKubeStateMetricsFlags()
+
+	if err := n.Parse(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// applyConfigFile reads the configuration file and sets each option
+// contained in it, unless it was already set on the command line. Lists are
+// set item by item and maps entry by entry in the form <key>=<value>, which
+// is equivalent to passing the corresponding flag multiple times.
+func (o *Options) applyConfigFile() error {
+	b, err := ioutil.ReadFile(o.Config)
+	if err != nil {
+		return errors.Wrap(err, "read config file")
+	}
+
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return errors.Wrapf(err, "parse config file %s", o.Config)
+	}
+
+	for name, value := range config {
+		f := o.flags.Lookup(name)
+		if f == nil || name == "config" {
+			return errors.Errorf("unknown option %q in config file %s", name, o.Config)
+		}
+		if f.Changed {
+			continue
+		}
+
+		for _, v := range configValues(value) {
+			if err := f.Value.Set(v); err != nil {
+				return errors.Wrapf(err, "invalid value %q for option %q in config file %s", v, name, o.Config)
+			}
+		}
+	}
+
+	o.configHash = hashConfig(b)
+	return nil
+}
+
+// configValues converts a value of the configuration file into the flag
+// values it corresponds to.
+func configValues(value interface{}) []string {
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	case map[string]interface{}:
+		values := make([]string, 0, len(v))
+		for key, item := range v {
+			values = append(values, key+"="+fmt.Sprint(item))
+		}
+		sort.Strings(values)
+		return values
+	case nil:
+		return nil
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// ConfigFileHash returns the hex encoded SHA256 hash of the content of the
+// configuration file at the given path.
+func ConfigFileHash(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(err, "read config file")
+	}
+	return hashConfig(b), nil
+}
+
+func hashConfig(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
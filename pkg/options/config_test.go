@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestApplyConfigFile(t *testing.T) {
+	tests := []struct {
+		Desc    string
+		Args    []string
+		Config  string
+		Want    func(o *Options) bool
+		WantErr bool
+	}{
+		{
+			Desc:   "list options",
+			Config: "resources: [pods, nodes]\nnamespaces: [default]\n",
+			Want: func(o *Options) bool {
+				return reflect.DeepEqual(o.Resources, ResourceSet{"pods": struct{}{}, "nodes": struct{}{}}) &&
+					reflect.DeepEqual(o.Namespaces, NamespaceList{"default"})
+			},
+		},
+		{
+			Desc:   "map and scalar options",
+			Config: "resource-label-selector:\n  pods: app=web\nlabel-selector: team=payments\nport: 9090\n",
+			Want: func(o *Options) bool {
+				return reflect.DeepEqual(o.ResourceLabelSelectors, SelectorMap{"pods": "app=web"}) &&
+					o.LabelSelector == "team=payments" && o.Port == 9090
+			},
+		},
+		{
+			Desc:   "command line takes precedence",
+			Args:   []string{"--resources=secrets"},
+			Config: "resources: [pods]\n",
+			Want: func(o *Options) bool {
+				return reflect.DeepEqual(o.Resources, ResourceSet{"secrets": struct{}{}})
+			},
+		},
+		{
+			Desc:    "unknown option",
+			Config:  "unknown: true\n",
+			WantErr: true,
+		},
+		{
+			Desc:    "config option",
+			Config:  "config: other.yaml\n",
+			WantErr: true,
+		},
+		{
+			Desc:    "invalid value",
+			Config:  "port: eighty\n",
+			WantErr: true,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "ksm-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range tests {
+		path := filepath.Join(dir, "config.yaml")
+		if err := ioutil.WriteFile(path, []byte(test.Config), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		opts := NewOptions()
+		opts.flags = pflag.NewFlagSet("config_test", pflag.ContinueOnError)
+		opts.addKubeStateMetricsFlags()
+		if err := opts.flags.Parse(append(test.Args, "--config="+path)); err != nil {
+			t.Fatalf("Test error for Desc: %s. Parse failed: %v", test.Desc, err)
+		}
+
+		err := opts.applyConfigFile()
+		if test.WantErr {
+			if err == nil {
+				t.Errorf("Test error for Desc: %s. Expected error, got nil", test.Desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test error for Desc: %s. Unexpected error: %v", test.Desc, err)
+			continue
+		}
+		if !test.Want(opts) {
+			t.Errorf("Test error for Desc: %s. Unexpected options: %+v", test.Desc, opts)
+		}
+
+		hash, err := ConfigFileHash(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts.ConfigHash() != hash {
+			t.Errorf("Test error for Desc: %s. Want config hash %s, got %s", test.Desc, hash, opts.ConfigHash())
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"k8s.io/klog"
 
@@ -30,6 +31,9 @@ import (
 type Options struct {
 	Apiserver       string
 	Kubeconfig      string
+	Context         string
+	KubeAPIQPS      float32
+	KubeAPIBurst    int
 	Help            bool
 	Port            int
 	Host            string
@@ -43,11 +47,66 @@ type Options struct {
 	Namespace       string
 	MetricDenylist  MetricSet
 	MetricAllowlist MetricSet
+	MetricPrefix    string
+	CustomLabels    LabelMap
 	Version         bool
 
+	LabelSelector          string
+	ResourceLabelSelectors SelectorMap
+	ResourceFieldSelectors SelectorMap
+	ResyncPeriods          ResyncPeriods
+	UseAPIServerCache      bool
+	MetadataOnlyResources  ResourceSet
+
+	EmitDeprecatedMetricNames bool
+	MaxLabelValueLength       int
+	AddUIDLabel               bool
+
+	EnableSkipAnnotation bool
+	SkipAnnotation       string
+
 	EnableGZIPEncoding bool
 
-	flags *pflag.FlagSet
+	TLSCertFile       string
+	TLSPrivateKeyFile string
+	TLSMinVersion     string
+
+	EnableAuth   bool
+	AuthVerb     string
+	AuthResource string
+
+	EnableLeaderElection        bool
+	LeaderElectionLeaseName     string
+	LeaderElectionNamespace     string
+	LeaderElectionLeaseDuration time.Duration
+	LeaderElectionRenewDeadline time.Duration
+	LeaderElectionRetryPeriod   time.Duration
+
+	ShutdownDrainTimeout time.Duration
+	ReloadSyncTimeout    time.Duration
+
+	FamilyGenerationDurationSampling int
+
+	RemoteWriteURL                   string
+	RemoteWriteUsername              string
+	RemoteWritePasswordFile          string
+	RemoteWriteBearerTokenFile       string
+	RemoteWriteTLSCAFile             string
+	RemoteWriteTLSCertFile           string
+	RemoteWriteTLSKeyFile            string
+	RemoteWriteTLSInsecureSkipVerify bool
+	PushInterval                     time.Duration
+
+	EnablePprof bool
+
+	ScrapeConcurrency int
+
+	NamespaceFilterIncludeClusterScoped bool
+
+	Config string
+
+	flags      *pflag.FlagSet
+	configHash string
 }
 
 // NewOptions returns a new instance of `Options`.
@@ -56,6 +115,12 @@ func NewOptions() *Options {
 		Resources:       ResourceSet{},
 		MetricAllowlist: MetricSet{},
 		MetricDenylist:  MetricSet{},
+
+		ResourceLabelSelectors: SelectorMap{},
+		ResyncPeriods:          ResyncPeriods{},
+		ResourceFieldSelectors: SelectorMap{},
+		CustomLabels:           LabelMap{},
+		MetadataOnlyResources:  ResourceSet{},
 	}
 }
 
@@ -75,8 +140,17 @@ func (o *Options) AddFlags() {
 		o.flags.PrintDefaults()
 	}
 
+	o.addKubeStateMetricsFlags()
+}
+
+// addKubeStateMetricsFlags adds all flags except for the klog ones to the
+// flag set of the Options.
+func (o *Options) addKubeStateMetricsFlags() {
 	o.flags.StringVar(&o.Apiserver, "apiserver", "", `The URL of the apiserver to use as a master`)
 	o.flags.StringVar(&o.Kubeconfig, "kubeconfig", "", "Absolute path to the kubeconfig file")
+	o.flags.StringVar(&o.Context, "context", "", "The name of the kubeconfig context to use. Defaults to the current context.")
+	o.flags.Float32Var(&o.KubeAPIQPS, "kube-api-qps", 50, "Maximum number of queries per second sent to the apiserver. The initial lists of all resources are limited by it.")
+	o.flags.IntVar(&o.KubeAPIBurst, "kube-api-burst", 100, "Maximum burst of queries sent to the apiserver, exceeding --kube-api-qps.")
 	o.flags.BoolVarP(&o.Help, "help", "h", false, "Print Help text")
 	o.flags.IntVar(&o.Port, "port", 8080, `Port to expose metrics on.`)
 	o.flags.StringVar(&o.Host, "host", "0.0.0.0", `Host to expose metrics on.`)
@@ -86,6 +160,19 @@ func (o *Options) AddFlags() {
 	o.flags.Var(&o.Namespaces, "namespaces", fmt.Sprintf("Comma-separated list of namespaces to be enabled. Defaults to %q", &DefaultNamespaces))
 	o.flags.Var(&o.MetricAllowlist, "metric-allowlist", "Comma-separated list of metrics to be exposed. This list comprises of exact metric names and/or regex patterns. The allowlist and denylist are mutually exclusive.")
 	o.flags.Var(&o.MetricDenylist, "metric-denylist", "Comma-separated list of metrics not to be enabled. This list comprises of exact metric names and/or regex patterns. The allowlist and denylist are mutually exclusive.")
+	o.flags.StringVar(&o.MetricPrefix, "metric-prefix", "kube_", "Prefix replacing the leading kube_ of all metric names, e.g. ksm_staging_. The metric allowlist and denylist apply to the prefixed names.")
+	o.flags.BoolVar(&o.EmitDeprecatedMetricNames, "emit-deprecated-metric-names", false, "Expose the metric families renamed to end with the unit of their values, e.g. kube_pod_created_timestamp_seconds, under their deprecated names as well, e.g. kube_pod_created, and the per-resource metric families replaced by resource and unit labels, e.g. kube_pod_container_resource_requests_cpu_cores, for dashboards and alerts not yet migrated.")
+	o.flags.BoolVar(&o.AddUIDLabel, "add-uid-label", false, "Add the uid of each object as a label to the info metric family of its resource, e.g. kube_node_info, to tell apart objects recreated with the same name. Every recreation of an object creates a new series, which increases churn, so it is disabled by default.")
+	o.flags.BoolVar(&o.EnableSkipAnnotation, "enable-skip-annotation", false, "Skip the objects annotated with --skip-annotation set to \"true\", e.g. short-lived objects sharing namespaces and labels with others. Their metrics are not generated and they are not held in memory. Removing the annotation exposes their metrics again on the next update of the object.")
+	o.flags.StringVar(&o.SkipAnnotation, "skip-annotation", "kube-state-metrics.io/skip-metrics", "Key of the annotation objects are skipped by with --enable-skip-annotation.")
+	o.flags.IntVar(&o.MaxLabelValueLength, "max-label-value-length", 0, "Maximum length in bytes of the values of labels converted from Kubernetes labels and annotations, e.g. label_app. Longer values are truncated at a character boundary and suffixed with \"...\", which is counted by kube_state_metrics_label_values_truncated_total. 0 means unlimited.")
+	o.flags.Var(&o.CustomLabels, "custom-labels", "Comma-separated list of static labels added to every metric, e.g. cluster=prod,env=staging. They are appended after the labels of each metric and must not collide with the labels of kube-state-metrics, e.g. namespace, status or label_*.")
+	o.flags.StringVar(&o.LabelSelector, "label-selector", "", "Label selector restricting the objects listed and watched for all resources, e.g. team=payments. Filtering happens on the apiserver, objects not matching are neither cached nor exposed.")
+	o.flags.Var(&o.ResourceLabelSelectors, "resource-label-selector", "Label selector restricting the objects listed and watched for a single resource in the form <resource>=<selector>, e.g. pods=app=web. Takes precedence over --label-selector, an empty selector disables it for the resource. Can be specified multiple times.")
+	o.flags.Var(&o.ResourceFieldSelectors, "resource-field-selector", "Field selector restricting the objects listed and watched for a single resource in the form <resource>=<selector>, e.g. pods=status.phase!=Succeeded. The supported fields depend on the resource, unsupported ones make kube-state-metrics exit on startup and fail configuration reloads. Can be specified multiple times.")
+	o.flags.Var(&o.ResyncPeriods, "resync-period", "Resync period of the reflectors, either for all resources or per resource in the form [<resource>=]<duration>, e.g. default=0,pods=0,nodes=5m. A resync re-processes all cached objects, reconciling missed updates at the cost of CPU spikes for large resources. 0 disables resyncing, which is the default.")
+	o.flags.BoolVar(&o.UseAPIServerCache, "use-apiserver-cache", false, "List objects with resourceVersion 0, so that lists are served from the watch cache of the apiserver instead of quorum reads from etcd. This greatly reduces the load on the apiserver and etcd, at the cost of possibly stale lists, which the following watches catch up with. Watches are not affected.")
+	o.flags.Var(&o.MetadataOnlyResources, "metadata-only-resources", "Comma-separated list of resources the objects of which are listed and watched metadata only, so that their data is neither transferred from the apiserver nor held in memory. Supported are configmaps and secrets. kube_secret_type is not available for secrets listed metadata only.")
 	o.flags.Int32Var(&o.Shard, "shard", int32(0), "The instances shard nominal (zero indexed) within the total number of shards. (default 0)")
 	o.flags.IntVar(&o.TotalShards, "total-shards", 1, "The total number of shards. Sharding is disabled when total shards is set to 1.")
 
@@ -95,12 +182,48 @@ func (o *Options) AddFlags() {
 	o.flags.StringVar(&o.Namespace, "pod-namespace", "", "Name of the namespace of the pod specified by --pod. "+autoshardingNotice)
 	o.flags.BoolVarP(&o.Version, "version", "", false, "kube-state-metrics build version information")
 	o.flags.BoolVar(&o.EnableGZIPEncoding, "enable-gzip-encoding", false, "Gzip responses when requested by clients via 'Accept-Encoding: gzip' header.")
+	o.flags.StringVar(&o.TLSCertFile, "tls-cert-file", "", "Path to the TLS certificate used to serve both the metrics and the telemetry endpoints via HTTPS. Requires --tls-private-key-file. The file is re-read periodically to pick up rotated certificates.")
+	o.flags.StringVar(&o.TLSPrivateKeyFile, "tls-private-key-file", "", "Path to the private key of the certificate given by --tls-cert-file.")
+	o.flags.BoolVar(&o.EnableAuth, "enable-auth", false, "Require requests for metrics to present a bearer token, which is authenticated with a TokenReview and authorized with a SubjectAccessReview against the apiserver. Unauthenticated requests get 401, unauthorized ones 403. Allowed decisions are cached for a minute.")
+	o.flags.StringVar(&o.AuthVerb, "auth-verb", "get", "Verb of the SubjectAccessReviews authorizing requests when --enable-auth is set.")
+	o.flags.StringVar(&o.AuthResource, "auth-resource", "", "Resource of the SubjectAccessReviews authorizing requests when --enable-auth is set, in the form <resource>[.<group>]. If empty, the request path is reviewed as a non-resource URL, e.g. /metrics.")
+	o.flags.StringVar(&o.TLSMinVersion, "tls-min-version", "VersionTLS12", "Minimum TLS version accepted when serving via HTTPS. One of VersionTLS10, VersionTLS11, VersionTLS12, VersionTLS13.")
+	o.flags.BoolVar(&o.EnableLeaderElection, "enable-leader-election", false, "Elect a leader among the replicas via a coordination.k8s.io Lease for active/passive high availability. Only the leader serves metrics, the other replicas keep their caches warm and respond to requests for metrics with 503.")
+	o.flags.StringVar(&o.LeaderElectionLeaseName, "leader-election-lease-name", "kube-state-metrics", "Name of the Lease used for leader election.")
+	o.flags.StringVar(&o.LeaderElectionNamespace, "leader-election-namespace", "", "Namespace of the Lease used for leader election. Defaults to --pod-namespace.")
+	o.flags.DurationVar(&o.LeaderElectionLeaseDuration, "leader-election-lease-duration", 15*time.Second, "Duration non-leaders wait before taking over the Lease of a leader that stopped renewing it.")
+	o.flags.DurationVar(&o.LeaderElectionRenewDeadline, "leader-election-renew-deadline", 10*time.Second, "Duration the leader keeps retrying to renew the Lease before it stops serving metrics.")
+	o.flags.DurationVar(&o.LeaderElectionRetryPeriod, "leader-election-retry-period", 2*time.Second, "Interval in which acquiring or renewing the Lease is tried.")
+	o.flags.DurationVar(&o.ShutdownDrainTimeout, "shutdown-drain-timeout", 20*time.Second, "Duration in-flight requests are given to complete on SIGTERM or SIGINT, after the servers stopped accepting new requests.")
+	o.flags.DurationVar(&o.ReloadSyncTimeout, "reload-sync-timeout", 5*time.Minute, "Duration the stores rebuilt on a configuration reload or re-sharding are given to complete their initial sync, while the previous stores keep being served. Reloads not synced in time fail and keep the previous configuration. 0 waits indefinitely.")
+	o.flags.IntVar(&o.FamilyGenerationDurationSampling, "family-generation-duration-sampling", 0, "Observe the duration of every Nth generation of each metric family for an object in kube_state_metrics_family_generation_duration_seconds, e.g. 100. Metric families are generated whenever an object is added or updated. 0 disables the observation.")
+	o.flags.StringVar(&o.RemoteWriteURL, "remote-write-url", "", "URL of a Prometheus remote-write receiver the metrics are pushed to every --push-interval, e.g. for clusters kube-state-metrics cannot be scraped in. The metrics keep being served on the metrics port. Nothing is pushed while any store has not completed its initial sync or, with --enable-leader-election, by non-leaders.")
+	o.flags.StringVar(&o.RemoteWriteUsername, "remote-write-username", "", "Username of the basic authentication with the remote-write receiver.")
+	o.flags.StringVar(&o.RemoteWritePasswordFile, "remote-write-password-file", "", "Path to the password of the basic authentication with the remote-write receiver.")
+	o.flags.StringVar(&o.RemoteWriteBearerTokenFile, "remote-write-bearer-token-file", "", "Path to the bearer token presented to the remote-write receiver. The file is re-read for every request to pick up rotated tokens.")
+	o.flags.StringVar(&o.RemoteWriteTLSCAFile, "remote-write-tls-ca-file", "", "Path to the CA certificates verifying the remote-write receiver. Defaults to the system's CA certificates.")
+	o.flags.StringVar(&o.RemoteWriteTLSCertFile, "remote-write-tls-cert-file", "", "Path to the client certificate presented to the remote-write receiver. Requires --remote-write-tls-key-file.")
+	o.flags.StringVar(&o.RemoteWriteTLSKeyFile, "remote-write-tls-key-file", "", "Path to the private key of the certificate given by --remote-write-tls-cert-file.")
+	o.flags.BoolVar(&o.RemoteWriteTLSInsecureSkipVerify, "remote-write-tls-insecure-skip-verify", false, "Skip verifying the certificate of the remote-write receiver.")
+	o.flags.DurationVar(&o.PushInterval, "push-interval", 30*time.Second, "Interval in which the metrics are pushed to --remote-write-url. Failed requests are retried until the next push is due.")
+	o.flags.BoolVar(&o.EnablePprof, "enable-pprof", false, "Serve the Go profiling endpoints under /debug/pprof/ on the telemetry port, never on the metrics port. Profiles can expose sensitive data like memory contents and command line arguments, restrict access to the telemetry port or enable --enable-auth, which applies to these endpoints as well.")
+	o.flags.IntVar(&o.ScrapeConcurrency, "scrape-concurrency", 1, "Number of resource stores written concurrently during a scrape. Each store is written into its own buffer, which trades memory for scrape latency when greater than 1.")
+	o.flags.BoolVar(&o.NamespaceFilterIncludeClusterScoped, "namespace-filter-include-cluster-scoped", false, "Include the metrics of cluster-scoped objects, e.g. nodes, in scrapes filtered by the namespace query parameter. By default, such scrapes only include the metrics of objects in the given namespaces.")
+	o.flags.StringVar(&o.Config, "config", "", "Path to a YAML file containing options, keyed by their flag names, e.g. 'resources: [pods, nodes]'. Command line flags take precedence. The file is watched for changes, which rebuild the stores if the resources, namespaces, metric allow- or denylist or selectors changed.")
 }
 
-// Parse parses the flag definitions from the argument list.
+// Parse parses the flag definitions from the argument list and, if given,
+// the configuration file.
 func (o *Options) Parse() error {
 	err := o.flags.Parse(os.Args)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if o.Config == "" {
+		return nil
+	}
+	return o.applyConfigFile()
 }
 
 // Usage is the function called when an error occurs while parsing flags.
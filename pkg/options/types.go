@@ -19,6 +19,9 @@ package options
 import (
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/pkg/errors"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -110,19 +113,148 @@ func (n *NamespaceList) IsAllNamespaces() bool {
 	return len(*n) == 1 && (*n)[0] == metav1.NamespaceAll
 }
 
-// Set converts a comma-separated string of namespaces into a slice and appends it to the NamespaceList
+// Set converts a comma-separated string of namespaces into a slice and appends it to the NamespaceList.
+// Namespaces already part of the NamespaceList are skipped.
 func (n *NamespaceList) Set(value string) error {
 	splitNamespaces := strings.Split(value, ",")
 	for _, ns := range splitNamespaces {
 		ns = strings.TrimSpace(ns)
-		if len(ns) != 0 {
+		if len(ns) != 0 && !n.contains(ns) {
 			*n = append(*n, ns)
 		}
 	}
 	return nil
 }
 
+func (n *NamespaceList) contains(namespace string) bool {
+	for _, ns := range *n {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
 // Type returns a descriptive string about the NamespaceList type.
 func (n *NamespaceList) Type() string {
 	return "string"
 }
+
+// SelectorMap represents a per-resource mapping of selectors.
+type SelectorMap map[string]string
+
+func (s *SelectorMap) String() string {
+	m := *s
+	ss := make([]string, 0, len(m))
+	for resource, selector := range m {
+		ss = append(ss, resource+"="+selector)
+	}
+	sort.Strings(ss)
+	return strings.Join(ss, " ")
+}
+
+// Set converts a string of the form <resource>=<selector> into an entry of the SelectorMap.
+// The selector itself may contain both commas and equal signs.
+func (s *SelectorMap) Set(value string) error {
+	m := *s
+	parts := strings.SplitN(value, "=", 2)
+	resource := strings.TrimSpace(parts[0])
+	if len(parts) != 2 || len(resource) == 0 {
+		return errors.Errorf("expected <resource>=<selector>, got %q", value)
+	}
+	m[resource] = strings.TrimSpace(parts[1])
+	return nil
+}
+
+// Type returns a descriptive string about the SelectorMap type.
+func (s *SelectorMap) Type() string {
+	return "string"
+}
+
+// LabelMap represents a set of static labels.
+type LabelMap map[string]string
+
+func (l *LabelMap) String() string {
+	m := *l
+	ss := make([]string, 0, len(m))
+	for key, value := range m {
+		ss = append(ss, key+"="+value)
+	}
+	sort.Strings(ss)
+	return strings.Join(ss, ",")
+}
+
+// Set converts a comma-separated string of <key>=<value> pairs into entries of the LabelMap.
+func (l *LabelMap) Set(value string) error {
+	m := *l
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || len(key) == 0 {
+			return errors.Errorf("expected <key>=<value>, got %q", pair)
+		}
+		m[key] = strings.TrimSpace(parts[1])
+	}
+	return nil
+}
+
+// Type returns a descriptive string about the LabelMap type.
+func (l *LabelMap) Type() string {
+	return "string"
+}
+
+// DefaultResyncPeriodKey is the key of the ResyncPeriods entry applying to all
+// resources without an entry of their own.
+const DefaultResyncPeriodKey = "default"
+
+// ResyncPeriods represents the resync periods of the reflectors per resource.
+type ResyncPeriods map[string]time.Duration
+
+func (r *ResyncPeriods) String() string {
+	m := *r
+	ss := make([]string, 0, len(m))
+	for resource, period := range m {
+		ss = append(ss, resource+"="+period.String())
+	}
+	sort.Strings(ss)
+	return strings.Join(ss, ",")
+}
+
+// Set converts a comma-separated string of <resource>=<duration> pairs into
+// entries of the ResyncPeriods. A duration without resource sets the default,
+// like default=<duration>.
+func (r *ResyncPeriods) Set(value string) error {
+	m := *r
+	for _, pair := range strings.Split(value, ",") {
+		resource, duration := DefaultResyncPeriodKey, strings.TrimSpace(pair)
+		if parts := strings.SplitN(pair, "=", 2); len(parts) == 2 {
+			resource, duration = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		}
+		if len(resource) == 0 {
+			return errors.Errorf("expected [<resource>=]<duration>, got %q", pair)
+		}
+		period, err := time.ParseDuration(duration)
+		if err != nil {
+			return errors.Wrapf(err, "invalid resync period for %s", resource)
+		}
+		if period < 0 {
+			return errors.Errorf("resync period for %s must not be negative, got %s", resource, period)
+		}
+		m[resource] = period
+	}
+	return nil
+}
+
+// Type returns a descriptive string about the ResyncPeriods type.
+func (r *ResyncPeriods) Type() string {
+	return "string"
+}
+
+// Get returns the resync period of the given resource, falling back to the
+// default. Zero disables resyncing.
+func (r ResyncPeriods) Get(resource string) time.Duration {
+	if period, ok := r[resource]; ok {
+		return period
+	}
+	return r[DefaultResyncPeriodKey]
+}
@@ -19,6 +19,7 @@ package options
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestResourceSetSet(t *testing.T) {
@@ -75,6 +76,14 @@ func TestNamespaceListSet(t *testing.T) {
 				"kube-system",
 			}),
 		},
+		{
+			Desc:  "duplicate namespaces",
+			Value: "default,kube-system,default",
+			Wanted: NamespaceList([]string{
+				"default",
+				"kube-system",
+			}),
+		},
 	}
 
 	for _, test := range tests {
@@ -116,3 +125,202 @@ func TestMetricSetSet(t *testing.T) {
 		}
 	}
 }
+
+func TestSelectorMapSet(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Values      []string
+		Wanted      SelectorMap
+		WantedError bool
+	}{
+		{
+			Desc:   "single selector",
+			Values: []string{"pods=app=web"},
+			Wanted: SelectorMap{"pods": "app=web"},
+		},
+		{
+			Desc:   "selectors with multiple requirements",
+			Values: []string{"pods=app=web,tier!=frontend", "nodes=node-role.kubernetes.io/worker"},
+			Wanted: SelectorMap{
+				"pods":  "app=web,tier!=frontend",
+				"nodes": "node-role.kubernetes.io/worker",
+			},
+		},
+		{
+			Desc:   "empty selector",
+			Values: []string{"nodes="},
+			Wanted: SelectorMap{"nodes": ""},
+		},
+		{
+			Desc:        "missing resource",
+			Values:      []string{"=app=web"},
+			Wanted:      SelectorMap{},
+			WantedError: true,
+		},
+		{
+			Desc:        "missing selector",
+			Values:      []string{"pods"},
+			Wanted:      SelectorMap{},
+			WantedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		sm := &SelectorMap{}
+		var gotError error
+		for _, v := range test.Values {
+			if err := sm.Set(v); err != nil {
+				gotError = err
+			}
+		}
+		if (gotError != nil) != test.WantedError || !reflect.DeepEqual(*sm, test.Wanted) {
+			t.Errorf("Test error for Desc: %s. Want: %+v. Got: %+v. Wanted Error: %v, Got Error: %v", test.Desc, test.Wanted, *sm, test.WantedError, gotError)
+		}
+	}
+}
+
+func TestLabelMapSet(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Values      []string
+		Wanted      LabelMap
+		WantedError bool
+	}{
+		{
+			Desc:   "single label",
+			Values: []string{"cluster=prod"},
+			Wanted: LabelMap{"cluster": "prod"},
+		},
+		{
+			Desc:   "comma-separated labels",
+			Values: []string{"cluster=prod, env=staging"},
+			Wanted: LabelMap{"cluster": "prod", "env": "staging"},
+		},
+		{
+			Desc:   "repeated flag",
+			Values: []string{"cluster=prod", "region=eu-west-1"},
+			Wanted: LabelMap{"cluster": "prod", "region": "eu-west-1"},
+		},
+		{
+			Desc:        "missing value",
+			Values:      []string{"cluster"},
+			Wanted:      LabelMap{},
+			WantedError: true,
+		},
+		{
+			Desc:        "missing key",
+			Values:      []string{"=prod"},
+			Wanted:      LabelMap{},
+			WantedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		lm := &LabelMap{}
+		var gotError error
+		for _, v := range test.Values {
+			if err := lm.Set(v); err != nil {
+				gotError = err
+			}
+		}
+		if (gotError != nil) != test.WantedError || !reflect.DeepEqual(*lm, test.Wanted) {
+			t.Errorf("Test error for Desc: %s. Want: %+v. Got: %+v. Wanted Error: %v, Got Error: %v", test.Desc, test.Wanted, *lm, test.WantedError, gotError)
+		}
+	}
+}
+
+func TestResyncPeriodsSet(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Values      []string
+		Wanted      ResyncPeriods
+		WantedError bool
+	}{
+		{
+			Desc:   "default only",
+			Values: []string{"5m"},
+			Wanted: ResyncPeriods{"default": 5 * time.Minute},
+		},
+		{
+			Desc:   "default and overrides",
+			Values: []string{"default=0,pods=0, nodes=5m"},
+			Wanted: ResyncPeriods{"default": 0, "pods": 0, "nodes": 5 * time.Minute},
+		},
+		{
+			Desc:   "repeated flag",
+			Values: []string{"nodes=5m", "services=1h"},
+			Wanted: ResyncPeriods{"nodes": 5 * time.Minute, "services": time.Hour},
+		},
+		{
+			Desc:        "invalid duration",
+			Values:      []string{"nodes=5"},
+			Wanted:      ResyncPeriods{},
+			WantedError: true,
+		},
+		{
+			Desc:        "negative duration",
+			Values:      []string{"nodes=-5m"},
+			Wanted:      ResyncPeriods{},
+			WantedError: true,
+		},
+		{
+			Desc:        "missing resource",
+			Values:      []string{"=5m"},
+			Wanted:      ResyncPeriods{},
+			WantedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		rp := &ResyncPeriods{}
+		var gotError error
+		for _, v := range test.Values {
+			if err := rp.Set(v); err != nil {
+				gotError = err
+			}
+		}
+		if (gotError != nil) != test.WantedError || !reflect.DeepEqual(*rp, test.Wanted) {
+			t.Errorf("Test error for Desc: %s. Want: %+v. Got: %+v. Wanted Error: %v, Got Error: %v", test.Desc, test.Wanted, *rp, test.WantedError, gotError)
+		}
+	}
+}
+
+func TestResyncPeriodsGet(t *testing.T) {
+	tests := []struct {
+		Desc     string
+		Periods  ResyncPeriods
+		Resource string
+		Wanted   time.Duration
+	}{
+		{
+			Desc:     "no periods",
+			Periods:  ResyncPeriods{},
+			Resource: "pods",
+			Wanted:   0,
+		},
+		{
+			Desc:     "default",
+			Periods:  ResyncPeriods{"default": 5 * time.Minute},
+			Resource: "pods",
+			Wanted:   5 * time.Minute,
+		},
+		{
+			Desc:     "override",
+			Periods:  ResyncPeriods{"default": 5 * time.Minute, "nodes": time.Hour},
+			Resource: "nodes",
+			Wanted:   time.Hour,
+		},
+		{
+			Desc:     "override disabling resync",
+			Periods:  ResyncPeriods{"default": 5 * time.Minute, "pods": 0},
+			Resource: "pods",
+			Wanted:   0,
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.Periods.Get(test.Resource); got != test.Wanted {
+			t.Errorf("Test error for Desc: %s. Want: %s. Got: %s.", test.Desc, test.Wanted, got)
+		}
+	}
+}
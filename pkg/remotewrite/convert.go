@@ -0,0 +1,187 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotewrite
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// The following types mirror the messages of the Prometheus remote-write
+// protocol, see prometheus/prompb/remote.proto and types.proto. Only the
+// fields needed to write samples are declared. They are encoded by hand, see
+// WriteRequest.Marshal(), instead of depending on the generated code.
+
+// WriteRequest is the body of a remote-write request.
+type WriteRequest struct {
+	Timeseries []TimeSeries
+}
+
+// TimeSeries is a series identified by its labels, including the __name__
+// label, with its samples.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// Label is a label of a TimeSeries.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value of a TimeSeries at a timestamp in milliseconds since the
+// epoch.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// Protocol buffer wire types and field numbers of the messages above.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+
+	fieldWriteRequestTimeseries = 1
+	fieldTimeSeriesLabels       = 1
+	fieldTimeSeriesSamples      = 2
+	fieldLabelName              = 1
+	fieldLabelValue             = 2
+	fieldSampleValue            = 1
+	fieldSampleTimestamp        = 2
+)
+
+// Marshal returns the protocol buffer encoding of the WriteRequest.
+func (r *WriteRequest) Marshal() []byte {
+	var b, ts, msg []byte
+	for _, s := range r.Timeseries {
+		ts = ts[:0]
+		for _, l := range s.Labels {
+			msg = msg[:0]
+			if l.Name != "" {
+				msg = appendBytes(msg, fieldLabelName, []byte(l.Name))
+			}
+			if l.Value != "" {
+				msg = appendBytes(msg, fieldLabelValue, []byte(l.Value))
+			}
+			ts = appendBytes(ts, fieldTimeSeriesLabels, msg)
+		}
+		for _, sample := range s.Samples {
+			msg = msg[:0]
+			if sample.Value != 0 || math.Signbit(sample.Value) {
+				msg = appendTag(msg, fieldSampleValue, wireFixed64)
+				var v [8]byte
+				binary.LittleEndian.PutUint64(v[:], math.Float64bits(sample.Value))
+				msg = append(msg, v[:]...)
+			}
+			if sample.Timestamp != 0 {
+				msg = appendTag(msg, fieldSampleTimestamp, wireVarint)
+				msg = appendVarint(msg, uint64(sample.Timestamp))
+			}
+			ts = appendBytes(ts, fieldTimeSeriesSamples, msg)
+		}
+		b = appendBytes(b, fieldWriteRequestTimeseries, ts)
+	}
+	return b
+}
+
+func appendTag(b []byte, field, wireType int) []byte {
+	return appendVarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// nameLabel is the label holding the metric name of a TimeSeries.
+const nameLabel = "__name__"
+
+// Convert parses the metrics in the Prometheus text format read from r and
+// returns a TimeSeries with a single sample at the given time for each of
+// them. The stores cache the rendered metrics instead of the metric families
+// they were generated from, so that the text format is what there is to
+// convert. Series are ordered by metric family name, their labels by name,
+// as remote-write receivers require.
+func Convert(r io.Reader, t time.Time) ([]TimeSeries, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse metrics")
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	timestamp := t.UnixNano() / int64(time.Millisecond)
+	var series []TimeSeries
+	for _, name := range names {
+		f := families[name]
+		for _, m := range f.GetMetric() {
+			value, err := sampleValue(f.GetType(), m)
+			if err != nil {
+				return nil, errors.Wrapf(err, "convert metric family %s", name)
+			}
+
+			labels := make([]Label, 0, len(m.GetLabel())+1)
+			labels = append(labels, Label{Name: nameLabel, Value: name})
+			for _, l := range m.GetLabel() {
+				labels = append(labels, Label{Name: l.GetName(), Value: l.GetValue()})
+			}
+			sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+			series = append(series, TimeSeries{
+				Labels:  labels,
+				Samples: []Sample{{Value: value, Timestamp: timestamp}},
+			})
+		}
+	}
+	return series, nil
+}
+
+// sampleValue returns the value of the given metric of a family of the given
+// type. Metric families are exposed as gauges, counters or untyped only.
+func sampleValue(t dto.MetricType, m *dto.Metric) (float64, error) {
+	switch t {
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue(), nil
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue(), nil
+	case dto.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue(), nil
+	default:
+		return 0, errors.Errorf("unsupported metric type %s", t)
+	}
+}
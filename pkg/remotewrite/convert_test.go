@@ -0,0 +1,221 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotewrite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestMarshal(t *testing.T) {
+	req := &WriteRequest{Timeseries: []TimeSeries{{
+		Labels:  []Label{{Name: "__name__", Value: "a"}},
+		Samples: []Sample{{Value: 1, Timestamp: 2}},
+	}}}
+
+	want := []byte{
+		0x0a, 0x1c, // timeseries, 28 bytes
+		0x0a, 0x0d, // labels, 13 bytes
+		0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_', // name
+		0x12, 0x01, 'a', // value
+		0x12, 0x0b, // samples, 11 bytes
+		0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, // value 1.0
+		0x10, 0x02, // timestamp 2
+	}
+	if got := req.Marshal(); !bytes.Equal(got, want) {
+		t.Errorf("want:\n% x\ngot:\n% x", want, got)
+	}
+
+	got, err := unmarshalWriteRequest(req.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, req) {
+		t.Errorf("expected round trip to yield %+v, got %+v", req, got)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	ts := time.Unix(1500000000, 123000000)
+	tests := []struct {
+		desc    string
+		metrics string
+		want    []TimeSeries
+		wantErr bool
+	}{
+		{
+			desc:    "empty",
+			metrics: "",
+			want:    nil,
+		},
+		{
+			desc: "families ordered by name, labels by name",
+			metrics: `# HELP kube_pod_info Information about pod.
+# TYPE kube_pod_info gauge
+kube_pod_info{pod="a",namespace="ns"} 1
+# HELP kube_node_info Information about node.
+# TYPE kube_node_info gauge
+kube_node_info{node="n"} 1
+`,
+			want: []TimeSeries{
+				{
+					Labels:  []Label{{Name: "__name__", Value: "kube_node_info"}, {Name: "node", Value: "n"}},
+					Samples: []Sample{{Value: 1, Timestamp: 1500000000123}},
+				},
+				{
+					Labels:  []Label{{Name: "__name__", Value: "kube_pod_info"}, {Name: "namespace", Value: "ns"}, {Name: "pod", Value: "a"}},
+					Samples: []Sample{{Value: 1, Timestamp: 1500000000123}},
+				},
+			},
+		},
+		{
+			desc: "counters, untyped and escaped label values",
+			metrics: `# TYPE kube_pod_container_status_restarts_total counter
+kube_pod_container_status_restarts_total{container="c\"1\\\n"} 3
+kube_untyped 0.5
+`,
+			want: []TimeSeries{
+				{
+					Labels:  []Label{{Name: "__name__", Value: "kube_pod_container_status_restarts_total"}, {Name: "container", Value: "c\"1\\\n"}},
+					Samples: []Sample{{Value: 3, Timestamp: 1500000000123}},
+				},
+				{
+					Labels:  []Label{{Name: "__name__", Value: "kube_untyped"}},
+					Samples: []Sample{{Value: 0.5, Timestamp: 1500000000123}},
+				},
+			},
+		},
+		{
+			desc: "unsupported type",
+			metrics: `# TYPE kube_summary summary
+kube_summary_sum 1
+kube_summary_count 1
+`,
+			wantErr: true,
+		},
+		{
+			desc:    "invalid",
+			metrics: "kube_pod_info{\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := Convert(strings.NewReader(test.metrics), ts)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: expected error %t, got %v", test.desc, test.wantErr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: want %+v, got %+v", test.desc, test.want, got)
+		}
+	}
+}
+
+// unmarshalWriteRequest decodes the protocol buffer encoding of a
+// WriteRequest, independent of WriteRequest.Marshal().
+func unmarshalWriteRequest(b []byte) (*WriteRequest, error) {
+	req := &WriteRequest{}
+	err := decodeFields(b, func(field int, v []byte, _ uint64) error {
+		if field != fieldWriteRequestTimeseries {
+			return nil
+		}
+		var s TimeSeries
+		err := decodeFields(v, func(field int, v []byte, _ uint64) error {
+			switch field {
+			case fieldTimeSeriesLabels:
+				var l Label
+				err := decodeFields(v, func(field int, v []byte, _ uint64) error {
+					switch field {
+					case fieldLabelName:
+						l.Name = string(v)
+					case fieldLabelValue:
+						l.Value = string(v)
+					}
+					return nil
+				})
+				s.Labels = append(s.Labels, l)
+				return err
+			case fieldTimeSeriesSamples:
+				var sample Sample
+				err := decodeFields(v, func(field int, _ []byte, n uint64) error {
+					switch field {
+					case fieldSampleValue:
+						sample.Value = math.Float64frombits(n)
+					case fieldSampleTimestamp:
+						sample.Timestamp = int64(n)
+					}
+					return nil
+				})
+				s.Samples = append(s.Samples, sample)
+				return err
+			}
+			return nil
+		})
+		req.Timeseries = append(req.Timeseries, s)
+		return err
+	})
+	return req, err
+}
+
+// decodeFields calls fn with the field number and either the bytes or the
+// numeric value of each field of the given encoded message.
+func decodeFields(b []byte, fn func(field int, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		tag, l := binary.Uvarint(b)
+		if l <= 0 {
+			return errors.New("invalid tag")
+		}
+		b = b[l:]
+
+		var v []byte
+		var n uint64
+		switch tag & 7 {
+		case wireVarint:
+			n, l = binary.Uvarint(b)
+			if l <= 0 {
+				return errors.New("invalid varint")
+			}
+			b = b[l:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errors.New("invalid fixed64")
+			}
+			n, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireBytes:
+			size, l := binary.Uvarint(b)
+			if l <= 0 || uint64(len(b)-l) < size {
+				return errors.New("invalid length")
+			}
+			v, b = b[l:l+int(size)], b[l+int(size):]
+		default:
+			return errors.Errorf("unsupported wire type %d", tag&7)
+		}
+
+		if err := fn(int(tag>>3), v, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
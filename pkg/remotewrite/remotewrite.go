@@ -0,0 +1,321 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package remotewrite pushes the metrics of kube-state-metrics to a receiver
+// of the Prometheus remote-write protocol, for clusters kube-state-metrics
+// cannot be scraped in.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog"
+
+	"k8s.io/kube-state-metrics/pkg/version"
+)
+
+const (
+	// maxSamplesPerRequest is the number of samples sent per request at most,
+	// so that requests stay below the size limits of receivers.
+	maxSamplesPerRequest = 2000
+	// minBackoff and maxBackoff bound the delay between retries of a request.
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 5 * time.Second
+)
+
+// Config configures a Writer.
+type Config struct {
+	// URL is the remote-write endpoint of the receiver.
+	URL string
+	// Interval is the interval in which the metrics are pushed. Failed
+	// requests are retried until the next push is due.
+	Interval time.Duration
+
+	// Username and PasswordFile configure basic authentication.
+	Username     string
+	PasswordFile string
+	// BearerTokenFile configures bearer token authentication. It is re-read
+	// for every push, so that rotated tokens are picked up.
+	BearerTokenFile string
+
+	// TLSCAFile, TLSCertFile and TLSKeyFile configure the CA verifying the
+	// receiver and the client certificate presented to it.
+	TLSCAFile             string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSInsecureSkipVerify bool
+}
+
+// Validate returns an error if the Config is incomplete or inconsistent.
+func (c Config) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return errors.Wrap(err, "invalid URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("URL %q has to be http or https", c.URL)
+	}
+	if c.Interval <= 0 {
+		return errors.New("push interval has to be positive")
+	}
+	if (c.Username != "" || c.PasswordFile != "") && c.BearerTokenFile != "" {
+		return errors.New("basic authentication and bearer token are mutually exclusive")
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("TLS certificate and key have to be given together")
+	}
+	return nil
+}
+
+// Source provides the metrics pushed by a Writer.
+type Source interface {
+	// WriteMetrics writes the metrics to push in the Prometheus text format.
+	// It returns false if no metrics are to be pushed currently.
+	WriteMetrics(w io.Writer) bool
+}
+
+// SourceFunc is an adapter to use a function as Source.
+type SourceFunc func(w io.Writer) bool
+
+// WriteMetrics implements the Source interface.
+func (f SourceFunc) WriteMetrics(w io.Writer) bool {
+	return f(w)
+}
+
+// Writer pushes the metrics of a Source to a remote-write receiver.
+type Writer struct {
+	config Config
+	source Source
+	client *http.Client
+
+	samples *prometheus.CounterVec
+	retries prometheus.Counter
+}
+
+// New returns a new Writer. The samples pushed and the retries are exposed via
+// the kube_state_metrics_remote_write_samples_total and
+// kube_state_metrics_remote_write_retries_total metrics registered with the
+// given registry.
+func New(config Config, source Source, r prometheus.Registerer) (*Writer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	w := &Writer{
+		config: config,
+		source: source,
+		client: &http.Client{Transport: transport},
+		samples: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "kube_state_metrics_remote_write_samples_total",
+				Help: "Number of samples pushed via remote-write, by whether the receiver accepted them.",
+			},
+			[]string{"result"},
+		),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "kube_state_metrics_remote_write_retries_total",
+			Help: "Number of remote-write requests retried after a recoverable error.",
+		}),
+	}
+	if r != nil {
+		r.MustRegister(w.samples, w.retries)
+	}
+	return w, nil
+}
+
+// newTLSConfig returns the TLS configuration of the client for the given
+// Config.
+func newTLSConfig(config Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.TLSInsecureSkipVerify}
+	if config.TLSCAFile != "" {
+		ca, err := ioutil.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read CA")
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("no certificates found in %s", config.TLSCAFile)
+		}
+	}
+	if config.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// Run pushes the metrics every interval until the given context is done.
+func (w *Writer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pushCtx, cancel := context.WithTimeout(ctx, w.config.Interval)
+			if err := w.Push(pushCtx); err != nil {
+				klog.Errorf("Failed to push metrics to %s: %v", w.config.URL, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Push converts the current metrics of the source and sends them in batches
+// of at most maxSamplesPerRequest samples. Batches failing with a recoverable
+// error are retried with backoff until the given context is done. Sending
+// continues with the next batch if a batch failed.
+func (w *Writer) Push(ctx context.Context) error {
+	buf := &bytes.Buffer{}
+	now := time.Now()
+	if !w.source.WriteMetrics(buf) {
+		klog.V(2).Info("No metrics to push")
+		return nil
+	}
+	series, err := Convert(buf, now)
+	if err != nil {
+		return err
+	}
+
+	var failed error
+	for start := 0; start < len(series); start += maxSamplesPerRequest {
+		end := start + maxSamplesPerRequest
+		if end > len(series) {
+			end = len(series)
+		}
+		batch := series[start:end]
+
+		if err := w.sendWithRetries(ctx, &WriteRequest{Timeseries: batch}); err != nil {
+			w.samples.WithLabelValues("error").Add(float64(len(batch)))
+			failed = err
+			continue
+		}
+		w.samples.WithLabelValues("success").Add(float64(len(batch)))
+	}
+	return failed
+}
+
+// sendWithRetries sends the given request, retrying with exponential backoff
+// as long as it fails with a recoverable error and the context is not done.
+func (w *Writer) sendWithRetries(ctx context.Context, req *WriteRequest) error {
+	body := snappy.Encode(nil, req.Marshal())
+	backoff := minBackoff
+	for {
+		err := w.send(ctx, body)
+		if _, ok := err.(recoverableError); !ok {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(err, "giving up retrying")
+		case <-time.After(backoff):
+		}
+		w.retries.Inc()
+		klog.V(2).Infof("Retrying remote-write request after %v: %v", backoff, err)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// recoverableError is an error of a request worth retrying, i.e. a network
+// error, a server error or the receiver throttling requests.
+type recoverableError struct {
+	error
+}
+
+// send sends the given snappy compressed WriteRequest once.
+func (w *Writer) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "kube-state-metrics/"+version.Release)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if err := w.authenticate(req); err != nil {
+		return err
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return recoverableError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	err = errors.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return recoverableError{err}
+	}
+	return err
+}
+
+// authenticate sets the credentials of the given request, reading them from
+// their files.
+func (w *Writer) authenticate(req *http.Request) error {
+	if w.config.BearerTokenFile != "" {
+		token, err := ioutil.ReadFile(w.config.BearerTokenFile)
+		if err != nil {
+			return errors.Wrap(err, "read bearer token")
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	if w.config.Username != "" || w.config.PasswordFile != "" {
+		var password []byte
+		if w.config.PasswordFile != "" {
+			var err error
+			password, err = ioutil.ReadFile(w.config.PasswordFile)
+			if err != nil {
+				return errors.Wrap(err, "read password")
+			}
+		}
+		req.SetBasicAuth(w.config.Username, strings.TrimSpace(string(password)))
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package remotewrite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// receiver is a remote-write receiver answering requests with the given
+// status codes in turn, and with 200 once they are used up.
+type receiver struct {
+	mtx      sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []*WriteRequest
+	errs     []error
+}
+
+func (rcv *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcv.mtx.Lock()
+	defer rcv.mtx.Unlock()
+
+	rcv.requests = append(rcv.requests, r)
+	compressed, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		var b []byte
+		b, err = snappy.Decode(nil, compressed)
+		if err == nil {
+			var req *WriteRequest
+			req, err = unmarshalWriteRequest(b)
+			rcv.bodies = append(rcv.bodies, req)
+		}
+	}
+	if err != nil {
+		rcv.errs = append(rcv.errs, err)
+	}
+
+	status := http.StatusOK
+	if len(rcv.statuses) > 0 {
+		status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func counterValue(t *testing.T, r *prometheus.Registry, name string, labels map[string]string) float64 {
+	families, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if labels[l.GetName()] != l.GetValue() {
+					continue metrics
+				}
+			}
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestPush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "remotewrite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	passwordFile := writeFile(t, dir, "password", "secret\n")
+	tokenFile := writeFile(t, dir, "token", "token\n")
+
+	metrics := `# HELP kube_pod_info Information about pod.
+# TYPE kube_pod_info gauge
+kube_pod_info{namespace="ns",pod="a"} 1
+kube_pod_info{namespace="ns",pod="b"} 1
+`
+	var many string
+	for i := 0; i < maxSamplesPerRequest+1; i++ {
+		many += fmt.Sprintf("kube_untyped{i=\"%d\"} %d\n", i, i)
+	}
+
+	tests := []struct {
+		desc          string
+		config        Config
+		metrics       string
+		ready         bool
+		statuses      []int
+		wantErr       bool
+		wantRequests  int
+		wantSeries    int
+		wantSuccess   float64
+		wantError     float64
+		wantRetries   float64
+		wantAuthority string
+	}{
+		{
+			desc:          "basic authentication",
+			config:        Config{Username: "user", PasswordFile: passwordFile},
+			metrics:       metrics,
+			ready:         true,
+			wantRequests:  1,
+			wantSeries:    2,
+			wantSuccess:   2,
+			wantAuthority: "basic",
+		},
+		{
+			desc:          "bearer token",
+			config:        Config{BearerTokenFile: tokenFile},
+			metrics:       metrics,
+			ready:         true,
+			wantRequests:  1,
+			wantSeries:    2,
+			wantSuccess:   2,
+			wantAuthority: "bearer",
+		},
+		{
+			desc:    "source not ready",
+			metrics: metrics,
+			ready:   false,
+		},
+		{
+			desc:         "server error is retried",
+			metrics:      metrics,
+			ready:        true,
+			statuses:     []int{http.StatusInternalServerError, http.StatusTooManyRequests},
+			wantRequests: 3,
+			wantSeries:   2,
+			wantSuccess:  2,
+			wantRetries:  2,
+		},
+		{
+			desc:         "client error is not retried",
+			metrics:      metrics,
+			ready:        true,
+			statuses:     []int{http.StatusBadRequest},
+			wantErr:      true,
+			wantRequests: 1,
+			wantSeries:   2,
+			wantError:    2,
+		},
+		{
+			desc:         "batches",
+			metrics:      many,
+			ready:        true,
+			wantRequests: 2,
+			wantSeries:   maxSamplesPerRequest + 1,
+			wantSuccess:  maxSamplesPerRequest + 1,
+		},
+		{
+			desc:         "failed batch does not stop following batches",
+			metrics:      many,
+			ready:        true,
+			statuses:     []int{http.StatusBadRequest},
+			wantErr:      true,
+			wantRequests: 2,
+			wantSeries:   maxSamplesPerRequest + 1,
+			wantSuccess:  1,
+			wantError:    maxSamplesPerRequest,
+		},
+	}
+
+	for _, test := range tests {
+		rcv := &receiver{statuses: test.statuses}
+		server := httptest.NewServer(rcv)
+
+		config := test.config
+		config.URL = server.URL
+		config.Interval = time.Minute
+		source := SourceFunc(func(w io.Writer) bool {
+			if test.ready {
+				io.WriteString(w, test.metrics)
+			}
+			return test.ready
+		})
+		registry := prometheus.NewRegistry()
+		writer, err := New(config, source, registry)
+		if err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+
+		err = writer.Push(context.Background())
+		server.Close()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: expected error %t, got %v", test.desc, test.wantErr, err)
+		}
+
+		if len(rcv.errs) > 0 {
+			t.Errorf("%s: failed to decode requests: %v", test.desc, rcv.errs)
+		}
+		if len(rcv.requests) != test.wantRequests {
+			t.Errorf("%s: expected %d requests, got %d", test.desc, test.wantRequests, len(rcv.requests))
+		}
+		series := map[string]bool{}
+		for _, body := range rcv.bodies {
+			for _, s := range body.Timeseries {
+				series[fmt.Sprint(s.Labels)] = true
+			}
+		}
+		if len(series) != test.wantSeries {
+			t.Errorf("%s: expected %d series, got %d", test.desc, test.wantSeries, len(series))
+		}
+
+		for _, r := range rcv.requests {
+			for header, want := range map[string]string{
+				"Content-Encoding":                  "snappy",
+				"Content-Type":                      "application/x-protobuf",
+				"X-Prometheus-Remote-Write-Version": "0.1.0",
+			} {
+				if got := r.Header.Get(header); got != want {
+					t.Errorf("%s: expected header %s %q, got %q", test.desc, header, want, got)
+				}
+			}
+
+			username, password, basic := r.BasicAuth()
+			switch test.wantAuthority {
+			case "basic":
+				if !basic || username != "user" || password != "secret" {
+					t.Errorf("%s: expected basic authentication as user, got %q", test.desc, r.Header.Get("Authorization"))
+				}
+			case "bearer":
+				if got := r.Header.Get("Authorization"); got != "Bearer token" {
+					t.Errorf("%s: expected bearer token, got %q", test.desc, got)
+				}
+			default:
+				if got := r.Header.Get("Authorization"); got != "" {
+					t.Errorf("%s: expected no authorization, got %q", test.desc, got)
+				}
+			}
+		}
+
+		for _, c := range []struct {
+			name   string
+			labels map[string]string
+			want   float64
+		}{
+			{"kube_state_metrics_remote_write_samples_total", map[string]string{"result": "success"}, test.wantSuccess},
+			{"kube_state_metrics_remote_write_samples_total", map[string]string{"result": "error"}, test.wantError},
+			{"kube_state_metrics_remote_write_retries_total", nil, test.wantRetries},
+		} {
+			if got := counterValue(t, registry, c.name, c.labels); got != c.want {
+				t.Errorf("%s: expected %s%v to be %v, got %v", test.desc, c.name, c.labels, c.want, got)
+			}
+		}
+	}
+}
+
+func TestPushGivesUpRetryingWhenContextDone(t *testing.T) {
+	rcv := &receiver{statuses: []int{
+		http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable,
+		http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable,
+	}}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+
+	source := SourceFunc(func(w io.Writer) bool {
+		io.WriteString(w, "kube_untyped 1\n")
+		return true
+	})
+	writer, err := New(Config{URL: server.URL, Interval: time.Minute}, source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	if err := writer.Push(ctx); err == nil {
+		t.Error("expected error once the context is done")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		desc    string
+		config  Config
+		wantErr bool
+	}{
+		{
+			desc:   "valid",
+			config: Config{URL: "https://example.com/api/v1/write", Interval: time.Second},
+		},
+		{
+			desc:    "unsupported scheme",
+			config:  Config{URL: "ftp://example.com", Interval: time.Second},
+			wantErr: true,
+		},
+		{
+			desc:    "no interval",
+			config:  Config{URL: "https://example.com"},
+			wantErr: true,
+		},
+		{
+			desc:    "basic authentication and bearer token",
+			config:  Config{URL: "https://example.com", Interval: time.Second, Username: "user", BearerTokenFile: "token"},
+			wantErr: true,
+		},
+		{
+			desc:    "certificate without key",
+			config:  Config{URL: "https://example.com", Interval: time.Second, TLSCertFile: "cert"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.config.Validate(); (err != nil) != test.wantErr {
+			t.Errorf("%s: expected error %t, got %v", test.desc, test.wantErr, err)
+		}
+	}
+}
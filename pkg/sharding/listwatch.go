@@ -56,6 +56,10 @@ func (s *shardedListWatch) List(options metav1.ListOptions) (runtime.Object, err
 	res := &metav1.List{
 		Items: []runtime.RawExtension{},
 	}
+	// The reflector starts watching from the resource version of the list.
+	if l, err := meta.ListAccessor(list); err == nil {
+		res.ResourceVersion = l.GetResourceVersion()
+	}
 	for _, item := range items {
 		a, err := meta.Accessor(item)
 		if err != nil {
@@ -76,6 +80,12 @@ func (s *shardedListWatch) Watch(options metav1.ListOptions) (watch.Interface, e
 	}
 
 	return watch.Filter(w, func(in watch.Event) (out watch.Event, keep bool) {
+		// Bookmarks carry the current resource version only, which every
+		// shard needs to restart its watch from.
+		if in.Type == watch.Bookmark {
+			return in, true
+		}
+
 		a, err := meta.Accessor(in.Object)
 		if err != nil {
 			// TODO(brancz): needs logging
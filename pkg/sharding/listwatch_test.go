@@ -18,10 +18,14 @@ package sharding
 
 import (
 	"testing"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestSharding(t *testing.T) {
@@ -54,3 +58,48 @@ func TestSharding(t *testing.T) {
 		t.Fatal("Shard two should not pick up the object.")
 	}
 }
+
+func TestShardedListWatch(t *testing.T) {
+	fakeWatch := watch.NewFake()
+	lw := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return &v1.ConfigMapList{
+				ListMeta: metav1.ListMeta{ResourceVersion: "10"},
+			}, nil
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			return fakeWatch, nil
+		},
+	}
+
+	// Each shard is given the resource version of the list and every bookmark,
+	// regardless of the UID-less bookmark object.
+	for shard := int32(0); shard < 2; shard++ {
+		slw := NewShardedListWatch(shard, 2, lw)
+
+		list, err := slw.List(metav1.ListOptions{ResourceVersion: "0"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rv := list.(*metav1.List).ResourceVersion; rv != "10" {
+			t.Errorf("shard %d: expected resource version 10 of the list, got %q", shard, rv)
+		}
+
+		w, err := slw.Watch(metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		bookmark := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "12"}}
+		go fakeWatch.Action(watch.Bookmark, bookmark)
+		select {
+		case e := <-w.ResultChan():
+			if e.Type != watch.Bookmark || e.Object != bookmark {
+				t.Errorf("shard %d: expected bookmark event, got %v", shard, e)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("shard %d: expected bookmark event, got none", shard)
+		}
+		w.Stop()
+		fakeWatch = watch.NewFake()
+	}
+}
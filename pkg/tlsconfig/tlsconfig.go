@@ -0,0 +1,152 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+)
+
+// Versions maps the names of the supported TLS versions to their values.
+var Versions = map[string]uint16{
+	"VersionTLS10": tls.VersionTLS10,
+	"VersionTLS11": tls.VersionTLS11,
+	"VersionTLS12": tls.VersionTLS12,
+	"VersionTLS13": tls.VersionTLS13,
+}
+
+// ParseVersion returns the TLS version of the given name, e.g. VersionTLS12.
+func ParseVersion(name string) (uint16, error) {
+	if v, ok := Versions[name]; ok {
+		return v, nil
+	}
+
+	names := make([]string, 0, len(Versions))
+	for n := range Versions {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return 0, errors.Errorf("unknown TLS version %q. Available versions: %s", name, strings.Join(names, ","))
+}
+
+// CertReloader serves a certificate and private key read from files, which
+// are re-read periodically, so that rotated certificates are picked up
+// without restarting.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	// mtx protects cert, certPEM and keyPEM
+	mtx     sync.RWMutex
+	cert    *tls.Certificate
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// NewCertReloader returns a new CertReloader for the given certificate and
+// private key files, which have to contain a valid key pair.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the certificate and private key files and, if they changed,
+// replaces the served certificate. In case of an error, the previous
+// certificate is kept.
+func (r *CertReloader) Reload() error {
+	certPEM, err := ioutil.ReadFile(r.certFile)
+	if err != nil {
+		return errors.Wrap(err, "read TLS certificate")
+	}
+	keyPEM, err := ioutil.ReadFile(r.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "read TLS private key")
+	}
+
+	r.mtx.RLock()
+	unchanged := bytes.Equal(certPEM, r.certPEM) && bytes.Equal(keyPEM, r.keyPEM)
+	r.mtx.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return errors.Wrapf(err, "load TLS key pair from %s and %s", r.certFile, r.keyFile)
+	}
+
+	r.mtx.Lock()
+	r.cert = &cert
+	r.certPEM = certPEM
+	r.keyPEM = keyPEM
+	r.mtx.Unlock()
+
+	klog.Infof("Loaded TLS certificate from %s", r.certFile)
+	return nil
+}
+
+// Run reloads the certificate in the given interval until the context is
+// done.
+func (r *CertReloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(); err != nil {
+				klog.Errorf("Failed to reload TLS certificate, keeping the previous one: %v", err)
+			}
+		}
+	}
+}
+
+// GetCertificate returns the current certificate. It implements
+// tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.cert, nil
+}
+
+// New returns a tls.Config serving the certificate of the given CertReloader
+// and accepting connections of at least the given TLS version.
+func New(r *CertReloader, minVersion uint16) *tls.Config {
+	return &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: r.GetCertificate,
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate with the given serial
+// number and its private key to the given files.
+func writeCertificate(t *testing.T, certFile, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "kube-state-metrics"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func servedSerial(t *testing.T, r *CertReloader) int64 {
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.SerialNumber.Int64()
+}
+
+func TestCertReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ksm-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	if _, err := NewCertReloader(certFile, keyFile); err == nil {
+		t.Error("expected an error for missing files")
+	}
+
+	writeCertificate(t, certFile, keyFile, 1)
+	r, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serial := servedSerial(t, r); serial != 1 {
+		t.Errorf("expected certificate with serial 1, got %d", serial)
+	}
+
+	writeCertificate(t, certFile, keyFile, 2)
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if serial := servedSerial(t, r); serial != 2 {
+		t.Errorf("expected rotated certificate with serial 2, got %d", serial)
+	}
+
+	// A broken key pair must not replace the served certificate.
+	if err := ioutil.WriteFile(keyFile, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Error("expected an error for an invalid private key")
+	}
+	if serial := servedSerial(t, r); serial != 2 {
+		t.Errorf("expected previous certificate with serial 2 to be kept, got %d", serial)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Wanted      uint16
+		WantedError bool
+	}{
+		{Name: "VersionTLS12", Wanted: tls.VersionTLS12},
+		{Name: "VersionTLS13", Wanted: tls.VersionTLS13},
+		{Name: "TLS1.2", WantedError: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseVersion(test.Name)
+		if (err != nil) != test.WantedError || got != test.Wanted {
+			t.Errorf("Test error for Name: %s. Want: %v. Got: %v. Wanted Error: %v, Got Error: %v", test.Name, test.Wanted, got, test.WantedError, err)
+		}
+	}
+}
@@ -18,6 +18,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 var (
@@ -62,3 +64,22 @@ func GetVersion() Version {
 		Platform:  fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
 	}
 }
+
+// NewBuildInfoCollector returns a collector exposing the
+// kube_state_metrics_build_info metric, which is always 1 and labeled with the
+// version kube-state-metrics was built with.
+func NewBuildInfoCollector() prometheus.Collector {
+	v := GetVersion()
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kube_state_metrics_build_info",
+		Help: "A metric with a constant '1' value labeled by version, revision, go_version and build_date from which kube-state-metrics was built.",
+		ConstLabels: prometheus.Labels{
+			"version":    v.Release,
+			"revision":   v.GitCommit,
+			"go_version": v.GoVersion,
+			"build_date": v.BuildDate,
+		},
+	})
+	buildInfo.Set(1)
+	return buildInfo
+}
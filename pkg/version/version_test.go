@@ -0,0 +1,60 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package version
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestBuildInfoCollector(t *testing.T) {
+	Release, Commit, BuildDate = "v1.9.0", "abc1234", "2020-01-02T03:04:05Z"
+	defer func() {
+		Release, Commit, BuildDate = "UNKNOWN", "UNKNOWN", ""
+	}()
+
+	r := prometheus.NewRegistry()
+	r.MustRegister(NewBuildInfoCollector())
+	families, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 1 || families[0].GetName() != "kube_state_metrics_build_info" || len(families[0].Metric) != 1 {
+		t.Fatalf("expected a single kube_state_metrics_build_info metric, got %v", families)
+	}
+
+	m := families[0].Metric[0]
+	if v := m.GetGauge().GetValue(); v != 1 {
+		t.Errorf("expected value 1, got %v", v)
+	}
+	labels := map[string]string{}
+	for _, l := range m.Label {
+		labels[l.GetName()] = l.GetValue()
+	}
+	expected := map[string]string{
+		"version":    "v1.9.0",
+		"revision":   "abc1234",
+		"go_version": runtime.Version(),
+		"build_date": "2020-01-02T03:04:05Z",
+	}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected labels %v, got %v", expected, labels)
+	}
+}
@@ -17,22 +17,40 @@ limitations under the License.
 package watch
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 )
 
-// ListWatchMetrics stores the pointers of kube_state_metrics_[list|watch]_total metrics.
+// ListWatchMetrics stores the pointers of kube_state_metrics_[list|watch|relist]_total,
+// kube_state_metrics_last_resource_version,
+// kube_state_metrics_initial_list_duration_seconds,
+// kube_state_metrics_store_last_sync_timestamp_seconds and
+// kube_state_metrics_store_objects metrics.
 type ListWatchMetrics struct {
-	WatchTotal *prometheus.CounterVec
-	ListTotal  *prometheus.CounterVec
+	WatchTotal          *prometheus.CounterVec
+	ListTotal           *prometheus.CounterVec
+	RelistTotal         *prometheus.CounterVec
+	LastResourceVersion *prometheus.GaugeVec
+	InitialListDuration *prometheus.HistogramVec
+	LastSyncTimestamp   *prometheus.GaugeVec
+	StoreObjects        *prometheus.GaugeVec
 }
 
 // NewListWatchMetrics takes in a prometheus registry and initializes
-// and registers the kube_state_metrics_list_total and
-// kube_state_metrics_watch_total metrics. It returns those registered metrics.
+// and registers the kube_state_metrics_list_total,
+// kube_state_metrics_watch_total, kube_state_metrics_relist_total,
+// kube_state_metrics_last_resource_version,
+// kube_state_metrics_initial_list_duration_seconds,
+// kube_state_metrics_store_last_sync_timestamp_seconds and
+// kube_state_metrics_store_objects metrics. It returns those registered
+// metrics.
 func NewListWatchMetrics(r *prometheus.Registry) *ListWatchMetrics {
 	var m ListWatchMetrics
 	m.WatchTotal = prometheus.NewCounterVec(
@@ -50,10 +68,56 @@ func NewListWatchMetrics(r *prometheus.Registry) *ListWatchMetrics {
 		},
 		[]string{"result", "resource"},
 	)
+
+	m.RelistTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kube_state_metrics_relist_total",
+			Help: "Number of total resource lists after the initial list in kube-state-metrics, e.g. after a watch expired",
+		},
+		[]string{"resource"},
+	)
+
+	m.LastResourceVersion = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_state_metrics_last_resource_version",
+			Help: "Resource version of the most recent list or watch event of a resource in kube-state-metrics",
+		},
+		[]string{"resource"},
+	)
+
+	m.InitialListDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kube_state_metrics_initial_list_duration_seconds",
+			Help:    "Duration of the initial list of a resource in kube-state-metrics, including all pages",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		},
+		[]string{"resource"},
+	)
+
+	m.LastSyncTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_state_metrics_store_last_sync_timestamp_seconds",
+			Help: "Unix timestamp of the most recent list or watch event of a resource applied to its store in kube-state-metrics",
+		},
+		[]string{"resource"},
+	)
+
+	m.StoreObjects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kube_state_metrics_store_objects",
+			Help: "Number of objects of a resource currently held by its store in kube-state-metrics, after sharding and filtering",
+		},
+		[]string{"resource"},
+	)
 	if r != nil {
 		r.MustRegister(
 			m.ListTotal,
 			m.WatchTotal,
+			m.RelistTotal,
+			m.LastResourceVersion,
+			m.InitialListDuration,
+			m.LastSyncTimestamp,
+			m.StoreObjects,
 		)
 	}
 	return &m
@@ -65,6 +129,11 @@ type InstrumentedListerWatcher struct {
 	lw       cache.ListerWatcher
 	metrics  *ListWatchMetrics
 	resource string
+
+	// initialListStart is the time the first page of the initial list was
+	// requested, initialListDone is set once its last page was received.
+	initialListStart time.Time
+	initialListDone  bool
 }
 
 // NewInstrumentedListerWatcher returns a new InstrumentedListerWatcher.
@@ -77,8 +146,17 @@ func NewInstrumentedListerWatcher(lw cache.ListerWatcher, metrics *ListWatchMetr
 }
 
 // List is a wrapper func around the cache.ListerWatcher.List func. It increases the success/error
-// / counters based on the outcome of the List operation it instruments.
+// / counters based on the outcome of the List operation it instruments. It
+// records the resource version of the list and, for the initial list, its
+// duration across all pages. Any later list is counted as a relist.
 func (i *InstrumentedListerWatcher) List(options metav1.ListOptions) (res runtime.Object, err error) {
+	if !i.initialListDone && i.initialListStart.IsZero() {
+		i.initialListStart = time.Now()
+	}
+	if i.initialListDone && options.Continue == "" {
+		i.metrics.RelistTotal.WithLabelValues(i.resource).Inc()
+	}
+
 	res, err = i.lw.List(options)
 	if err != nil {
 		i.metrics.ListTotal.WithLabelValues("error", i.resource).Inc()
@@ -86,11 +164,20 @@ func (i *InstrumentedListerWatcher) List(options metav1.ListOptions) (res runtim
 	}
 
 	i.metrics.ListTotal.WithLabelValues("success", i.resource).Inc()
+
+	if l, err := meta.ListAccessor(res); err == nil {
+		i.setLastResourceVersion(l.GetResourceVersion())
+		if !i.initialListDone && l.GetContinue() == "" {
+			i.initialListDone = true
+			i.metrics.InitialListDuration.WithLabelValues(i.resource).Observe(time.Since(i.initialListStart).Seconds())
+		}
+	}
 	return
 }
 
 // Watch is a wrapper func around the cache.ListerWatcher.Watch func. It increases the success/error
-// counters based on the outcome of the Watch operation it instruments.
+// counters based on the outcome of the Watch operation it instruments. It
+// records the resource version of every watch event.
 func (i *InstrumentedListerWatcher) Watch(options metav1.ListOptions) (res watch.Interface, err error) {
 	res, err = i.lw.Watch(options)
 	if err != nil {
@@ -99,5 +186,70 @@ func (i *InstrumentedListerWatcher) Watch(options metav1.ListOptions) (res watch
 	}
 
 	i.metrics.WatchTotal.WithLabelValues("success", i.resource).Inc()
+
+	res = watch.Filter(res, func(e watch.Event) (watch.Event, bool) {
+		if o, err := meta.Accessor(e.Object); err == nil {
+			i.setLastResourceVersion(o.GetResourceVersion())
+		}
+		return e, true
+	})
 	return
 }
+
+// setLastResourceVersion sets kube_state_metrics_last_resource_version to the
+// given resource version. Resource versions are opaque, but numeric in
+// practice. Non-numeric ones are ignored.
+func (i *InstrumentedListerWatcher) setLastResourceVersion(resourceVersion string) {
+	rv, err := strconv.ParseUint(resourceVersion, 10, 64)
+	if err != nil {
+		return
+	}
+	i.metrics.LastResourceVersion.WithLabelValues(i.resource).Set(float64(rv))
+}
+
+// InstrumentedStore provides the
+// kube_state_metrics_store_last_sync_timestamp_seconds metric with a
+// cache.Store fed by a reflector and the related resource.
+type InstrumentedStore struct {
+	cache.Store
+	lastSync prometheus.Gauge
+}
+
+// NewInstrumentedStore returns a new InstrumentedStore.
+func NewInstrumentedStore(store cache.Store, metrics *ListWatchMetrics, resource string) *InstrumentedStore {
+	return &InstrumentedStore{
+		Store:    store,
+		lastSync: metrics.LastSyncTimestamp.WithLabelValues(resource),
+	}
+}
+
+// Add is a wrapper func around the cache.Store.Add func. It records the time
+// of the watch event if the object was added successfully.
+func (s *InstrumentedStore) Add(obj interface{}) error {
+	return s.synced(s.Store.Add(obj))
+}
+
+// Update is a wrapper func around the cache.Store.Update func. It records the
+// time of the watch event if the object was updated successfully.
+func (s *InstrumentedStore) Update(obj interface{}) error {
+	return s.synced(s.Store.Update(obj))
+}
+
+// Delete is a wrapper func around the cache.Store.Delete func. It records the
+// time of the watch event if the object was deleted successfully.
+func (s *InstrumentedStore) Delete(obj interface{}) error {
+	return s.synced(s.Store.Delete(obj))
+}
+
+// Replace is a wrapper func around the cache.Store.Replace func. It records
+// the time of the list if the objects were replaced successfully.
+func (s *InstrumentedStore) Replace(list []interface{}, resourceVersion string) error {
+	return s.synced(s.Store.Replace(list, resourceVersion))
+}
+
+func (s *InstrumentedStore) synced(err error) error {
+	if err == nil {
+		s.lastSync.SetToCurrentTime()
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2020 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestInstrumentedListerWatcher(t *testing.T) {
+	r := prometheus.NewRegistry()
+	metrics := NewListWatchMetrics(r)
+
+	lists := []struct {
+		list runtime.Object
+		err  error
+	}{
+		{nil, errors.New("throttled")},
+		{&v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "10", Continue: "page-2"}}, nil},
+		{&v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "10"}}, nil},
+		{&v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "20"}}, nil},
+	}
+	fakeWatch := watch.NewFake()
+	// Unlike cache.ListWatch, the fake does not follow continue tokens itself.
+	lw := NewInstrumentedListerWatcher(&fakeListerWatcher{
+		list: func() (runtime.Object, error) {
+			l := lists[0]
+			lists = lists[1:]
+			return l.list, l.err
+		},
+		watch: fakeWatch,
+	}, metrics, "*v1.Pod")
+
+	for len(lists) > 0 {
+		lw.List(metav1.ListOptions{})
+	}
+	if got := gauge(t, r, "kube_state_metrics_last_resource_version"); got != 20 {
+		t.Errorf("expected the resource version of the last list, got %v", got)
+	}
+	if got := histogramCount(t, r, "kube_state_metrics_initial_list_duration_seconds"); got != 1 {
+		t.Errorf("expected the initial list to be observed once, after its last page, got %d observations", got)
+	}
+	if got := counter(t, r, "kube_state_metrics_relist_total"); got != 1 {
+		t.Errorf("expected the list after the initial list to be counted as relist, got %v", got)
+	}
+
+	w, err := lw.Watch(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	go fakeWatch.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", ResourceVersion: "25"}})
+	<-w.ResultChan()
+	if got := gauge(t, r, "kube_state_metrics_last_resource_version"); got != 25 {
+		t.Errorf("expected the resource version of the last watch event, got %v", got)
+	}
+
+	go fakeWatch.Error(&metav1.Status{Message: "expired"})
+	<-w.ResultChan()
+	if got := gauge(t, r, "kube_state_metrics_last_resource_version"); got != 25 {
+		t.Errorf("expected watch errors to keep the resource version, got %v", got)
+	}
+}
+
+type fakeListerWatcher struct {
+	list  func() (runtime.Object, error)
+	watch watch.Interface
+}
+
+func (lw *fakeListerWatcher) List(options metav1.ListOptions) (runtime.Object, error) {
+	return lw.list()
+}
+
+func (lw *fakeListerWatcher) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	return lw.watch, nil
+}
+
+// family returns the only metric of the metric family of the given name.
+func TestInstrumentedStore(t *testing.T) {
+	r := prometheus.NewRegistry()
+	s := NewInstrumentedStore(&failingStore{Store: cache.NewStore(cache.MetaNamespaceKeyFunc)}, NewListWatchMetrics(r), "*v1.Pod")
+
+	if got := gauge(t, r, "kube_state_metrics_store_last_sync_timestamp_seconds"); got != 0 {
+		t.Errorf("expected no sync before the initial list, got %v", got)
+	}
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1"}}
+	if err := s.Add(pod); err == nil {
+		t.Fatal("expected the store to fail")
+	}
+	if got := gauge(t, r, "kube_state_metrics_store_last_sync_timestamp_seconds"); got != 0 {
+		t.Errorf("expected failed changes not to count as sync, got %v", got)
+	}
+
+	before := float64(time.Now().Unix())
+	for _, sync := range []func() error{
+		func() error { return s.Replace([]interface{}{pod}, "10") },
+		func() error { return s.Add(pod) },
+		func() error { return s.Update(pod) },
+		func() error { return s.Delete(pod) },
+	} {
+		s.Store.(*failingStore).ok = true
+		s.lastSync.Set(0)
+		if err := sync(); err != nil {
+			t.Fatal(err)
+		}
+		if got := gauge(t, r, "kube_state_metrics_store_last_sync_timestamp_seconds"); got < before {
+			t.Errorf("expected the time of the last sync, got %v", got)
+		}
+	}
+}
+
+// failingStore fails to add objects until ok is set.
+type failingStore struct {
+	cache.Store
+	ok bool
+}
+
+func (s *failingStore) Add(obj interface{}) error {
+	if !s.ok {
+		return errors.New("failed")
+	}
+	return s.Store.Add(obj)
+}
+
+func family(t *testing.T, r *prometheus.Registry, name string) *dto.Metric {
+	families, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() == name {
+			if len(f.Metric) != 1 {
+				t.Fatalf("expected a single %s metric, got %v", name, f.Metric)
+			}
+			return f.Metric[0]
+		}
+	}
+	t.Fatalf("metric family %s not found", name)
+	return nil
+}
+
+func gauge(t *testing.T, r *prometheus.Registry, name string) float64 {
+	return family(t, r, name).GetGauge().GetValue()
+}
+
+func counter(t *testing.T, r *prometheus.Registry, name string) float64 {
+	return family(t, r, name).GetCounter().GetValue()
+}
+
+func histogramCount(t *testing.T, r *prometheus.Registry, name string) uint64 {
+	return family(t, r, name).GetHistogram().GetSampleCount()
+}
@@ -153,7 +153,7 @@ func getLabelsDocumentation() (map[string][]string, error) {
 
 	// Match file names such as daemonset-metrics.md
 	fileRe := regexp.MustCompile(`^([a-z]*)-metrics.md$`)
-	// Match doc lines such as | kube_node_created | Gauge | `node`=&lt;node-address&gt;| STABLE |
+	// Match doc lines such as | kube_node_created_timestamp_seconds | Gauge | `node`=&lt;node-address&gt;| STABLE |
 	lineRe := regexp.MustCompile(`^\| *(kube_[a-z_]+) *\| *[a-zA-Z]+ *\|(.*)\| *[A-Z]+`)
 	// Match label names in label documentation
 	labelsRe := regexp.MustCompile("`([a-zA-Z_][a-zA-Z0-9_]*)`")
@@ -203,8 +203,14 @@ func TestKubeStateMetricsErrorMetrics(t *testing.T) {
 	// This map's keys are the metrics expected in kube-state-metrics telemetry.
 	// Its values are booleans, set to true when the metric is found.
 	foundMetricFamily := map[string]bool{
-		"kube_state_metrics_list_total":  false,
-		"kube_state_metrics_watch_total": false,
+		"kube_state_metrics_list_total":                        false,
+		"kube_state_metrics_watch_total":                       false,
+		"kube_state_metrics_build_info":                        false,
+		"kube_state_metrics_store_last_sync_timestamp_seconds": false,
+		"kube_state_metrics_store_objects":                     false,
+		"kube_state_metrics_shard_ordinal":                     false,
+		"kube_state_metrics_total_shards":                      false,
+		"kube_state_metrics_shard_config_hash":                 false,
 	}
 
 	for _, metricFamily := range metricFamilies {